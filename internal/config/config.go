@@ -1,41 +1,216 @@
 package config
 
+// Config 是配置文件的顶层结构
+// 注意: 配置文件由 yaml.v3 解析，字段名以 yaml tag 为准
 type Config struct {
-	Imports  []string          `mapstructure:"imports"`
-	Vars     map[string]string `mapstructure:"vars"` // Global variables
-	Commands []CommandConfig   `mapstructure:"commands"`
+	Imports  []string              `mapstructure:"imports" yaml:"imports"`
+	Vars     map[string]string     `mapstructure:"vars" yaml:"vars"` // Global variables
+	Auth     map[string]AuthConfig `mapstructure:"auth" yaml:"auth"` // 命名的认证配置，命令中通过 api.auth: <名称> 引用
+	Commands []CommandConfig       `mapstructure:"commands" yaml:"commands"`
 }
 
 // CommandConfig 定义单个命令的配置
 type CommandConfig struct {
-	Name        string          `mapstructure:"name"`
-	Usage       string          `mapstructure:"usage"`
-	Type        string          `mapstructure:"type"` // http, shell, system
-	SubCommands []CommandConfig `mapstructure:"subcommands"`
+	Name        string          `mapstructure:"name" yaml:"name"`
+	Usage       string          `mapstructure:"usage" yaml:"usage"`
+	Type        string          `mapstructure:"type" yaml:"type"` // http, shell, system
+	SubCommands []CommandConfig `mapstructure:"subcommands" yaml:"subcommands"`
+	Timeout     string          `mapstructure:"timeout" yaml:"timeout"` // 执行超时，如 30s、2m；超时后终止请求和子进程
+
+	// 参数声明: params 为位置参数, flags 为命名标志
+	Params []ParamConfig `mapstructure:"params" yaml:"params"`
+	Flags  []ParamConfig `mapstructure:"flags" yaml:"flags"`
 
 	// HTTP 相关配置
-	API APIConfig `mapstructure:"api"`
+	API APIConfig `mapstructure:"api" yaml:"api"`
+
+	// 输出格式化，在管道处理之后生效
+	Output OutputConfig `mapstructure:"output" yaml:"output"`
 
 	// Shell/Script 相关配置
-	Script string `mapstructure:"script"`
+	Script      string     `mapstructure:"script" yaml:"script"`
+	ScriptFile  string     `mapstructure:"script_file" yaml:"script_file"` // 外部脚本文件，相对路径基于声明它的配置文件
+	Interpreter StringList `mapstructure:"interpreter" yaml:"interpreter"` // sh(默认)、bash、zsh、python3、node 或任意命令前缀，如 ["python3", "-u"]
+	Strict      bool       `mapstructure:"strict" yaml:"strict"`           // shell 严格模式: set -euo pipefail
 
 	// System Command 相关配置
-	Command string   `mapstructure:"command"`
-	Args    []string `mapstructure:"args"`
+	Command string   `mapstructure:"command" yaml:"command"`
+	Args    []string `mapstructure:"args" yaml:"args"`
+	Exec    bool     `mapstructure:"exec" yaml:"exec"` // 用目标命令直接替换 sl-cli 进程 (不再包装)
 }
 
 // APIConfig 定义 HTTP 请求细节
 type APIConfig struct {
-	URL         string            `mapstructure:"url"`
-	Method      string            `mapstructure:"method"`
-	Headers     map[string]string `mapstructure:"headers"` // 支持 Header
-	QueryParams map[string]string `mapstructure:"query_params"`
-	Body        string            `mapstructure:"body"`
-	Pipes       []PipeConfig      `mapstructure:"pipes"`
+	URL         string                `mapstructure:"url" yaml:"url"`
+	Method      string                `mapstructure:"method" yaml:"method"`
+	Headers     map[string]string     `mapstructure:"headers" yaml:"headers"`           // 支持 Header
+	QueryParams map[string]StringList `mapstructure:"query_params" yaml:"query_params"` // 值可以是单个字符串或列表 (重复的 key)
+	Body        string                `mapstructure:"body" yaml:"body"`
+	Pipes       []PipeConfig          `mapstructure:"pipes" yaml:"pipes"`
+	ExitCodes   map[string]int        `mapstructure:"exit_codes" yaml:"exit_codes"` // HTTP 状态码到退出码的映射，如 "404": 4, "5xx": 5, "default": 1
+
+	Form          map[string]StringList `mapstructure:"form" yaml:"form"`                       // application/x-www-form-urlencoded，值可以是列表 (重复的 key)
+	Multipart     FieldList             `mapstructure:"multipart" yaml:"multipart"`             // multipart/form-data，按声明顺序发送；"@路径" 表示上传文件
+	BodyFile      string                `mapstructure:"body_file" yaml:"body_file"`             // 从文件流式读取 Body，相对路径基于当前工作目录
+	BodyFromStdin bool                  `mapstructure:"body_from_stdin" yaml:"body_from_stdin"` // 将标准输入流式作为 Body，如 cat payload.json | sl-cli create-item
+
+	Download DownloadConfig `mapstructure:"download" yaml:"download"` // 将响应保存为文件，也可以通过全局 -O 开启
+	Paginate PaginateConfig `mapstructure:"paginate" yaml:"paginate"` // 列表接口的分页方式，配合全局 --all、--max-pages 获取多页
+	Poll     PollConfig     `mapstructure:"poll" yaml:"poll"`         // 重复请求直到满足条件，如等待异步任务完成
+
+	SuccessStatus StringList  `mapstructure:"success_status" yaml:"success_status"` // 视为成功的状态码或类别，如 ["2xx", "404"]，默认 2xx
+	Retry         RetryConfig `mapstructure:"retry" yaml:"retry"`
+	Auth          AuthConfig  `mapstructure:"auth" yaml:"auth"` // 认证方式，可以内联，也可以写成顶层 auth 中的名称
+	Sign          SignConfig  `mapstructure:"sign" yaml:"sign"` // 请求签名，在模板渲染之后、发送之前计算
+
+	TLS       TLSConfig       `mapstructure:"tls" yaml:"tls"`
+	Transport TransportConfig `mapstructure:"transport" yaml:"transport"`
+}
+
+// TLSConfig 定义 HTTPS 连接的证书校验和客户端证书 (mTLS)
+// 文件路径的相对路径基于声明它的配置文件，支持 ~/ 和 ${ENV}
+type TLSConfig struct {
+	CA         string `mapstructure:"ca" yaml:"ca"`                   // PEM 格式的 CA 证书，追加到系统信任的证书中
+	Cert       string `mapstructure:"cert" yaml:"cert"`               // 客户端证书 (PEM)
+	Key        string `mapstructure:"key" yaml:"key"`                 // 客户端私钥 (PEM)，省略时从 cert 文件中读取
+	MinVersion string `mapstructure:"min_version" yaml:"min_version"` // 最低 TLS 版本: 1.0、1.1、1.2(默认)、1.3
+	ServerName string `mapstructure:"server_name" yaml:"server_name"` // 覆盖 SNI 和证书校验使用的主机名
+	Insecure   bool   `mapstructure:"insecure" yaml:"insecure"`       // 跳过证书校验 (不安全，每次执行都会输出警告)
+}
+
+// TransportConfig 定义 HTTP 连接方式
+type TransportConfig struct {
+	Proxy      string            `mapstructure:"proxy" yaml:"proxy"`             // 代理地址 (http、https、socks5)，"direct" 表示不使用代理；默认读取 HTTP_PROXY 等环境变量
+	NoProxy    StringList        `mapstructure:"no_proxy" yaml:"no_proxy"`       // 不经过代理的 host: 域名 (包括子域名)、IP、CIDR 或 "*"
+	HTTP2      *bool             `mapstructure:"http2" yaml:"http2"`             // false 时只使用 HTTP/1.1
+	Resolve    map[string]string `mapstructure:"resolve" yaml:"resolve"`         // 将 host 或 host:port 解析到指定的 IP (或 IP:port)，类似 curl --resolve
+	UnixSocket string            `mapstructure:"unix_socket" yaml:"unix_socket"` // 通过 Unix domain socket 连接，如 /var/run/docker.sock
+}
+
+// AuthConfig 定义 HTTP 请求的认证方式
+// password、token、key、client_secret 可以是字面值 (支持模板和 ${ENV})，也可以是凭据引用:
+//   - env:NAME     读取环境变量
+//   - file:PATH    读取文件内容 (去掉首尾空白)，相对路径基于声明它的配置文件
+//   - cmd:COMMAND  执行命令并读取其输出，如 "cmd:pass show github/token"
+type AuthConfig struct {
+	Use  string `mapstructure:"use" yaml:"use"`   // 引用顶层 auth 中定义的名称；写成 auth: <名称> 时自动设置
+	Type string `mapstructure:"type" yaml:"type"` // basic, bearer, api_key, oauth2
+
+	Username string `mapstructure:"username" yaml:"username"` // basic
+	Password string `mapstructure:"password" yaml:"password"` // basic
+	Token    string `mapstructure:"token" yaml:"token"`       // bearer
+	Key      string `mapstructure:"key" yaml:"key"`           // api_key
+	Name     string `mapstructure:"name" yaml:"name"`         // api_key 的 Header 或 query 参数名，默认 X-API-Key
+	In       string `mapstructure:"in" yaml:"in"`             // api_key 的位置: header(默认)、query
+
+	// oauth2: 获取的 access token 缓存在 ~/.config/sl-cli/tokens 中，过期后自动刷新
+	Flow         string     `mapstructure:"flow" yaml:"flow"`                 // client_credentials(默认)、device_code、authorization_code (PKCE)
+	TokenURL     string     `mapstructure:"token_url" yaml:"token_url"`       // token endpoint
+	AuthURL      string     `mapstructure:"auth_url" yaml:"auth_url"`         // authorization endpoint (authorization_code)
+	DeviceURL    string     `mapstructure:"device_url" yaml:"device_url"`     // device authorization endpoint (device_code)
+	RedirectURL  string     `mapstructure:"redirect_url" yaml:"redirect_url"` // 本地回调地址 (authorization_code)，默认 http://127.0.0.1:<随机端口>/callback
+	ClientID     string     `mapstructure:"client_id" yaml:"client_id"`
+	ClientSecret string     `mapstructure:"client_secret" yaml:"client_secret"` // 公共客户端 (device_code、PKCE) 可以不设置
+	Scopes       StringList `mapstructure:"scopes" yaml:"scopes"`
+}
+
+// SignConfig 定义请求签名，每次发送 (包括重试) 时按当前时间重新计算
+// access_key、secret_key、session_token、secret 与 auth 一样支持 env:/file:/cmd: 凭据引用
+type SignConfig struct {
+	Type string `mapstructure:"type" yaml:"type"` // aws_sigv4, hmac
+
+	// aws_sigv4: 未配置 access_key 时依次读取 AWS_ACCESS_KEY_ID 等环境变量和 ~/.aws/credentials
+	Region       string `mapstructure:"region" yaml:"region"`               // 默认读取 AWS_REGION、AWS_DEFAULT_REGION
+	Service      string `mapstructure:"service" yaml:"service"`             // 如 s3、execute-api
+	Profile      string `mapstructure:"profile" yaml:"profile"`             // ~/.aws/credentials 中的 profile，默认 AWS_PROFILE 或 default
+	AccessKey    string `mapstructure:"access_key" yaml:"access_key"`       // access key ID
+	SecretKey    string `mapstructure:"secret_key" yaml:"secret_key"`       // secret access key
+	SessionToken string `mapstructure:"session_token" yaml:"session_token"` // 临时凭据的 session token
+
+	// hmac: 对 "METHOD\n路径?query\n时间戳\n<headers>\n<body 哈希>" 计算 HMAC
+	Secret          string     `mapstructure:"secret" yaml:"secret"`                     // HMAC 密钥
+	KeyID           string     `mapstructure:"key_id" yaml:"key_id"`                     // 可在 format 中通过 {{.key_id}} 使用
+	Algorithm       string     `mapstructure:"algorithm" yaml:"algorithm"`               // sha256(默认)、sha1、sha512
+	Encoding        string     `mapstructure:"encoding" yaml:"encoding"`                 // 签名的编码: hex(默认)、base64
+	Headers         StringList `mapstructure:"headers" yaml:"headers"`                   // 参与签名的 Header，按配置顺序以 name:value 加入
+	BodyHash        bool       `mapstructure:"body_hash" yaml:"body_hash"`               // 是否加入 Body 的哈希 (hex，与 algorithm 相同的算法)
+	Header          string     `mapstructure:"header" yaml:"header"`                     // 签名写入的 Header，默认 X-Signature
+	Format          string     `mapstructure:"format" yaml:"format"`                     // 签名 Header 的值 (Go 模板)，默认 {{.signature}}
+	Timestamp       string     `mapstructure:"timestamp" yaml:"timestamp"`               // 时间戳格式: unix(默认)、unix_ms、rfc3339、http
+	TimestampHeader string     `mapstructure:"timestamp_header" yaml:"timestamp_header"` // 时间戳写入的 Header，默认 X-Timestamp
+}
+
+// DownloadConfig 定义下载模式: 响应流式写入文件，终端中显示进度条
+// 可以写成 download: true、download: "<路径>" 或完整的 mapping
+type DownloadConfig struct {
+	Enabled  bool   `mapstructure:"enabled" yaml:"enabled"`
+	Path     string `mapstructure:"path" yaml:"path"`         // 保存路径 (支持模板)，为空或目录时使用 Content-Disposition 或 URL 中的文件名
+	Resume   bool   `mapstructure:"resume" yaml:"resume"`     // 存在未完成的 <文件>.part 时通过 Range 请求继续下载
+	Checksum string `mapstructure:"checksum" yaml:"checksum"` // 校验和 (支持模板)，如 sha256:<hex>，也可以只写 hex (按长度判断算法)
+}
+
+// PaginateConfig 定义列表接口的分页方式，各页的结果数组拼接为一个数组后再交给管道和输出格式化
+type PaginateConfig struct {
+	Type      string `mapstructure:"type" yaml:"type"`             // link (Link Header)、cursor (Body 中的游标)、page (页码)、offset (偏移量)
+	Items     string `mapstructure:"items" yaml:"items"`           // 结果数组的 jq 路径，如 .data；为空时响应本身就是数组
+	Cursor    string `mapstructure:"cursor" yaml:"cursor"`         // cursor: 下一页游标的 jq 路径，如 .meta.next_cursor；值为 URL 时直接请求该 URL
+	Param     string `mapstructure:"param" yaml:"param"`           // 游标、页码或偏移量的 query 参数，默认 cursor、page、offset
+	SizeParam string `mapstructure:"size_param" yaml:"size_param"` // 每页数量的 query 参数，默认 page 为 per_page，offset 为 limit
+	Size      int    `mapstructure:"size" yaml:"size"`             // 每页数量，设置后随请求发送，结果不足一页时停止
+	Start     *int   `mapstructure:"start" yaml:"start"`           // 第一页的页码 (默认 1) 或起始偏移量 (默认 0)
+	All       bool   `mapstructure:"all" yaml:"all"`               // 默认获取全部页，相当于总是带上 --all
+	MaxPages  int    `mapstructure:"max_pages" yaml:"max_pages"`   // 最多获取的页数，0 表示不限制
+}
+
+// PollConfig 定义轮询: 按间隔重复发送请求，直到满足成功或失败条件，最后一次的响应交给管道
+type PollConfig struct {
+	Interval    string     `mapstructure:"interval" yaml:"interval"`         // 轮询间隔，默认 2s；响应带有 Retry-After 时以其为准
+	MaxDuration string     `mapstructure:"max_duration" yaml:"max_duration"` // 最长等待时间，如 10m，默认不限制 (仍受 timeout 约束)
+	Until       string     `mapstructure:"until" yaml:"until"`               // 成功条件 (jq)，如 .state == "DONE"
+	UntilStatus StringList `mapstructure:"until_status" yaml:"until_status"` // 成功的状态码或类别，如 ["200", "303"]
+	FailIf      string     `mapstructure:"fail_if" yaml:"fail_if"`           // 失败条件 (jq)，如 .state == "FAILED"
+	FailStatus  StringList `mapstructure:"fail_status" yaml:"fail_status"`   // 失败的状态码或类别
+	Progress    string     `mapstructure:"progress" yaml:"progress"`         // 显示在进度中的值 (jq)，如 .progress
+}
+
+// RetryConfig 定义 HTTP 请求的重试策略
+// 连接错误以及 on_status 中的状态码会触发重试，等待时间按指数增长并加入随机抖动，
+// 响应带有 Retry-After 时以其为准
+type RetryConfig struct {
+	Attempts      int        `mapstructure:"attempts" yaml:"attempts"`             // 最多尝试次数 (包括第一次)，0 或 1 表示不重试
+	Backoff       string     `mapstructure:"backoff" yaml:"backoff"`               // 第一次重试前的等待时间，之后每次翻倍，默认 1s
+	MaxBackoff    string     `mapstructure:"max_backoff" yaml:"max_backoff"`       // 单次等待的上限 (包括 Retry-After)，默认 30s
+	OnStatus      StringList `mapstructure:"on_status" yaml:"on_status"`           // 需要重试的状态码或类别，默认 429、502、503、504
+	NonIdempotent bool       `mapstructure:"non_idempotent" yaml:"non_idempotent"` // 连接错误时也重试 POST、PATCH 等非幂等请求
+}
+
+// OutputConfig 定义命令输出 (JSON) 的格式化方式
+type OutputConfig struct {
+	Format   string   `mapstructure:"format" yaml:"format"`     // raw(默认), json, yaml, table, csv, template
+	Columns  []string `mapstructure:"columns" yaml:"columns"`   // table/csv 的列: 字段名或 jq 路径，如 [name, .status.phase]
+	Template string   `mapstructure:"template" yaml:"template"` // format 为 template 时使用的 Go 模板
 }
 
 // PipeConfig 定义后续处理命令
+// 外部命令使用 command/args；内置处理阶段使用 builtin (目前支持 jq)，无需安装对应的程序
 type PipeConfig struct {
-	Command string   `mapstructure:"command"`
-	Args    []string `mapstructure:"args"`
+	Command string   `mapstructure:"command" yaml:"command"`
+	Args    []string `mapstructure:"args" yaml:"args"`
+
+	Builtin string `mapstructure:"builtin" yaml:"builtin"` // 内置处理阶段: jq
+	Expr    string `mapstructure:"expr" yaml:"expr"`       // jq 表达式，如 ".items[].name"
+	Raw     bool   `mapstructure:"raw" yaml:"raw"`         // 字符串结果不加引号输出 (jq -r)
+	Compact bool   `mapstructure:"compact" yaml:"compact"` // 每个结果输出为单行 JSON (jq -c)
+}
+
+// ParamConfig 定义一个位置参数或 flag
+type ParamConfig struct {
+	Name      string   `mapstructure:"name" yaml:"name"`
+	Type      string   `mapstructure:"type" yaml:"type"` // string(默认), int, bool, enum, duration, file
+	Required  bool     `mapstructure:"required" yaml:"required"`
+	Default   string   `mapstructure:"default" yaml:"default"`
+	Shorthand string   `mapstructure:"shorthand" yaml:"shorthand"` // 仅对 flags 有效，例如 "n"
+	Env       string   `mapstructure:"env" yaml:"env"`             // 未在命令行指定时读取的环境变量
+	Help      string   `mapstructure:"help" yaml:"help"`
+	Values    []string `mapstructure:"values" yaml:"values"` // enum 类型的可选值
 }
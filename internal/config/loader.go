@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -17,7 +18,13 @@ func LoadConfig(path string) (*Config, error) {
 	}
 
 	visited := make(map[string]bool)
-	return loadConfigRecursive(absPath, visited)
+	cfg, err := loadConfigRecursive(absPath, visited)
+	if err != nil {
+		return nil, err
+	}
+	// 所有文件合并之后再解析 auth 引用，命令可以引用其他文件 (如 imports) 中定义的 auth
+	resolveAuthRefs(cfg.Commands, cfg.Auth)
+	return cfg, nil
 }
 
 func loadConfigRecursive(path string, visited map[string]bool) (*Config, error) {
@@ -37,8 +44,14 @@ func loadConfigRecursive(path string, visited map[string]bool) (*Config, error)
 	}
 
 	baseDir := filepath.Dir(path)
+	resolveFileRefs(cfg.Commands, baseDir)
+	for name, a := range cfg.Auth {
+		resolveSecretFiles(a.SecretFields(), baseDir)
+		cfg.Auth[name] = a
+	}
 	mergedCfg := &Config{
 		Vars:     make(map[string]string),
+		Auth:     make(map[string]AuthConfig),
 		Commands: []CommandConfig{},
 	}
 
@@ -75,6 +88,9 @@ func mergeConfigs(base, override *Config) {
 	for k, v := range override.Vars {
 		base.Vars[k] = v
 	}
+	for k, v := range override.Auth {
+		base.Auth[k] = v
+	}
 
 	// Append Commands
 	// We might want to deduplicate by name, but for now just appending allows overrides?
@@ -82,3 +98,52 @@ func mergeConfigs(base, override *Config) {
 	// Let's just append for now, but in root.go we handle duplication.
 	base.Commands = append(base.Commands, override.Commands...)
 }
+
+// resolveFileRefs 将 script_file、TLS 证书、unix_socket 以及 auth、sign 中 file: 引用的相对路径解析为基于声明文件所在目录的绝对路径
+// 与 imports 的解析规则保持一致
+func resolveFileRefs(cmds []CommandConfig, baseDir string) {
+	for i := range cmds {
+		if cmds[i].ScriptFile != "" && !filepath.IsAbs(cmds[i].ScriptFile) {
+			cmds[i].ScriptFile = filepath.Join(baseDir, cmds[i].ScriptFile)
+		}
+		resolveSecretFiles(cmds[i].API.Auth.SecretFields(), baseDir)
+		resolveSecretFiles(cmds[i].API.Sign.SecretFields(), baseDir)
+		for _, path := range []*string{&cmds[i].API.TLS.CA, &cmds[i].API.TLS.Cert, &cmds[i].API.TLS.Key, &cmds[i].API.Transport.UnixSocket} {
+			*path = resolvePath(*path, baseDir)
+		}
+		resolveFileRefs(cmds[i].SubCommands, baseDir)
+	}
+}
+
+// resolveSecretFiles 将 file: 凭据引用中的相对路径解析为基于配置文件所在目录的绝对路径
+// "~/" 开头的路径在读取时展开
+func resolveSecretFiles(fields []*string, baseDir string) {
+	for _, field := range fields {
+		if path, ok := strings.CutPrefix(*field, "file:"); ok && path != "" {
+			*field = "file:" + resolvePath(path, baseDir)
+		}
+	}
+}
+
+// resolvePath 将相对路径解析为基于 baseDir 的绝对路径
+// 以 ~ 或 $ 开头的路径在使用时展开，保持原样
+func resolvePath(path, baseDir string) string {
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "~") || strings.HasPrefix(path, "$") {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// resolveAuthRefs 将命令中 auth: <名称> 的引用替换为对应的定义
+// 未定义的名称保持原样，由 config check 和执行时报告
+func resolveAuthRefs(cmds []CommandConfig, defs map[string]AuthConfig) {
+	for i := range cmds {
+		if use := cmds[i].API.Auth.Use; use != "" {
+			if def, ok := defs[use]; ok && def.Use == "" {
+				def.Use = use
+				cmds[i].API.Auth = def
+			}
+		}
+		resolveAuthRefs(cmds[i].SubCommands, defs)
+	}
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles 在 dir 下创建文件 (相对路径 -> 内容)
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadConfigScriptFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"sl-cli.yaml": `
+imports: ["team/ops.yaml"]
+commands:
+  - name: local
+    type: shell
+    script_file: scripts/local.sh
+  - name: absolute
+    type: shell
+    script_file: /opt/scripts/abs.sh
+`,
+		// 导入文件中的相对路径基于导入文件所在目录
+		"team/ops.yaml": `
+commands:
+  - name: ops
+    type: shell
+    subcommands:
+      - name: deploy
+        type: shell
+        script_file: ../scripts/deploy.sh
+`,
+	})
+	cfg, err := LoadConfig(filepath.Join(dir, "sl-cli.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	got := map[string]string{}
+	var walk func(cmds []CommandConfig)
+	walk = func(cmds []CommandConfig) {
+		for _, c := range cmds {
+			got[c.Name] = c.ScriptFile
+			walk(c.SubCommands)
+		}
+	}
+	walk(cfg.Commands)
+	want := map[string]string{
+		"local":    filepath.Join(dir, "scripts/local.sh"),
+		"absolute": "/opt/scripts/abs.sh",
+		"ops":      "",
+		"deploy":   filepath.Join(dir, "scripts/deploy.sh"),
+	}
+	for name, path := range want {
+		if got[name] != path {
+			t.Errorf("%s: script_file = %q, want %q", name, got[name], path)
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"missing-import.yaml": `imports: ["nope.yaml"]`,
+		"a.yaml":              `imports: ["b.yaml"]`,
+		"b.yaml":              `imports: ["a.yaml"]`,
+		"invalid.yaml":        "commands: [",
+	})
+	tests := []struct {
+		file    string
+		wantErr string
+	}{
+		{"does-not-exist.yaml", "failed to read config file"},
+		{"missing-import.yaml", "nope.yaml"},
+		{"a.yaml", "circular import"},
+		{"invalid.yaml", "failed to parse config file"},
+	}
+	for _, tt := range tests {
+		_, err := LoadConfig(filepath.Join(dir, tt.file))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("LoadConfig(%s) error = %v, want %q", tt.file, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigAuth(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"sl-cli.yaml": `
+imports: ["team/auth.yaml"]
+commands:
+  - name: named
+    type: http
+    api:
+      url: https://x
+      auth: prod
+  - name: inline
+    type: http
+    api:
+      url: https://x
+      auth:
+        type: bearer
+        token: file:secrets/inline.txt
+  - name: undefined
+    type: http
+    api:
+      url: https://x
+      auth: staging
+`,
+		// 在导入文件中定义的 auth 可以被主配置引用，file: 相对路径基于定义所在的文件
+		"team/auth.yaml": `
+auth:
+  prod:
+    type: basic
+    username: deploy
+    password: file:prod.pass
+`,
+	})
+	cfg, err := LoadConfig(filepath.Join(dir, "sl-cli.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig error: %v", err)
+	}
+	got := map[string]AuthConfig{}
+	for _, c := range cfg.Commands {
+		got[c.Name] = c.API.Auth
+	}
+	if a := got["named"]; a.Type != "basic" || a.Use != "prod" || a.Username != "deploy" || a.Password != "file:"+filepath.Join(dir, "team/prod.pass") {
+		t.Errorf("named auth = %+v", a)
+	}
+	if a := got["inline"]; a.Type != "bearer" || a.Token != "file:"+filepath.Join(dir, "secrets/inline.txt") {
+		t.Errorf("inline auth = %+v", a)
+	}
+	// 未定义的名称保持原样，由 config check 报告
+	if a := got["undefined"]; a.Type != "" || a.Use != "staging" {
+		t.Errorf("undefined auth = %+v", a)
+	}
+}
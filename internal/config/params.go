@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 支持的参数类型
+const (
+	ParamString   = "string"
+	ParamInt      = "int"
+	ParamBool     = "bool"
+	ParamEnum     = "enum"
+	ParamDuration = "duration"
+	ParamFile     = "file"
+)
+
+// Kind 返回参数类型，未指定时默认为 string
+func (p ParamConfig) Kind() string {
+	if p.Type == "" {
+		return ParamString
+	}
+	return p.Type
+}
+
+// Parse 将命令行上的字符串按声明的类型转换为具体的值
+// 返回的错误只描述原因，由调用方补充是哪个参数
+func (p ParamConfig) Parse(raw string) (interface{}, error) {
+	switch p.Kind() {
+	case ParamString:
+		return raw, nil
+	case ParamInt:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return n, nil
+	case ParamBool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	case ParamEnum:
+		for _, v := range p.Values {
+			if v == raw {
+				return raw, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of: %s", strings.Join(p.Values, ", "))
+	case ParamDuration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, errors.New("must be a duration like 30s or 5m")
+		}
+		return d, nil
+	case ParamFile:
+		info, err := os.Stat(raw)
+		if err != nil {
+			return nil, errors.New("file not found")
+		}
+		if info.IsDir() {
+			return nil, errors.New("is a directory, not a file")
+		}
+		return raw, nil
+	default:
+		return nil, fmt.Errorf("unknown type %q", p.Type)
+	}
+}
+
+// Zero 返回未提供值时的零值，保证模板中总能取到正确类型的值
+func (p ParamConfig) Zero() interface{} {
+	switch p.Kind() {
+	case ParamInt:
+		return 0
+	case ParamBool:
+		return false
+	case ParamDuration:
+		return time.Duration(0)
+	default:
+		return ""
+	}
+}
+
+// Validate 检查参数声明本身是否合法，返回所有发现的问题
+func (p ParamConfig) Validate() []string {
+	var problems []string
+	if p.Name == "" {
+		problems = append(problems, "'name' is required")
+	}
+	knownType := true
+	switch p.Kind() {
+	case ParamString, ParamInt, ParamBool, ParamDuration, ParamFile:
+	case ParamEnum:
+		if len(p.Values) == 0 {
+			problems = append(problems, "type enum requires 'values'")
+		}
+	default:
+		knownType = false
+		problems = append(problems, fmt.Sprintf("invalid type '%s'. Must be string, int, bool, enum, duration or file", p.Type))
+	}
+	if len(p.Shorthand) > 1 {
+		problems = append(problems, fmt.Sprintf("shorthand '%s' must be a single character", p.Shorthand))
+	}
+	// file 类型的默认值可能只在运行时存在，不在此处校验
+	if knownType && p.Default != "" && p.Kind() != ParamFile {
+		if _, err := p.Parse(p.Default); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid default '%s': %s", p.Default, err))
+		}
+	}
+	return problems
+}
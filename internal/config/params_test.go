@@ -0,0 +1,106 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParamParse(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		param   ParamConfig
+		raw     string
+		want    interface{}
+		wantErr string
+	}{
+		{"default type is string", ParamConfig{}, "a b", "a b", ""},
+		{"int", ParamConfig{Type: ParamInt}, "42", 42, ""},
+		{"int invalid", ParamConfig{Type: ParamInt}, "4x", nil, "must be an integer"},
+		{"bool", ParamConfig{Type: ParamBool}, "true", true, ""},
+		{"bool invalid", ParamConfig{Type: ParamBool}, "yes", nil, "must be true or false"},
+		{"enum", ParamConfig{Type: ParamEnum, Values: []string{"dev", "prod"}}, "prod", "prod", ""},
+		{"enum invalid", ParamConfig{Type: ParamEnum, Values: []string{"dev", "prod"}}, "test", nil, "must be one of: dev, prod"},
+		{"duration", ParamConfig{Type: ParamDuration}, "1m30s", 90 * time.Second, ""},
+		{"duration invalid", ParamConfig{Type: ParamDuration}, "90", nil, "must be a duration"},
+		{"file", ParamConfig{Type: ParamFile}, file, file, ""},
+		{"file missing", ParamConfig{Type: ParamFile}, filepath.Join(dir, "nope"), nil, "file not found"},
+		{"file is a directory", ParamConfig{Type: ParamFile}, dir, nil, "is a directory"},
+		{"unknown type", ParamConfig{Type: "float"}, "1", nil, `unknown type "float"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.param.Parse(tt.raw)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want %q", tt.raw, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %#v, want %#v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParamZero(t *testing.T) {
+	tests := []struct {
+		kind string
+		want interface{}
+	}{
+		{"", ""},
+		{ParamInt, 0},
+		{ParamBool, false},
+		{ParamDuration, time.Duration(0)},
+		{ParamEnum, ""},
+		{ParamFile, ""},
+	}
+	for _, tt := range tests {
+		if got := (ParamConfig{Type: tt.kind}).Zero(); got != tt.want {
+			t.Errorf("Zero(%q) = %#v, want %#v", tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestParamValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		param ParamConfig
+		want  []string
+	}{
+		{"valid", ParamConfig{Name: "city", Required: true}, nil},
+		{"valid default", ParamConfig{Name: "n", Type: ParamInt, Default: "3"}, nil},
+		{"missing name", ParamConfig{}, []string{"'name' is required"}},
+		{"invalid type", ParamConfig{Name: "x", Type: "float"}, []string{"invalid type 'float'"}},
+		{"enum without values", ParamConfig{Name: "env", Type: ParamEnum}, []string{"type enum requires 'values'"}},
+		{"enum default not in values", ParamConfig{Name: "env", Type: ParamEnum, Values: []string{"dev"}, Default: "prod"}, []string{"invalid default 'prod': must be one of: dev"}},
+		{"invalid int default", ParamConfig{Name: "n", Type: ParamInt, Default: "many"}, []string{"invalid default 'many': must be an integer"}},
+		// file 的默认值只在运行时检查
+		{"file default not checked", ParamConfig{Name: "f", Type: ParamFile, Default: "/does/not/exist"}, nil},
+		{"long shorthand", ParamConfig{Name: "v", Shorthand: "vv"}, []string{"shorthand 'vv' must be a single character"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.param.Validate()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Validate() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if !strings.Contains(got[i], tt.want[i]) {
+					t.Errorf("Validate()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
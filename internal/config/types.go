@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StringList 既可以写成单个字符串，也可以写成字符串列表
+//
+//	key: "a"
+//	key: ["a", "b"]
+type StringList []string
+
+// UnmarshalYAML 实现 yaml.Unmarshaler
+func (l *StringList) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*l = StringList{node.Value}
+		return nil
+	case yaml.SequenceNode:
+		var items []string
+		if err := node.Decode(&items); err != nil {
+			return err
+		}
+		*l = items
+		return nil
+	default:
+		return fmt.Errorf("line %d: expected a string or a list of strings", node.Line)
+	}
+}
+
+// Field 是 FieldList 中的一项
+type Field struct {
+	Name  string
+	Value string
+}
+
+// FieldList 是保留声明顺序的键值对，写成普通的 mapping
+//
+//	multipart:
+//	  name: "avatar"
+//	  file: "@{{.args.path}}"
+type FieldList []Field
+
+// UnmarshalYAML 实现 yaml.Unmarshaler
+func (l *FieldList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping of field names to values", node.Line)
+	}
+	fields := make(FieldList, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var f Field
+		if err := node.Content[i].Decode(&f.Name); err != nil {
+			return err
+		}
+		if err := node.Content[i+1].Decode(&f.Value); err != nil {
+			return err
+		}
+		fields = append(fields, f)
+	}
+	*l = fields
+	return nil
+}
+
+// UnmarshalYAML 实现 yaml.Unmarshaler，允许 auth: <名称> 的简写
+func (a *AuthConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*a = AuthConfig{Use: node.Value}
+		return nil
+	}
+	type plain AuthConfig // 避免递归调用 UnmarshalYAML
+	return node.Decode((*plain)(a))
+}
+
+// UnmarshalYAML 实现 yaml.Unmarshaler，允许 download: true 和 download: <路径> 的简写
+func (d *DownloadConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var enabled bool
+		if node.Tag == "!!bool" && node.Decode(&enabled) == nil {
+			*d = DownloadConfig{Enabled: enabled}
+			return nil
+		}
+		*d = DownloadConfig{Enabled: true, Path: node.Value}
+		return nil
+	}
+	type plain DownloadConfig // 避免递归调用 UnmarshalYAML
+	if err := node.Decode((*plain)(d)); err != nil {
+		return err
+	}
+	// 写成 mapping 时默认开启，可以用 enabled: false 关闭
+	d.Enabled = true
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "enabled" {
+			return node.Content[i+1].Decode(&d.Enabled)
+		}
+	}
+	return nil
+}
+
+// SecretFields 返回可以使用凭据引用的字段
+func (a *AuthConfig) SecretFields() []*string {
+	return []*string{&a.Password, &a.Token, &a.Key, &a.ClientSecret}
+}
+
+// SecretFields 返回可以使用凭据引用的字段
+func (s *SignConfig) SecretFields() []*string {
+	return []*string{&s.AccessKey, &s.SecretKey, &s.SessionToken, &s.Secret}
+}
@@ -0,0 +1,262 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"sl-cli/internal/config"
+)
+
+// ================= HTTP Authentication =================
+
+// 支持的认证方式
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthAPIKey = "api_key"
+	AuthOAuth2 = "oauth2"
+)
+
+// defaultAPIKeyName 是 api_key 未指定 name 时使用的 Header
+const defaultAPIKeyName = "X-API-Key"
+
+// ValidateAuth 校验认证配置，返回问题描述 (供 config check 输出)
+func ValidateAuth(a config.AuthConfig) []string {
+	if a.Type == "" {
+		if a.Use != "" {
+			return []string{fmt.Sprintf("auth '%s' is not defined", a.Use)}
+		}
+		return []string{"auth is missing 'type' (basic, bearer, api_key, oauth2)"}
+	}
+
+	var problems []string
+	require := func(field, value string) {
+		if value == "" {
+			problems = append(problems, fmt.Sprintf("%s auth requires '%s'", a.Type, field))
+		}
+	}
+	switch a.Type {
+	case AuthBasic:
+		require("username", a.Username)
+	case AuthBearer:
+		require("token", a.Token)
+	case AuthAPIKey:
+		require("key", a.Key)
+		if a.In != "" && a.In != "header" && a.In != "query" {
+			problems = append(problems, fmt.Sprintf("invalid api_key location '%s', must be header or query", a.In))
+		}
+	case AuthOAuth2:
+		problems = append(problems, validateOAuth2(a)...)
+	default:
+		return []string{fmt.Sprintf("invalid auth type '%s', must be one of basic, bearer, api_key, oauth2", a.Type)}
+	}
+	for _, field := range []string{a.Username, a.Password, a.Token, a.Key, a.ClientSecret} {
+		if err := checkSecretRef(field); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// checkSecretRef 检查凭据引用的格式，字面值总是合法的
+func checkSecretRef(value string) error {
+	for _, prefix := range []string{"env:", "file:", "cmd:"} {
+		if ref, ok := strings.CutPrefix(value, prefix); ok && strings.TrimSpace(ref) == "" {
+			return fmt.Errorf("empty secret reference '%s'", value)
+		}
+	}
+	return nil
+}
+
+// credentials 提供认证所需的实际凭据
+// 执行时使用 liveCredentials 读取真实的凭据；dry-run 使用 maskedCredentials，不读取凭据也不发起授权
+type credentials interface {
+	// secret 将字段值 (字面值或凭据引用) 解析为实际的值
+	secret(value string) (string, error)
+	// oauthToken 返回 OAuth2 access token，refresh 为 true 时忽略缓存中尚未过期的 token
+	oauthToken(a config.AuthConfig, refresh bool) (string, error)
+	// awsKeys 返回 aws_sigv4 签名使用的 AWS 凭据
+	awsKeys(s config.SignConfig) (awsCredentials, error)
+}
+
+type liveCredentials struct {
+	ctx context.Context
+	env *Env
+	sc  *scope
+}
+
+func (c liveCredentials) secret(value string) (string, error) {
+	return resolveSecret(c.ctx, c.env, c.sc, value)
+}
+
+type maskedCredentials struct{}
+
+func (maskedCredentials) secret(string) (string, error) { return maskedValue, nil }
+
+func (maskedCredentials) oauthToken(config.AuthConfig, bool) (string, error) { return maskedValue, nil }
+
+// applyAuth 按认证配置为请求添加凭据，返回请求中出现的敏感值，供 spinner、trace 和错误信息隐藏
+// refresh 为 true 时重新获取 OAuth2 token (如服务端返回 401)
+func applyAuth(req *http.Request, a config.AuthConfig, creds credentials, refresh bool) ([]string, error) {
+	if a.Type == "" && a.Use == "" {
+		return nil, nil
+	}
+	if problems := ValidateAuth(a); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid auth: %s", problems[0])
+	}
+
+	get := func(field, value string) (string, error) {
+		if value == "" {
+			return "", nil
+		}
+		v, err := creds.secret(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve auth %s: %w", field, err)
+		}
+		return v, nil
+	}
+
+	switch a.Type {
+	case AuthBasic:
+		user, err := get("username", a.Username)
+		if err != nil {
+			return nil, err
+		}
+		pass, err := get("password", a.Password)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(user, pass)
+		return []string{pass, base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))}, nil
+	case AuthBearer:
+		token, err := get("token", a.Token)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return []string{token}, nil
+	case AuthAPIKey:
+		key, err := get("key", a.Key)
+		if err != nil {
+			return nil, err
+		}
+		name := a.Name
+		if name == "" {
+			name = defaultAPIKeyName
+		}
+		if a.In == "query" {
+			addQuery(req.URL, name, key)
+		} else {
+			req.Header.Set(name, key)
+		}
+		return []string{key}, nil
+	case AuthOAuth2:
+		token, err := creds.oauthToken(a, refresh)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return []string{token}, nil
+	}
+	return nil, nil
+}
+
+// addQuery 在 URL 末尾追加 query 参数，不改变已有参数的顺序
+// 被隐藏的值 (dry-run) 原样写入，避免 * 被转义
+func addQuery(u *url.URL, name, value string) {
+	if value != maskedValue {
+		value = url.QueryEscape(value)
+	}
+	pair := url.QueryEscape(name) + "=" + value
+	if u.RawQuery == "" {
+		u.RawQuery = pair
+	} else {
+		u.RawQuery += "&" + pair
+	}
+}
+
+// resolveSecret 解析认证字段: env:/file:/cmd: 引用读取实际的凭据，其他值作为字面值插值
+func resolveSecret(ctx context.Context, env *Env, sc *scope, value string) (string, error) {
+	if name, ok := strings.CutPrefix(value, "env:"); ok {
+		v := env.Getenv(name)
+		if v == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return v, nil
+	}
+	if path, ok := strings.CutPrefix(value, "file:"); ok {
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			path = filepath.Join(home, rest)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	if command, ok := strings.CutPrefix(value, "cmd:"); ok {
+		// 密码管理器可能需要交互 (如解锁)，因此共享 stdin 和 stderr
+		var out bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Env, cmd.Dir = env.Environ, env.Dir
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = env.Stdin, &out, env.Stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("command %q failed: %w", command, err)
+		}
+		return strings.TrimSpace(out.String()), nil
+	}
+	return sc.interpolate(value, ctxNone)
+}
+
+// ---------- Redaction ----------
+
+type secretsKey struct{}
+
+// withSecrets 将请求中的敏感值附加到 ctx 上，trace 和 HAR 据此隐藏它们
+func withSecrets(ctx context.Context, secrets []string) context.Context {
+	if len(secrets) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, secretsKey{}, secrets)
+}
+
+func secretsFrom(ctx context.Context) []string {
+	secrets, _ := ctx.Value(secretsKey{}).([]string)
+	return secrets
+}
+
+// redact 将字符串中出现的敏感值 (包括 URL 编码后的形式) 替换为 ****
+func redact(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		for _, form := range []string{secret, url.QueryEscape(secret), url.PathEscape(secret)} {
+			s = strings.ReplaceAll(s, form, maskedValue)
+		}
+	}
+	return s
+}
+
+// redactError 隐藏错误信息中请求 URL 里的敏感值
+func redactError(err error, secrets []string) error {
+	if uerr, ok := err.(*url.Error); ok {
+		if u, perr := url.Parse(uerr.URL); perr == nil {
+			uerr.URL = maskURL(u)
+		}
+		uerr.URL = redact(uerr.URL, secrets)
+	}
+	return err
+}
@@ -0,0 +1,180 @@
+package executor
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sl-cli/internal/config"
+)
+
+func TestAuthRedaction(t *testing.T) {
+	const secret = "s3cr3t+/=token"
+	tests := []struct {
+		name  string
+		auth  config.AuthConfig
+		check func(r *http.Request) bool // 服务端收到的凭据是否正确
+	}{
+		{
+			name: "bearer",
+			auth: config.AuthConfig{Type: AuthBearer, Token: "env:TEST_TOKEN"},
+			check: func(r *http.Request) bool {
+				return r.Header.Get("Authorization") == "Bearer "+secret
+			},
+		},
+		{
+			name: "basic",
+			auth: config.AuthConfig{Type: AuthBasic, Username: "deploy", Password: "env:TEST_TOKEN"},
+			check: func(r *http.Request) bool {
+				user, pass, ok := r.BasicAuth()
+				return ok && user == "deploy" && pass == secret
+			},
+		},
+		{
+			name: "api key header",
+			auth: config.AuthConfig{Type: AuthAPIKey, Key: "env:TEST_TOKEN", Name: "X-Custom-Key"},
+			check: func(r *http.Request) bool {
+				return r.Header.Get("X-Custom-Key") == secret
+			},
+		},
+		{
+			name: "api key query",
+			auth: config.AuthConfig{Type: AuthAPIKey, Key: "env:TEST_TOKEN", Name: "k", In: "query"},
+			check: func(r *http.Request) bool {
+				return r.URL.Query().Get("k") == secret && r.URL.Query().Get("q") == "1"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				// 回显的凭据不在隐藏范围内，这里只返回固定内容
+				w.Write([]byte(`{"ok":true}`))
+			}))
+			defer srv.Close()
+
+			env, _, stderr := testEnv(t, "TEST_TOKEN="+secret)
+			var trace strings.Builder
+			env.Trace = &trace
+			env.HARFile = filepath.Join(t.TempDir(), "out.har")
+			api := config.APIConfig{URL: srv.URL + "/x?q=1", Auth: tt.auth}
+			if err := runAPI(t, env, api, Input{}); err != nil {
+				t.Fatalf("run error: %v", err)
+			}
+			if got == nil || !tt.check(got) {
+				t.Fatalf("server did not receive the credentials: %v", got)
+			}
+
+			if !strings.Contains(trace.String(), "> GET ") {
+				t.Fatalf("trace is missing the request:\n%s", trace.String())
+			}
+			har, err := os.ReadFile(env.HARFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := config.CommandConfig{Type: "http", API: api}
+			dry, err := DryRun(env, cfg, Input{})
+			if err != nil {
+				t.Fatalf("dry-run error: %v", err)
+			}
+			curl, err := RenderAs(env, cfg, Input{}, RenderCurl)
+			if err != nil {
+				t.Fatalf("render error: %v", err)
+			}
+			for name, out := range map[string]string{"trace": trace.String(), "stderr": stderr.String(), "har": string(har), "dry-run": dry, "curl": curl} {
+				for _, form := range []string{secret, url.QueryEscape(secret), base64.StdEncoding.EncodeToString([]byte("deploy:" + secret))} {
+					if strings.Contains(out, form) {
+						t.Errorf("%s leaks the credential (%s):\n%s", name, form, out)
+					}
+				}
+			}
+			if !strings.Contains(dry, maskedValue) {
+				t.Errorf("dry-run does not show the masked credential:\n%s", dry)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in      string
+		secrets []string
+		want    string
+	}{
+		{"Bearer abc", []string{"abc"}, "Bearer ****"},
+		{"https://x/?k=a+b%2F", []string{"a b/"}, "https://x/?k=****"},
+		{"nothing", []string{""}, "nothing"},
+	}
+	for _, tt := range tests {
+		if got := redact(tt.in, tt.secrets); got != tt.want {
+			t.Errorf("redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "token")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env, _, _ := testEnv(t, "TEST_TOKEN=from-env")
+	sc := newScope(env, config.CommandConfig{Params: []config.ParamConfig{{Name: "user"}}}, Input{Params: map[string]interface{}{"user": "alice"}})
+	tests := []struct {
+		value   string
+		want    string
+		wantErr string
+	}{
+		{"literal", "literal", ""},
+		{"{{.args.user}}", "alice", ""},
+		{"env:TEST_TOKEN", "from-env", ""},
+		{"env:MISSING_TOKEN", "", "environment variable MISSING_TOKEN is not set"},
+		{"file:" + file, "from-file", ""},
+		{"file:" + filepath.Join(dir, "nope"), "", "no such file"},
+		{"cmd:printf ' from-cmd\\n'", "from-cmd", ""},
+		{"cmd:exit 2", "", `command "exit 2" failed`},
+	}
+	for _, tt := range tests {
+		got, err := resolveSecret(context.Background(), env, sc, tt.value)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("resolveSecret(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolveSecret(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidateAuth(t *testing.T) {
+	tests := []struct {
+		auth config.AuthConfig
+		want string
+	}{
+		{config.AuthConfig{Type: AuthBasic, Username: "u", Password: "env:P"}, ""},
+		{config.AuthConfig{Type: AuthAPIKey, Key: "k", In: "query"}, ""},
+		{config.AuthConfig{Use: "prod"}, "auth 'prod' is not defined"},
+		{config.AuthConfig{Token: "t"}, "auth is missing 'type'"},
+		{config.AuthConfig{Type: "digest"}, "invalid auth type 'digest'"},
+		{config.AuthConfig{Type: AuthBasic}, "basic auth requires 'username'"},
+		{config.AuthConfig{Type: AuthBearer}, "bearer auth requires 'token'"},
+		{config.AuthConfig{Type: AuthAPIKey, Key: "k", In: "cookie"}, "invalid api_key location 'cookie'"},
+		{config.AuthConfig{Type: AuthBearer, Token: "env: "}, "empty secret reference 'env: '"},
+	}
+	for _, tt := range tests {
+		problems := strings.Join(ValidateAuth(tt.auth), "; ")
+		if (tt.want == "") != (problems == "") || !strings.Contains(problems, tt.want) {
+			t.Errorf("ValidateAuth(%+v) = %q, want %q", tt.auth, problems, tt.want)
+		}
+	}
+}
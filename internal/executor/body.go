@@ -0,0 +1,366 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sl-cli/internal/config"
+)
+
+// ================= Request Body =================
+
+// 请求体的来源，互相排斥
+const (
+	bodyRaw       = iota // api.body: 模板渲染后的字符串
+	bodyForm             // api.form: application/x-www-form-urlencoded
+	bodyMultipart        // api.multipart: multipart/form-data
+	bodyFile             // api.body_file
+	bodyStdin            // api.body_from_stdin
+)
+
+// requestBody 是渲染后的请求体
+// 字符串和表单在构建请求时写入；文件、标准输入和 multipart 只在真正发送时由 open 打开并流式读取
+type requestBody struct {
+	kind     int
+	data     string          // body、form 编码后的内容
+	parts    []multipartPart // multipart
+	boundary string          // multipart
+	path     string          // body_file
+}
+
+type multipartPart struct {
+	name        string
+	value       string // 普通字段的值
+	file        string // 非空时上传该文件
+	contentType string // 文件的类型，在 open 时检测
+	size        int64
+}
+
+// bodySources 返回配置中设置了的 Body 来源，供校验互斥使用
+func bodySources(api config.APIConfig) []string {
+	var set []string
+	for _, s := range []struct {
+		name string
+		ok   bool
+	}{
+		{"body", api.Body != ""},
+		{"form", len(api.Form) > 0},
+		{"multipart", len(api.Multipart) > 0},
+		{"body_file", api.BodyFile != ""},
+		{"body_from_stdin", api.BodyFromStdin},
+	} {
+		if s.ok {
+			set = append(set, s.name)
+		}
+	}
+	return set
+}
+
+// renderBody 渲染 Body 相关的配置，不读取文件和标准输入
+func renderBody(api config.APIConfig, sc *scope) (*requestBody, error) {
+	switch {
+	case len(api.Form) > 0:
+		keys := make([]string, 0, len(api.Form))
+		for k := range api.Form {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		form := url.Values{}
+		for _, k := range keys {
+			for _, v := range api.Form[k] {
+				val, err := sc.interpolate(v, ctxNone)
+				if err != nil {
+					return nil, fmt.Errorf("render form field %s error: %w", k, err)
+				}
+				// 与 query_params 一致，渲染后为空的值会被忽略
+				if val == "" {
+					continue
+				}
+				form.Add(k, val)
+			}
+		}
+		return &requestBody{kind: bodyForm, data: form.Encode()}, nil
+
+	case len(api.Multipart) > 0:
+		b := &requestBody{kind: bodyMultipart, boundary: multipart.NewWriter(io.Discard).Boundary()}
+		for _, f := range api.Multipart {
+			// 是否上传文件由配置原文决定，参数渲染出的 @ 只是普通文本，不能借此读取本地文件
+			src, isFile := strings.CutPrefix(f.Value, "@")
+			val, err := sc.interpolate(src, ctxNone)
+			if err != nil {
+				return nil, fmt.Errorf("render multipart field %s error: %w", f.Name, err)
+			}
+			part := multipartPart{name: f.Name, value: val}
+			if isFile {
+				part.value, part.file = "", sc.env.localPath(val)
+			}
+			b.parts = append(b.parts, part)
+		}
+		return b, nil
+
+	case api.BodyFile != "":
+		path, err := sc.interpolate(api.BodyFile, ctxNone)
+		if err != nil {
+			return nil, fmt.Errorf("render body_file error: %w", err)
+		}
+		return &requestBody{kind: bodyFile, path: sc.env.localPath(path)}, nil
+
+	case api.BodyFromStdin:
+		return &requestBody{kind: bodyStdin}, nil
+	}
+
+	bodyCtx := ctxNone
+	if isJSONBody(api) {
+		bodyCtx = ctxJSON
+	}
+	data, err := sc.interpolate(api.Body, bodyCtx)
+	if err != nil {
+		return nil, fmt.Errorf("render body error: %w", err)
+	}
+	return &requestBody{kind: bodyRaw, data: data}, nil
+}
+
+// localPath 将命令行中的路径解析为绝对路径: 展开 ~/ 和 ${ENV}，相对路径基于工作目录
+func (e *Env) localPath(path string) string {
+	path = e.expandPath(path)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	dir := e.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	return filepath.Join(dir, path)
+}
+
+// inMemory 判断 Body 是否已经渲染为字符串
+func (b *requestBody) inMemory() bool {
+	return b.kind == bodyRaw || b.kind == bodyForm
+}
+
+// setContentType 设置 Body 对应的 Content-Type
+// multipart 总是使用自己的 boundary；其他类型只在未配置 Content-Type 时设置
+func (b *requestBody) setContentType(h http.Header) {
+	switch b.kind {
+	case bodyMultipart:
+		h.Set("Content-Type", "multipart/form-data; boundary="+b.boundary)
+		return
+	case bodyForm:
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	case bodyFile:
+		if t := mime.TypeByExtension(filepath.Ext(b.path)); t != "" && h.Get("Content-Type") == "" {
+			h.Set("Content-Type", t)
+		}
+	}
+}
+
+// open 为真正发送的请求打开流式的 Body
+// 文件和 multipart 可以通过 GetBody 重新读取 (重试、签名)；标准输入只能读取一次
+func (b *requestBody) open(req *http.Request, env *Env) error {
+	switch b.kind {
+	case bodyFile:
+		f, size, err := openRegular(b.path)
+		if err != nil {
+			return fmt.Errorf("body_file: %w", err)
+		}
+		setFileBody(req, f, size, b.path)
+	case bodyStdin:
+		if isTerminal(env.Stdin) {
+			fmt.Fprintln(env.Stderr, "Reading request body from stdin (Ctrl-D to finish)...")
+		}
+		req.Body, req.ContentLength, req.GetBody = io.NopCloser(env.Stdin), -1, nil
+		if f, ok := env.Stdin.(*os.File); ok {
+			// 从文件重定向时可以得到长度，避免 chunked 编码
+			if st, err := f.Stat(); err == nil && st.Mode().IsRegular() {
+				if pos, err := f.Seek(0, io.SeekCurrent); err == nil {
+					req.ContentLength = st.Size() - pos
+				}
+			}
+		}
+		if req.ContentLength == 0 {
+			req.Body = http.NoBody
+		}
+	case bodyMultipart:
+		for i := range b.parts {
+			p := &b.parts[i]
+			if p.file == "" {
+				continue
+			}
+			f, size, err := openRegular(p.file)
+			if err != nil {
+				return fmt.Errorf("multipart field %s: %w", p.name, err)
+			}
+			p.size = size
+			p.contentType, err = detectContentType(f, p.file)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("multipart field %s: %w", p.name, err)
+			}
+		}
+		req.Body = b.multipartReader()
+		req.GetBody = func() (io.ReadCloser, error) { return b.multipartReader(), nil }
+		req.ContentLength = b.multipartSize()
+	}
+	return nil
+}
+
+// openRegular 打开普通文件并返回其大小
+func openRegular(path string) (*os.File, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	if st.IsDir() {
+		f.Close()
+		return nil, 0, fmt.Errorf("%s is a directory", path)
+	}
+	if !st.Mode().IsRegular() {
+		// 管道、设备等无法预知长度
+		return f, -1, nil
+	}
+	return f, st.Size(), nil
+}
+
+// setFileBody 将文件设置为请求的 Body；普通文件可以重新打开以便重试
+func setFileBody(req *http.Request, f *os.File, size int64, path string) {
+	if size == 0 {
+		f.Close()
+		req.Body, req.ContentLength = http.NoBody, 0
+		req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+		return
+	}
+	req.Body, req.ContentLength, req.GetBody = f, size, nil
+	if size > 0 {
+		req.GetBody = func() (io.ReadCloser, error) { return os.Open(path) }
+	}
+}
+
+// detectContentType 先按扩展名判断文件类型，无法判断时读取文件开头检测
+func detectContentType(f *os.File, path string) (string, error) {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t, nil
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// multipartReader 在 goroutine 中边读文件边编码，不会把文件全部读入内存
+// 读取端关闭 (请求结束或失败) 后写入失败，goroutine 随之退出
+func (b *requestBody) multipartReader() io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(b.writeMultipart(pw, true))
+	}()
+	return pr
+}
+
+// multipartSize 计算 multipart Body 的长度，使请求带有 Content-Length 而不是 chunked 编码
+func (b *requestBody) multipartSize() int64 {
+	var n countingWriter
+	_ = b.writeMultipart(&n, false)
+	size := int64(n)
+	for _, p := range b.parts {
+		if p.file != "" {
+			if p.size < 0 {
+				return -1
+			}
+			size += p.size
+		}
+	}
+	return size
+}
+
+// writeMultipart 写出 multipart Body；withFiles 为 false 时不写文件内容 (用于计算长度)
+func (b *requestBody) writeMultipart(w io.Writer, withFiles bool) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(b.boundary); err != nil {
+		return err
+	}
+	for _, p := range b.parts {
+		if p.file == "" {
+			if err := mw.WriteField(p.name, p.value); err != nil {
+				return err
+			}
+			continue
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(p.name), escapeQuotes(filepath.Base(p.file))))
+		h.Set("Content-Type", p.contentType)
+		pw, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if !withFiles {
+			continue
+		}
+		f, err := os.Open(p.file)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(pw, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// escapeQuotes 与 mime/multipart 中的实现一致
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+type countingWriter int64
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
+
+// describe 返回 dry-run 中显示的 Body: 字符串原样输出，文件和标准输入只显示来源
+func (b *requestBody) describe() string {
+	switch b.kind {
+	case bodyMultipart:
+		lines := make([]string, len(b.parts))
+		for i, p := range b.parts {
+			if p.file != "" {
+				lines[i] = p.name + "=@" + p.file
+			} else if strings.HasPrefix(p.value, "@") {
+				// 以 @ 开头的普通字段加引号，与上传文件区分
+				lines[i] = p.name + "=" + strconv.Quote(p.value)
+			} else {
+				lines[i] = p.name + "=" + p.value
+			}
+		}
+		return strings.Join(lines, "\n")
+	case bodyFile:
+		return "@" + b.path
+	case bodyStdin:
+		return "@-  # stdin"
+	}
+	return b.data
+}
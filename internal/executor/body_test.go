@@ -0,0 +1,276 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sl-cli/internal/config"
+)
+
+// echoBody 返回请求的 Content-Type、Content-Length 和 Body
+func echoBody(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s|%d|%s", r.Header.Get("Content-Type"), r.ContentLength, data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// runBody 执行 http 命令，params 中的参数都会被声明
+func runBody(t *testing.T, env *Env, api config.APIConfig, params map[string]interface{}) error {
+	t.Helper()
+	cfg := config.CommandConfig{Name: "test", Type: "http", API: api}
+	for name := range params {
+		cfg.Params = append(cfg.Params, config.ParamConfig{Name: name})
+	}
+	return Run(t.Context(), env, cfg, Input{Params: params})
+}
+
+// openFile 打开文件作为标准输入，测试结束时关闭
+func openFile(t *testing.T, path string) *os.File {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestRequestBodies(t *testing.T) {
+	dir := t.TempDir()
+	payload := filepath.Join(dir, "payload.json")
+	empty := filepath.Join(dir, "empty")
+	for path, data := range map[string]string{payload: `{"id":1}`, empty: ""} {
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := echoBody(t)
+
+	tests := []struct {
+		name    string
+		api     config.APIConfig
+		stdin   io.Reader
+		params  map[string]interface{}
+		want    string
+		wantErr string
+	}{
+		{
+			name: "form",
+			api: config.APIConfig{Form: map[string]config.StringList{
+				"q":     {"{{.args.q}}"},
+				"tag":   {"a", "b&c"},
+				"empty": {"{{.args.missing}}"},
+			}},
+			params: map[string]interface{}{"q": "x y", "missing": ""},
+			want:   "application/x-www-form-urlencoded|21|q=x+y&tag=a&tag=b%26c",
+		},
+		{
+			name: "form keeps content type",
+			api:  config.APIConfig{Form: map[string]config.StringList{"a": {"1"}}, Headers: map[string]string{"Content-Type": "text/plain"}},
+			want: "text/plain|3|a=1",
+		},
+		{
+			name: "body_file",
+			api:  config.APIConfig{BodyFile: "{{.args.file}}"},
+			// 相对路径基于工作目录，Content-Type 按扩展名检测
+			params: map[string]interface{}{"file": "payload.json"},
+			want:   "application/json|8|{\"id\":1}",
+		},
+		{
+			name:    "body_file missing",
+			api:     config.APIConfig{BodyFile: "missing.json"},
+			wantErr: "body_file",
+		},
+		{
+			name:  "stdin",
+			api:   config.APIConfig{BodyFromStdin: true, Headers: map[string]string{"Content-Type": "text/csv"}},
+			stdin: strings.NewReader("a,b\n1,2\n"),
+			// 长度未知，使用 chunked 编码
+			want: "text/csv|-1|a,b\n1,2\n",
+		},
+		{
+			name:  "stdin from file",
+			api:   config.APIConfig{BodyFromStdin: true},
+			stdin: openFile(t, payload),
+			// 从文件重定向时使用文件长度
+			want: "|8|{\"id\":1}",
+		},
+		{
+			name:  "empty stdin file",
+			api:   config.APIConfig{BodyFromStdin: true},
+			stdin: openFile(t, empty),
+			want:  "|0|",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, stdout, _ := testEnv(t)
+			env.Dir = dir
+			if tt.stdin != nil {
+				env.Stdin = tt.stdin
+			}
+			tt.api.URL, tt.api.Method = srv.URL, http.MethodPost
+			err := runBody(t, env, tt.api, tt.params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run error: %v", err)
+			}
+			if got := strings.TrimSuffix(stdout.String(), "\n"); got != tt.want {
+				t.Errorf("request = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultipartBody(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "avatar.png"), []byte("\x89PNG\r\n\x1a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		mr, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			data, _ := io.ReadAll(p)
+			got = append(got, fmt.Sprintf("%s=%q file=%q type=%q", p.FormName(), data, p.FileName(), p.Header.Get("Content-Type")))
+		}
+	}))
+	defer srv.Close()
+
+	env, _, _ := testEnv(t)
+	env.Dir = dir
+	api := config.APIConfig{URL: srv.URL, Method: http.MethodPost, Multipart: config.FieldList{
+		{Name: "name", Value: "{{.args.name}}"},
+		{Name: "avatar", Value: "@avatar.png"},
+	}}
+	if err := runBody(t, env, api, map[string]interface{}{"name": "alice"}); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	want := []string{
+		`name="alice" file="" type=""`,
+		`avatar="\x89PNG\r\n\x1a\n" file="avatar.png" type="image/png"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("parts (in declaration order):\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestBodyFileRetry 检查重试时重新读取 body_file
+func TestBodyFileRetry(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "data.txt"), []byte("payload"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	env, _, _ := testEnv(t)
+	env.Dir = dir
+	api := config.APIConfig{URL: srv.URL, Method: http.MethodPut, BodyFile: "data.txt", Retry: config.RetryConfig{Attempts: 2, Backoff: "1ms"}}
+	if err := runAPI(t, env, api, Input{}); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if strings.Join(bodies, ",") != "payload,payload" {
+		t.Errorf("bodies = %q, want the file sent on every attempt", bodies)
+	}
+}
+
+// TestBodyOpenedLast 检查请求的其他部分出错时不会打开 body 文件
+func TestBodyOpenedLast(t *testing.T) {
+	env, _, _ := testEnv(t)
+	env.Dir = t.TempDir()
+	api := config.APIConfig{URL: "http://127.0.0.1:1", Method: http.MethodPut, BodyFile: "missing.bin", Sign: config.SignConfig{Type: SignAWSv4, Service: "s3"}}
+	err := runAPI(t, env, api, Input{})
+	if err == nil || !strings.Contains(err.Error(), "requires 'region'") {
+		t.Fatalf("error = %v, want the sign error reported before body_file is opened", err)
+	}
+}
+
+func TestValidateBodySources(t *testing.T) {
+	cfg := config.CommandConfig{Type: "http", API: config.APIConfig{URL: "http://x", Body: "{}", BodyFile: "a.json", BodyFromStdin: true}}
+	problems := strings.Join(httpRunner{}.Validate(cfg), "; ")
+	if !strings.Contains(problems, "Only one of body, form, multipart, body_file, body_from_stdin can be set (got body, body_file, body_from_stdin)") {
+		t.Errorf("Validate() = %q, want the mutually exclusive body sources", problems)
+	}
+}
+
+// TestMultipartFileSource 检查只有配置原文中的 @ 才会上传文件，参数值中的 @ 是普通文本
+func TestMultipartFileSource(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "report.txt"), []byte("report body"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(dir, "secret.txt")
+	if err := os.WriteFile(secret, []byte("do not send"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type part struct{ value, filename string }
+	var got map[string]part
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = map[string]part{}
+		mr, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			data, _ := io.ReadAll(p)
+			got[p.FormName()] = part{string(data), p.FileName()}
+		}
+	}))
+	defer srv.Close()
+
+	env, _, _ := testEnv(t)
+	env.Dir = dir
+	api := config.APIConfig{URL: srv.URL, Method: http.MethodPost, Multipart: config.FieldList{
+		{Name: "note", Value: "{{.args.note}}"},
+		{Name: "upload", Value: "@{{.args.file}}"},
+	}}
+	in := Input{Params: map[string]interface{}{"note": "@" + secret, "file": "report.txt"}}
+	cfg := config.CommandConfig{Type: "http", Params: []config.ParamConfig{{Name: "note"}, {Name: "file"}}, API: api}
+	if err := Run(t.Context(), env, cfg, in); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if p := got["note"]; p.value != "@"+secret || p.filename != "" {
+		t.Errorf("note = %+v, want the literal text @%s", p, secret)
+	}
+	if p := got["upload"]; p.value != "report body" || p.filename != "report.txt" {
+		t.Errorf("upload = %+v, want the content of report.txt", p)
+	}
+}
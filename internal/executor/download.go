@@ -0,0 +1,549 @@
+package executor
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"sl-cli/internal/config"
+)
+
+// ================= Download =================
+
+// partSuffix 是下载过程中临时文件的后缀，完成 (并通过校验) 后才重命名为最终文件
+const partSuffix = ".part"
+
+// validatorSuffix 是临时文件旁保存 ETag / Last-Modified 的文件后缀 (如 data.bin.part.validator)
+// 续传时作为 If-Range 发送，文件在服务端被修改过时服务端会返回完整内容，而不是拼接两个版本
+const validatorSuffix = ".validator"
+
+// checksumAlgos 是 download.checksum 支持的算法，只写 hex 时按长度判断
+var checksumAlgos = []struct {
+	name string
+	size int
+	new  func() hash.Hash
+}{
+	{"md5", md5.Size, md5.New},
+	{"sha1", sha1.Size, sha1.New},
+	{"sha256", sha256.Size, sha256.New},
+	{"sha512", sha512.Size, sha512.New},
+}
+
+// ApplyDownloadFlag 将全局 -O/--download 合并到配置中: 只覆盖保存路径，保留配置中的 resume 和 checksum
+func ApplyDownloadFlag(cfg config.CommandConfig, path string) (config.CommandConfig, error) {
+	if cfg.Type != "http" {
+		return cfg, fmt.Errorf("--download is not supported for %s commands", cfg.Type)
+	}
+	cfg.API.Download.Enabled = true
+	cfg.API.Download.Path = path
+	return cfg, nil
+}
+
+// validateDownload 校验 api.download；含模板的校验和只能在渲染后检查
+func validateDownload(api config.APIConfig) []string {
+	d := api.Download
+	if !d.Enabled {
+		return nil
+	}
+	var problems []string
+	if len(api.Pipes) > 0 {
+		problems = append(problems, "'download' and 'pipes' cannot be used together: the response is saved to a file")
+	}
+	if d.Checksum != "" && !strings.Contains(d.Checksum, "{{") {
+		if _, err := parseChecksum(d.Checksum); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// checksum 是解析后的 download.checksum
+type checksum struct {
+	algo string
+	sum  []byte
+	new  func() hash.Hash
+}
+
+// parseChecksum 解析 <algo>:<hex> 或只有 hex 的校验和
+func parseChecksum(s string) (*checksum, error) {
+	algo, sum, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		algo, sum = "", algo
+	}
+	raw, err := hex.DecodeString(sum)
+	if err != nil || len(raw) == 0 {
+		return nil, fmt.Errorf("invalid download checksum %q: must be <algo>:<hex> such as sha256:9f86d0...", s)
+	}
+	for _, a := range checksumAlgos {
+		if (algo == "" && a.size == len(raw)) || strings.EqualFold(algo, a.name) {
+			if a.size != len(raw) {
+				return nil, fmt.Errorf("invalid download checksum %q: %s must be %d hex characters", s, a.name, a.size*2)
+			}
+			return &checksum{algo: a.name, sum: raw, new: a.new}, nil
+		}
+	}
+	if algo == "" {
+		return nil, fmt.Errorf("invalid download checksum %q: cannot infer the algorithm from %d hex characters", s, len(sum))
+	}
+	return nil, fmt.Errorf("invalid download checksum %q: unknown algorithm %q (supported: md5, sha1, sha256, sha512)", s, algo)
+}
+
+// download 描述一次下载: 保存位置、续传状态和校验和
+type download struct {
+	env      *Env
+	raw      string // 渲染后、解析前的路径，用于 dry-run 和 --as
+	target   string // 保存的文件或目录 (绝对路径)
+	dir      bool   // target 是目录时，文件名取自 Content-Disposition 或 URL
+	resume   bool
+	checksum *checksum
+	urlName  string // URL 中的文件名
+
+	part   string // 续传的临时文件
+	offset int64  // 临时文件中已有的字节数
+}
+
+// newDownload 渲染 api.download；未开启下载时返回 nil
+func newDownload(api config.APIConfig, sc *scope, u *url.URL) (*download, error) {
+	cfg := api.Download
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	raw, err := sc.interpolate(cfg.Path, ctxNone)
+	if err != nil {
+		return nil, fmt.Errorf("render download path error: %w", err)
+	}
+	d := &download{env: sc.env, raw: raw, resume: cfg.Resume, urlName: urlFileName(u)}
+	if raw == "" {
+		raw = "."
+	}
+	d.target = sc.env.localPath(raw)
+	if strings.HasSuffix(raw, "/") || strings.HasSuffix(raw, string(filepath.Separator)) {
+		d.dir = true
+	} else if st, err := os.Stat(d.target); err == nil && st.IsDir() {
+		d.dir = true
+	}
+	if cfg.Checksum != "" {
+		sum, err := sc.interpolate(cfg.Checksum, ctxNone)
+		if err != nil {
+			return nil, fmt.Errorf("render download checksum error: %w", err)
+		}
+		if d.checksum, err = parseChecksum(sum); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// urlFileName 取 URL 路径的最后一段作为文件名
+func urlFileName(u *url.URL) string {
+	if name := safeFileName(u.Path); name != "" {
+		return name
+	}
+	return "download"
+}
+
+// safeFileName 只保留路径的最后一段，避免服务端返回的文件名写到目标目录之外
+func safeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// candidate 返回发送请求前就能确定的保存路径 (用于续传)
+func (d *download) candidate() string {
+	if d.dir {
+		return filepath.Join(d.target, d.urlName)
+	}
+	return d.target
+}
+
+// prepare 在发送前调整请求: 存在未完成的临时文件且开启了 resume 时请求剩余部分
+// 没有保存 ETag / Last-Modified 的临时文件无法确认服务端的文件是否变化，不续传
+func (d *download) prepare(req *http.Request) {
+	// 透明 gzip 解压会让 Content-Length 和 Range 的偏移失去意义，下载时要求原始字节
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", "identity")
+	}
+	if !d.resume {
+		return
+	}
+	part := d.candidate() + partSuffix
+	st, err := os.Stat(part)
+	if err != nil || !st.Mode().IsRegular() || st.Size() == 0 {
+		return
+	}
+	validator, err := os.ReadFile(part + validatorSuffix)
+	if err != nil || len(bytes.TrimSpace(validator)) == 0 {
+		return
+	}
+	d.part, d.offset = part, st.Size()
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", d.offset))
+	req.Header.Set("If-Range", string(bytes.TrimSpace(validator)))
+}
+
+// responseValidator 返回可用于 If-Range 的验证器: 强 ETag 优先，其次 Last-Modified
+// 弱 ETag (W/"...") 不能用于 If-Range
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// saveValidator 在开始写入新的临时文件时保存验证器，供下次续传使用
+func saveValidator(part string, resp *http.Response) error {
+	validator := responseValidator(resp)
+	if validator == "" {
+		os.Remove(part + validatorSuffix)
+		return nil
+	}
+	if err := os.WriteFile(part+validatorSuffix, []byte(validator+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to save download: %w", err)
+	}
+	return nil
+}
+
+// removePart 删除临时文件及其验证器
+func removePart(part string) {
+	os.Remove(part)
+	os.Remove(part + validatorSuffix)
+}
+
+// complete 判断 416 响应是否表示临时文件已经下载完整
+func (d *download) complete(resp *http.Response) bool {
+	return d.offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable
+}
+
+// fileName 确定最终的保存路径: 指定的文件 > Content-Disposition > URL
+// URL 取跟随重定向之后的地址，例如 /latest 重定向到 /v1.2.tar.gz 时保存为 v1.2.tar.gz
+func (d *download) fileName(resp *http.Response) string {
+	if !d.dir {
+		return d.target
+	}
+	if cd := resp.Header.Get("Content-Disposition"); cd != "" {
+		if _, params, err := mime.ParseMediaType(cd); err == nil {
+			if name := safeFileName(params["filename"]); name != "" {
+				return filepath.Join(d.target, name)
+			}
+		}
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		return filepath.Join(d.target, urlFileName(resp.Request.URL))
+	}
+	return filepath.Join(d.target, d.urlName)
+}
+
+// save 将响应体写入临时文件，校验通过后重命名为最终文件
+// 中断时: 开启了 resume 则保留临时文件以便下次续传，否则删除
+func (d *download) save(resp *http.Response) (err error) {
+	name, part := "", ""
+	flags, written, total := os.O_CREATE|os.O_WRONLY|os.O_TRUNC, int64(0), resp.ContentLength
+	switch {
+	case d.complete(resp):
+		// 服务端认为请求的范围超出了文件大小: 临时文件已经完整
+		name, part = strings.TrimSuffix(d.part, partSuffix), d.part
+		flags, written, total = os.O_WRONLY|os.O_APPEND, d.offset, d.offset
+		resp.Body, resp.ContentLength = http.NoBody, 0
+	case d.offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		start, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return err
+		}
+		if start != d.offset {
+			return fmt.Errorf("cannot resume download: requested bytes from %d but server returned %d", d.offset, start)
+		}
+		name, part = strings.TrimSuffix(d.part, partSuffix), d.part
+		flags, written = os.O_WRONLY|os.O_APPEND, d.offset
+		if total = size; total < 0 && resp.ContentLength >= 0 {
+			total = d.offset + resp.ContentLength
+		}
+	default:
+		if d.offset > 0 {
+			fmt.Fprintf(d.env.Stderr, "⚠️  Remote file changed or server does not support resuming, restarting download of %s\n", d.display(strings.TrimSuffix(d.part, partSuffix)))
+		}
+		name = d.fileName(resp)
+		part = name + partSuffix
+		if d.resume {
+			if err := saveValidator(part, resp); err != nil {
+				return err
+			}
+		}
+	}
+
+	var h hash.Hash
+	if d.checksum != nil {
+		h = d.checksum.new()
+		if written > 0 {
+			// 续传时已有的部分也要参与校验
+			if err := hashFile(h, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	f, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to save download: %w", err)
+	}
+	defer func() {
+		if err != nil && !d.resume {
+			removePart(part)
+		}
+	}()
+
+	var w io.Writer = f
+	if h != nil {
+		w = io.MultiWriter(f, h)
+	}
+	var bar *progressBar
+	if isTerminal(d.env.Stderr) && d.env.Trace == nil {
+		bar = newProgressBar(d.env.Stderr, filepath.Base(name), written, total, d.env.Now)
+		w = io.MultiWriter(w, bar)
+	}
+	n, err := io.Copy(w, resp.Body)
+	if bar != nil {
+		bar.finish()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		if d.resume {
+			return fmt.Errorf("download interrupted: %w (partial file kept at %s, run again to resume)", err, d.display(part))
+		}
+		return fmt.Errorf("download interrupted: %w", err)
+	}
+	written += n
+	if resp.ContentLength >= 0 && n < resp.ContentLength {
+		return fmt.Errorf("download interrupted: got %d of %d bytes", n, resp.ContentLength)
+	}
+
+	if h != nil {
+		if got := h.Sum(nil); !bytes.Equal(got, d.checksum.sum) {
+			removePart(part)
+			return fmt.Errorf("checksum mismatch for %s: expected %s:%x, got %s:%x", d.display(name), d.checksum.algo, d.checksum.sum, d.checksum.algo, got)
+		}
+	}
+	if err := os.Rename(part, name); err != nil {
+		return fmt.Errorf("failed to save download: %w", err)
+	}
+	os.Remove(part + validatorSuffix)
+	fmt.Fprintf(d.env.Stderr, "Saved %s (%s)\n", d.display(name), formatBytes(written))
+	return nil
+}
+
+// parseContentRange 解析 "bytes <start>-<end>/<size>"，size 未知 (*) 时返回 -1
+func parseContentRange(s string) (start, size int64, err error) {
+	spec, ok := strings.CutPrefix(s, "bytes ")
+	rng, sz, ok2 := strings.Cut(spec, "/")
+	first, _, ok3 := strings.Cut(rng, "-")
+	if !ok || !ok2 || !ok3 {
+		return 0, 0, fmt.Errorf("cannot resume download: invalid Content-Range %q", s)
+	}
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("cannot resume download: invalid Content-Range %q", s)
+	}
+	size = -1
+	if sz != "*" {
+		if size, err = strconv.ParseInt(sz, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("cannot resume download: invalid Content-Range %q", s)
+		}
+	}
+	return start, size, nil
+}
+
+func hashFile(h hash.Hash, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	return err
+}
+
+// display 返回相对于工作目录的路径 (在工作目录之外时返回绝对路径)
+func (d *download) display(p string) string {
+	dir := d.env.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	if rel, err := filepath.Rel(dir, p); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return p
+}
+
+// describe 返回 dry-run 中显示的保存位置
+func (d *download) describe() string {
+	var notes []string
+	target := d.raw
+	if d.dir {
+		target = filepath.Join(d.raw, d.urlName)
+		notes = append(notes, "name may come from Content-Disposition")
+	}
+	if target == "" {
+		target = d.urlName
+	}
+	if d.resume {
+		notes = append(notes, "resume")
+	}
+	if d.checksum != nil {
+		notes = append(notes, d.checksum.algo)
+	}
+	line := "> " + target + "  # download"
+	if len(notes) > 0 {
+		line += " (" + strings.Join(notes, ", ") + ")"
+	}
+	return line
+}
+
+// downloadArgs 返回 --as 中保存文件的参数；校验和无法表达，只能忽略
+func downloadArgs(format string, d *download) ([]string, error) {
+	switch format {
+	case RenderCurl:
+		var args []string
+		if d.dir {
+			if d.raw != "" && d.raw != "." {
+				args = append(args, "--output-dir", d.raw)
+			}
+			args = append(args, "-O", "-J")
+		} else {
+			args = append(args, "-o", d.raw)
+		}
+		if d.resume {
+			args = append(args, "-C", "-")
+		}
+		return args, nil
+	case RenderHTTPie:
+		args := []string{"--download"}
+		if !d.dir {
+			args = append(args, "--output", d.raw)
+		} else if d.raw != "" && d.raw != "." {
+			return nil, errors.New("--as httpie cannot save into a directory, set download to a file path")
+		}
+		if d.resume {
+			if d.dir {
+				return nil, errors.New("--as httpie can only resume when download is a file path")
+			}
+			args = append(args, "--continue")
+		}
+		return args, nil
+	case RenderWget:
+		var args []string
+		if d.dir {
+			args = append(args, "--content-disposition")
+			if d.raw != "" && d.raw != "." {
+				args = append(args, "-P", d.raw)
+			}
+		} else {
+			args = append(args, "-O", d.raw)
+		}
+		if d.resume {
+			args = append(args, "-c")
+		}
+		return args, nil
+	}
+	return nil, nil
+}
+
+// ---------- Progress Bar ----------
+
+// progressBar 在终端中显示下载进度，最多每 100ms 刷新一次
+type progressBar struct {
+	w       io.Writer
+	now     func() time.Time
+	name    string
+	start   time.Time
+	last    time.Time
+	resumed int64 // 续传前已有的字节，不计入速度
+	done    int64
+	total   int64 // 未知时为 -1
+}
+
+const progressWidth = 30
+
+func newProgressBar(w io.Writer, name string, done, total int64, now func() time.Time) *progressBar {
+	if len([]rune(name)) > 24 {
+		name = string([]rune(name)[:23]) + "…"
+	}
+	start := now()
+	return &progressBar{w: w, now: now, name: name, start: start, last: start, resumed: done, done: done, total: total}
+}
+
+func (p *progressBar) Write(b []byte) (int, error) {
+	p.done += int64(len(b))
+	if now := p.now(); now.Sub(p.last) >= 100*time.Millisecond {
+		p.last = now
+		p.render()
+	}
+	return len(b), nil
+}
+
+func (p *progressBar) render() {
+	rate := float64(0)
+	if elapsed := p.now().Sub(p.start).Seconds(); elapsed > 0 {
+		rate = float64(p.done-p.resumed) / elapsed
+	}
+	var line string
+	if p.total > 0 {
+		frac := min(float64(p.done)/float64(p.total), 1)
+		filled := int(frac * progressWidth)
+		bar := strings.Repeat("=", filled)
+		if filled < progressWidth {
+			bar += ">" + strings.Repeat(" ", progressWidth-filled-1)
+		}
+		line = fmt.Sprintf("%s %3d%% [%s] %s / %s  %s/s", p.name, int(frac*100), bar, formatBytes(p.done), formatBytes(p.total), formatBytes(int64(rate)))
+		if rate > 0 && p.done < p.total {
+			eta := time.Duration(float64(p.total-p.done)/rate) * time.Second
+			line += "  ETA " + eta.Round(time.Second).String()
+		}
+	} else {
+		line = fmt.Sprintf("%s %s  %s/s", p.name, formatBytes(p.done), formatBytes(int64(rate)))
+	}
+	// \033[K 清除上一次输出残留的字符
+	fmt.Fprintf(p.w, "\r%s\033[K", line)
+}
+
+func (p *progressBar) finish() {
+	p.render()
+	fmt.Fprintln(p.w)
+}
+
+// formatBytes 以 1024 为单位格式化字节数
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,216 @@
+package executor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sl-cli/internal/config"
+)
+
+func TestDownload(t *testing.T) {
+	content := []byte("file content\n")
+	sum := sha256.Sum256(content)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if name := r.URL.Query().Get("attachment"); name != "" {
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		}
+		if r.URL.Path == "/latest" {
+			http.Redirect(w, r, "/files/v1.2.tar.gz", http.StatusFound)
+			return
+		}
+		w.Write(content)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		path     string // 相对于临时目录
+		checksum string
+		want     string // 保存的文件，相对于临时目录
+		wantErr  string
+	}{
+		{name: "url name", url: "/files/report.csv", path: "", want: "report.csv"},
+		{name: "into directory", url: "/files/report.csv", path: "out/", want: "out/report.csv"},
+		{name: "redirected url name", url: "/latest", path: "out/", want: "out/v1.2.tar.gz"},
+		{name: "content disposition", url: "/dl?attachment=data.bin", path: "out/", want: "out/data.bin"},
+		{name: "unsafe content disposition", url: "/dl?attachment=../../evil.sh", path: "out/", want: "out/evil.sh"},
+		{name: "explicit file", url: "/files/report.csv", path: "saved.txt", want: "saved.txt"},
+		{name: "templated path", url: "/files/report.csv", path: "{{.args.name}}.csv", want: "q3.csv"},
+		{name: "checksum", url: "/files/a", path: "a", checksum: fmt.Sprintf("sha256:%x", sum), want: "a"},
+		{name: "checksum without algorithm", url: "/files/a", path: "a", checksum: fmt.Sprintf("%x", sum), want: "a"},
+		{name: "checksum mismatch", url: "/files/a", path: "a", checksum: "sha256:" + strings.Repeat("0", 64), wantErr: "checksum mismatch for a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(dir, "out"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			env, stdout, stderr := testEnv(t)
+			env.Dir = dir
+			// 下载模式自行写出结果，配置的 output 不生效 (否则空的 stdout 会被报告为无效 JSON)
+			cfg := config.CommandConfig{Name: "test", Type: "http", Params: []config.ParamConfig{{Name: "name"}}, API: config.APIConfig{
+				URL:      srv.URL + tt.url,
+				Download: config.DownloadConfig{Enabled: true, Path: tt.path, Checksum: tt.checksum},
+			}, Output: config.OutputConfig{Format: OutputJSON}}
+			err := Run(t.Context(), env, cfg, Input{Params: map[string]interface{}{"name": "q3"}})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				// 校验失败时不留下任何文件
+				if entries, _ := filepath.Glob(filepath.Join(dir, "a*")); len(entries) > 0 {
+					t.Errorf("files left behind: %v", entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run error: %v", err)
+			}
+			got, err := os.ReadFile(filepath.Join(dir, tt.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("content = %q, want %q", got, content)
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("stdout = %q, want nothing (the response is saved to a file)", stdout)
+			}
+			if want := fmt.Sprintf("Saved %s (13 B)", tt.want); !strings.Contains(stderr.String(), want) {
+				t.Errorf("stderr = %q, want %q", stderr, want)
+			}
+			if parts, _ := filepath.Glob(filepath.Join(dir, "*", "*"+partSuffix)); len(parts) > 0 {
+				t.Errorf("temporary files left behind: %v", parts)
+			}
+		})
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	const etag = `"v2"`
+	tests := []struct {
+		name      string
+		part      []byte // 已有的临时文件内容，nil 表示没有
+		validator string // 已保存的验证器，空表示没有
+		wantRange string // 服务端收到的 Range
+	}{
+		{"fresh", nil, "", ""},
+		{"matching validator resumes", content[:300], etag, "bytes=300-"},
+		{"changed file restarts", []byte("stale content"), `"v1"`, "bytes=13-"},
+		// 没有验证器时无法确认文件未变化，重新下载
+		{"part without validator", content[:300], "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRange, gotIfRange string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRange, gotIfRange = r.Header.Get("Range"), r.Header.Get("If-Range")
+				w.Header().Set("ETag", etag)
+				// ServeContent 按 If-Range 决定返回 206 还是完整的 200
+				http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(content))
+			}))
+			defer srv.Close()
+
+			dir := t.TempDir()
+			target := filepath.Join(dir, "data.bin")
+			part := target + partSuffix
+			if tt.part != nil {
+				if err := os.WriteFile(part, tt.part, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.validator != "" {
+				if err := os.WriteFile(part+validatorSuffix, []byte(tt.validator+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			env, _, stderr := testEnv(t)
+			api := config.APIConfig{URL: srv.URL + "/data.bin", Download: config.DownloadConfig{Enabled: true, Path: target, Resume: true}}
+			if err := runAPI(t, env, api, Input{}); err != nil {
+				t.Fatalf("run error: %v (stderr %q)", err, stderr.String())
+			}
+			if gotRange != tt.wantRange {
+				t.Errorf("Range = %q, want %q", gotRange, tt.wantRange)
+			}
+			if tt.wantRange != "" && gotIfRange != tt.validator {
+				t.Errorf("If-Range = %q, want %q", gotIfRange, tt.validator)
+			}
+			got, err := os.ReadFile(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("downloaded %d bytes, content does not match", len(got))
+			}
+			for _, leftover := range []string{part, part + validatorSuffix} {
+				if _, err := os.Stat(leftover); err == nil {
+					t.Errorf("%s was not removed", filepath.Base(leftover))
+				}
+			}
+		})
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	var out bytes.Buffer
+	// 续传时已有的 1000 字节不计入速度
+	bar := newProgressBar(&out, "data.bin", 1000, 4000, clock)
+	now = now.Add(2 * time.Second)
+	bar.Write(make([]byte, 1024))
+	want := "data.bin  50% [===============>              ] 2.0 KiB / 3.9 KiB  512 B/s  ETA 3s"
+	if got := out.String(); !strings.Contains(got, want) {
+		t.Errorf("progress = %q, want %q", got, want)
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	tests := []struct {
+		in, algo, wantErr string
+	}{
+		{in: "sha256:" + strings.Repeat("ab", 32), algo: "sha256"},
+		{in: "SHA1:" + strings.Repeat("ab", 20), algo: "sha1"},
+		{in: strings.Repeat("ab", 16), algo: "md5"},
+		{in: strings.Repeat("ab", 64), algo: "sha512"},
+		{in: "sha256:" + strings.Repeat("ab", 20), wantErr: "sha256 must be 64 hex characters"},
+		{in: "crc32:abcd", wantErr: `unknown algorithm "crc32"`},
+		{in: "abcd", wantErr: "cannot infer the algorithm from 4 hex characters"},
+		{in: "sha256:xyz", wantErr: "must be <algo>:<hex>"},
+	}
+	for _, tt := range tests {
+		c, err := parseChecksum(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseChecksum(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || c.algo != tt.algo {
+			t.Errorf("parseChecksum(%q) = %+v, %v, want %s", tt.in, c, err, tt.algo)
+		}
+	}
+}
+
+func TestApplyDownloadFlag(t *testing.T) {
+	cfg := config.CommandConfig{Type: "http", API: config.APIConfig{Download: config.DownloadConfig{Path: "a", Resume: true}}}
+	got, err := ApplyDownloadFlag(cfg, "b")
+	if err != nil || !got.API.Download.Enabled || got.API.Download.Path != "b" || !got.API.Download.Resume {
+		t.Errorf("ApplyDownloadFlag = %+v, %v, want path overridden and resume kept", got.API.Download, err)
+	}
+	if _, err := ApplyDownloadFlag(config.CommandConfig{Type: "shell"}, "b"); err == nil {
+		t.Error("ApplyDownloadFlag on a shell command should fail")
+	}
+}
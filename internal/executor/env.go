@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Env 描述命令的执行环境
+// 嵌入 sl-cli 的程序可以通过它捕获输出、在指定目录运行命令，或将 HTTP 请求指向测试服务器
+// 未设置的字段使用当前进程的默认值
+type Env struct {
+	Stdin     io.Reader
+	Stdout    io.Writer
+	Stderr    io.Writer
+	Environ   []string          // 子进程的环境变量、${ENV} 插值及参数环境变量回退的来源，nil 时使用 os.Environ()
+	Dir       string            // 子进程的工作目录，空字符串表示当前目录
+	Now       func() time.Time  // 时钟，nil 时使用 time.Now
+	Transport http.RoundTripper // HTTP 传输层，nil 时使用 http.DefaultTransport
+	Trace     io.Writer         // 非 nil 时输出 HTTP 请求/响应的 Header、重定向和各阶段耗时
+	HARFile   string            // 非空时将 HTTP 请求记录为 HAR 文件
+}
+
+// DefaultEnv 返回使用当前进程标准输入输出和环境变量的执行环境
+func DefaultEnv() *Env {
+	return (&Env{}).withDefaults()
+}
+
+// withDefaults 返回补全了默认值的副本
+func (e *Env) withDefaults() *Env {
+	out := &Env{}
+	if e != nil {
+		*out = *e
+	}
+	if out.Stdin == nil {
+		out.Stdin = os.Stdin
+	}
+	if out.Stdout == nil {
+		out.Stdout = os.Stdout
+	}
+	if out.Stderr == nil {
+		out.Stderr = os.Stderr
+	}
+	if out.Environ == nil {
+		out.Environ = os.Environ()
+	}
+	if out.Now == nil {
+		out.Now = time.Now
+	}
+	if out.Transport == nil {
+		out.Transport = http.DefaultTransport
+	}
+	return out
+}
+
+// Getenv 从 Environ 中读取环境变量，同名变量以最后一个为准
+func (e *Env) Getenv(key string) string {
+	for i := len(e.Environ) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(e.Environ[i], "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// expand 展开字符串中的 $VAR / ${VAR}
+func (e *Env) expand(s string) string {
+	return os.Expand(s, e.Getenv)
+}
+
+// httpClient 返回使用 Env.Transport 的 HTTP 客户端
+func (e *Env) httpClient() *http.Client {
+	return &http.Client{Transport: e.Transport}
+}
+
+// ownsProcess 判断执行环境是否就是当前进程本身 (标准输入输出、目录均未被替换)
+// 只有这种情况下才能用 exec 替换 sl-cli 进程
+func (e *Env) ownsProcess() bool {
+	return e.Stdin == os.Stdin && e.Stdout == os.Stdout && e.Stderr == os.Stderr && e.Dir == ""
+}
@@ -0,0 +1,112 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sl-cli/internal/config"
+)
+
+func TestEnvGetenv(t *testing.T) {
+	env := &Env{Environ: []string{"A=1", "B=x=y", "A=2", "EMPTY="}}
+	tests := map[string]string{"A": "2", "B": "x=y", "EMPTY": "", "MISSING": ""}
+	for key, want := range tests {
+		if got := env.Getenv(key); got != want {
+			t.Errorf("Getenv(%q) = %q, want %q", key, got, want)
+		}
+	}
+	if got := env.expand("${A}-$B"); got != "2-x=y" {
+		t.Errorf("expand = %q, want %q", got, "2-x=y")
+	}
+}
+
+func TestEnvWithDefaults(t *testing.T) {
+	var nilEnv *Env
+	env := nilEnv.withDefaults()
+	if env.Stdin != os.Stdin || env.Stdout != os.Stdout || env.Stderr != os.Stderr || env.Now == nil || env.Transport == nil {
+		t.Errorf("withDefaults() = %+v, want the process defaults", env)
+	}
+	if !env.ownsProcess() {
+		t.Error("default env should own the process")
+	}
+
+	custom := &Env{Environ: []string{}, Dir: "/tmp"}
+	got := custom.withDefaults()
+	if got == custom || got.Environ == nil || len(got.Environ) != 0 || got.Dir != "/tmp" {
+		t.Errorf("withDefaults() = %+v, want a copy keeping the custom fields", got)
+	}
+	if got.ownsProcess() {
+		t.Error("env with a custom Dir should not own the process")
+	}
+}
+
+func TestShellUsesEnv(t *testing.T) {
+	t.Setenv("SL_TEST_PROCESS", "leaked")
+	dir := t.TempDir()
+	env, stdout, stderr := testEnv(t, "SL_TEST_GREETING=hello")
+	env.Stdin = strings.NewReader("from stdin\n")
+	env.Dir = dir
+
+	cfg := config.CommandConfig{
+		Type:   "shell",
+		Script: `pwd; cat; echo "$SL_TEST_GREETING [$SL_TEST_PROCESS]"; echo oops >&2`,
+	}
+	if err := Run(context.Background(), env, cfg, Input{}); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	wantDir, _ := filepath.EvalSymlinks(dir)
+	want := wantDir + "\nfrom stdin\nhello []\n"
+	if stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+	if stderr.String() != "oops\n" {
+		t.Errorf("stderr = %q, want %q", stderr, "oops\n")
+	}
+}
+
+func TestSystemUsesEnv(t *testing.T) {
+	dir := t.TempDir()
+	env, stdout, _ := testEnv(t)
+	env.Dir = dir
+	cfg := config.CommandConfig{Type: "system", Command: "pwd"}
+	if err := Run(context.Background(), env, cfg, Input{}); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	wantDir, _ := filepath.EvalSymlinks(dir)
+	if got := strings.TrimSpace(stdout.String()); got != wantDir {
+		t.Errorf("pwd = %q, want %q", got, wantDir)
+	}
+}
+
+// roundTripFunc 让普通函数实现 http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestHTTPUsesEnvTransport(t *testing.T) {
+	var gotURL string
+	env, stdout, _ := testEnv(t, "SL_TEST_HOST=api.invalid")
+	env.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		gotURL = r.URL.String()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/plain"}},
+			Body:       io.NopCloser(strings.NewReader("pong")),
+			Request:    r,
+		}, nil
+	})
+	if err := runAPI(t, env, config.APIConfig{URL: "https://${SL_TEST_HOST}/ping"}, Input{}); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if gotURL != "https://api.invalid/ping" {
+		t.Errorf("request url = %q, want the host from Env.Environ", gotURL)
+	}
+	if !strings.Contains(stdout.String(), "pong") {
+		t.Errorf("stdout = %q, want the response body", stdout)
+	}
+}
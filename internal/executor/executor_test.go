@@ -0,0 +1,119 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"sl-cli/internal/config"
+)
+
+// testEnv 返回捕获输出的执行环境，environ 作为子进程和 ${ENV} 插值唯一可见的环境变量
+func testEnv(t *testing.T, environ ...string) (*Env, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	env := &Env{
+		Stdin:   bytes.NewReader(nil),
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+		Environ: append([]string{"HOME=" + t.TempDir(), "PATH=" + os.Getenv("PATH")}, environ...),
+	}
+	return env, &stdout, &stderr
+}
+
+// runAPI 以 http 类型执行 api 配置
+func runAPI(t *testing.T, env *Env, api config.APIConfig, in Input) error {
+	t.Helper()
+	return Run(context.Background(), env, config.CommandConfig{Name: "test", Type: "http", API: api}, in)
+}
+
+// sleepRunner 等待 d 后返回 err，不理会 ctx，用于模拟在截止时间附近结束的命令
+type sleepRunner struct {
+	d   time.Duration
+	err error
+}
+
+func (r sleepRunner) Run(ctx context.Context, env *Env, cfg config.CommandConfig, in Input) error {
+	time.Sleep(r.d)
+	return r.err
+}
+
+func (sleepRunner) Validate(cfg config.CommandConfig) []string { return nil }
+
+func (sleepRunner) DryRun(env *Env, cfg config.CommandConfig, in Input) (string, error) {
+	return "", nil
+}
+
+func (sleepRunner) Complete(cfg config.CommandConfig, args []string, toComplete string) []string {
+	return nil
+}
+
+func init() {
+	Register("test-sleep-ok", sleepRunner{d: 100 * time.Millisecond})
+	Register("test-sleep-fail", sleepRunner{d: 100 * time.Millisecond, err: &ExitError{Code: 3, Err: errors.New("failed")}})
+}
+
+func TestRunTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte(`"ok"`)) // 合法的 JSON，可以交给内置 jq
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		cfg      config.CommandConfig
+		wantCode int
+	}{
+		{"http success", config.CommandConfig{Type: "http", Timeout: "5s", API: config.APIConfig{URL: srv.URL + "/fast"}}, 0},
+		{"http timed out", config.CommandConfig{Type: "http", Timeout: "50ms", API: config.APIConfig{URL: srv.URL + "/slow"}}, timeoutExitCode},
+		{"shell timed out", config.CommandConfig{Type: "shell", Timeout: "50ms", Script: "sleep 10"}, timeoutExitCode},
+		{"system timed out", config.CommandConfig{Type: "system", Timeout: "50ms", Command: "sleep", Args: []string{"10"}}, timeoutExitCode},
+		// 内置 jq 不读写管道也会在超时时停止，而不是等到 killGrace 之后
+		{"builtin timed out", config.CommandConfig{Type: "http", Timeout: "500ms", API: config.APIConfig{URL: srv.URL + "/fast", Pipes: []config.PipeConfig{{Builtin: "jq", Expr: "[range(1e10)] | length"}}}}, timeoutExitCode},
+		{"invalid timeout", config.CommandConfig{Type: "shell", Timeout: "soon", Script: "true"}, 1},
+		// 命令在截止时间之后才返回，但已经成功完成，不应报告为超时
+		{"success after deadline", config.CommandConfig{Type: "test-sleep-ok", Timeout: "20ms"}, 0},
+		{"failure after deadline", config.CommandConfig{Type: "test-sleep-fail", Timeout: "20ms"}, timeoutExitCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, _, _ := testEnv(t)
+			start := time.Now()
+			err := Run(context.Background(), env, tt.cfg, Input{})
+			if got := ExitCode(err); got != tt.wantCode {
+				t.Fatalf("exit code = %d (err %v), want %d", got, err, tt.wantCode)
+			}
+			if d := time.Since(start); d > 2*time.Second {
+				t.Errorf("Run took %s, want the command stopped at its deadline", d)
+			}
+		})
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"30s", 30 * time.Second, false},
+		{"1m30s", 90 * time.Second, false},
+		{"-1s", 0, true},
+		{"30", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeout(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTimeout(%q) = %s, %v; want %s (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// ExitError 携带 sl-cli 进程应使用的退出码
+type ExitError struct {
+	Code  int
+	Err   error
+	Quiet bool // 子进程已经自行输出了错误信息，调用方无需再打印
+}
+
+func (e *ExitError) Error() string { return e.Err.Error() }
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// ExitCode 从错误中提取退出码: nil 为 0，未携带退出码的错误为 1
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return 1
+}
+
+// childExit 将子进程的运行结果转换为 ExitError，沿用 shell 的约定:
+// 正常退出使用子进程的退出码，被信号杀死为 128+n，找不到命令为 127，无法执行为 126
+func childExit(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		code := ee.ExitCode()
+		if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			code = 128 + int(ws.Signal())
+		}
+		return &ExitError{Code: code, Err: err, Quiet: true}
+	}
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		return &ExitError{Code: 127, Err: err}
+	}
+	if errors.Is(err, os.ErrPermission) {
+		return &ExitError{Code: 126, Err: err}
+	}
+	return err
+}
+
+// httpExitCode 根据 api.exit_codes 将 HTTP 状态码映射为退出码
+// 匹配顺序: 精确状态码 ("404") > 状态类别 ("4xx") > default > 1
+func httpExitCode(codes map[string]int, status int) int {
+	if code, ok := codes[strconv.Itoa(status)]; ok {
+		return code
+	}
+	if code, ok := codes[fmt.Sprintf("%dxx", status/100)]; ok {
+		return code
+	}
+	if code, ok := codes["default"]; ok {
+		return code
+	}
+	return 1
+}
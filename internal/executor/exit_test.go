@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sl-cli/internal/config"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("x"), 1},
+		{&ExitError{Code: 3, Err: errors.New("x")}, 3},
+		{fmt.Errorf("wrapped: %w", &ExitError{Code: 4, Err: errors.New("x")}), 4},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPExitCode(t *testing.T) {
+	codes := map[string]int{"404": 4, "5xx": 5, "default": 9}
+	tests := []struct {
+		codes  map[string]int
+		status int
+		want   int
+	}{
+		{codes, 404, 4},
+		{codes, 503, 5},
+		{codes, 401, 9},
+		{nil, 500, 1},
+	}
+	for _, tt := range tests {
+		if got := httpExitCode(tt.codes, tt.status); got != tt.want {
+			t.Errorf("httpExitCode(%v, %d) = %d, want %d", tt.codes, tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestChildExitCode(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.CommandConfig
+		want  int
+		quiet bool
+	}{
+		{"exit status", config.CommandConfig{Script: "exit 3"}, 3, true},
+		{"killed by signal", config.CommandConfig{Script: "kill -TERM $$"}, 128 + 15, true},
+		{"command not found", config.CommandConfig{Script: "x", Interpreter: config.StringList{"sl-cli-no-such-interpreter"}}, 127, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Type = "shell"
+			env, _, _ := testEnv(t)
+			err := Run(context.Background(), env, tt.cfg, Input{})
+			var exitErr *ExitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("error = %v, want *ExitError", err)
+			}
+			if exitErr.Code != tt.want || exitErr.Quiet != tt.quiet {
+				t.Errorf("exit = %d (quiet %v), want %d (quiet %v)", exitErr.Code, exitErr.Quiet, tt.want, tt.quiet)
+			}
+		})
+	}
+}
+
+func TestHTTPStatusExitCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	cfg := config.CommandConfig{
+		Type: "http",
+		API:  config.APIConfig{URL: srv.URL, ExitCodes: map[string]int{"4xx": 4}},
+	}
+	env, _, _ := testEnv(t)
+	if got := ExitCode(Run(context.Background(), env, cfg, Input{})); got != 4 {
+		t.Errorf("exit code = %d, want 4", got)
+	}
+}
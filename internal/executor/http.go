@@ -0,0 +1,550 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"sl-cli/internal/config"
+
+	"github.com/briandowns/spinner"
+)
+
+// ================= HTTP Processor =================
+
+func init() {
+	Register("http", httpRunner{})
+}
+
+// httpRunner 执行 type: http 的命令
+type httpRunner struct{}
+
+func (httpRunner) Run(ctx context.Context, env *Env, cfg config.CommandConfig, in Input) error {
+	return runHTTP(ctx, env, cfg, in)
+}
+
+// exitCodeKey 匹配 api.exit_codes 中的状态码 ("404") 或状态类别 ("4xx")
+var exitCodeKey = regexp.MustCompile(`^[1-5]([0-9]{2}|xx)$`)
+
+func (httpRunner) Validate(cfg config.CommandConfig) []string {
+	var problems []string
+	if cfg.API.URL == "" {
+		problems = append(problems, "Type is http but 'api.url' is missing")
+	}
+	for idx, p := range cfg.API.Pipes {
+		switch {
+		case p.Command != "" && p.Builtin != "":
+			problems = append(problems, fmt.Sprintf("Pipe #%d: 'command' and 'builtin' cannot be used together", idx+1))
+		case p.Builtin != "":
+			problems = append(problems, validateBuiltin(idx, p)...)
+		case p.Command == "":
+			problems = append(problems, fmt.Sprintf("Pipe #%d missing 'command'", idx+1))
+		}
+	}
+	problems = append(problems, validateRetry(cfg.API)...)
+	problems = append(problems, validateSign(cfg.API)...)
+	problems = append(problems, validateTransport(cfg.API)...)
+	problems = append(problems, validateDownload(cfg.API)...)
+	problems = append(problems, validatePaginate(cfg.API)...)
+	problems = append(problems, validatePoll(cfg.API)...)
+	if sources := bodySources(cfg.API); len(sources) > 1 {
+		problems = append(problems, fmt.Sprintf("Only one of body, form, multipart, body_file, body_from_stdin can be set (got %s)", strings.Join(sources, ", ")))
+	}
+	if cfg.API.Auth.Use != "" || cfg.API.Auth.Type != "" {
+		problems = append(problems, ValidateAuth(cfg.API.Auth)...)
+	}
+	for k := range cfg.API.ExitCodes {
+		if k != "default" && !exitCodeKey.MatchString(k) {
+			problems = append(problems, fmt.Sprintf("Invalid exit_codes key '%s'. Use a status code (404), a class (4xx) or 'default'", k))
+		}
+	}
+	return problems
+}
+
+// validateBuiltin 校验内置处理阶段；含模板的表达式只能在渲染后编译，这里只检查模板语法
+func validateBuiltin(idx int, p config.PipeConfig) []string {
+	if p.Builtin != "jq" {
+		return []string{fmt.Sprintf("Pipe #%d: unknown builtin '%s' (supported: jq)", idx+1, p.Builtin)}
+	}
+	if strings.Contains(p.Expr, "{{") {
+		if err := CheckTemplate(p.Expr); err != nil {
+			return []string{fmt.Sprintf("Pipe #%d: invalid expr template: %s", idx+1, err)}
+		}
+		return nil
+	}
+	if err := CheckJQ(p.Expr); err != nil {
+		return []string{fmt.Sprintf("Pipe #%d: %s", idx+1, err)}
+	}
+	return nil
+}
+
+// DryRun 输出最终的请求行、Header、Body 以及管道命令，敏感的 Header 和 query 参数会被隐藏
+func (httpRunner) DryRun(env *Env, cfg config.CommandConfig, in Input) (string, error) {
+	sc := newScope(env, cfg, in)
+	req, body, err := buildRequest(cfg.API, sc)
+	if err != nil {
+		return "", err
+	}
+	pager, err := newPaginator(cfg.API, env.Environ)
+	if err != nil {
+		return "", err
+	}
+	if pager != nil {
+		pager.first(req.URL)
+	}
+	if err := applyMaskedAuth(env, cfg.API, req, sc); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", req.Method, maskURL(req.URL))
+	for _, k := range sortedHeaderKeys(req.Header) {
+		values := make([]string, len(req.Header[k]))
+		for i, v := range req.Header[k] {
+			values[i] = maskHeader(k, v)
+		}
+		fmt.Fprintf(&b, "%s: %s\n", k, strings.Join(values, ", "))
+	}
+	if desc := body.describe(); desc != "" {
+		fmt.Fprintf(&b, "\n%s\n", desc)
+	}
+	dl, err := newDownload(cfg.API, sc, req.URL)
+	if err != nil {
+		return "", err
+	}
+	if dl != nil {
+		// 下载模式下响应保存为文件，不经过管道
+		fmt.Fprintf(&b, "%s\n", dl.describe())
+		return b.String(), nil
+	}
+	if pager != nil {
+		fmt.Fprintf(&b, "%s\n", pager.describe())
+	}
+	poll, err := newPoller(cfg.API, env)
+	if err != nil {
+		return "", err
+	}
+	if poll != nil {
+		fmt.Fprintf(&b, "%s\n", poll.describe())
+	}
+	pipes, err := pipeLines(cfg.API.Pipes, sc)
+	if err != nil {
+		return "", err
+	}
+	for i, line := range pipes {
+		if cfg.API.Pipes[i].Builtin != "" {
+			line += "  # builtin"
+		}
+		fmt.Fprintf(&b, "| %s\n", line)
+	}
+	return b.String(), nil
+}
+
+// applyMaskedAuth 为 dry-run 和 --as 添加认证和签名，凭据和签名显示为 ****
+func applyMaskedAuth(env *Env, api config.APIConfig, req *http.Request, sc *scope) error {
+	if _, err := applyAuth(req, api.Auth, maskedCredentials{}, false); err != nil {
+		return err
+	}
+	signer, err := newSigner(api.Sign, maskedCredentials{}, sc)
+	if err != nil || signer == nil {
+		return err
+	}
+	_, err = signer.sign(req, env.Now())
+	return err
+}
+
+func (httpRunner) Complete(cfg config.CommandConfig, args []string, toComplete string) []string {
+	return nil
+}
+
+// WritesOwnOutput 下载模式把响应体写入文件，stdout 没有可以格式化的输出
+func (httpRunner) WritesOwnOutput(cfg config.CommandConfig) bool {
+	return cfg.API.Download.Enabled
+}
+
+func runHTTP(ctx context.Context, env *Env, cfg config.CommandConfig, in Input) (err error) {
+	// api.tls / api.transport: 自定义 CA、客户端证书、代理、解析和 Unix socket
+	if env, err = env.withTransport(cfg.API); err != nil {
+		return err
+	}
+
+	// 0. 准备模板数据
+	sc := newScope(env, cfg, in)
+
+	// 1. 构建请求 (URL、Method、Query、Headers、Body 统一经过模板 + ${ENV} 插值)
+	req, body, err := buildRequest(cfg.API, sc)
+	if err != nil {
+		return err
+	}
+	// api.download / -O: 响应体写入文件，可以通过 Range 续传
+	dl, err := newDownload(cfg.API, sc, req.URL)
+	if err != nil {
+		return err
+	}
+	if dl != nil {
+		dl.prepare(req)
+	}
+	// api.paginate: 下载模式下保存原始响应，不分页
+	pager, err := newPaginator(cfg.API, env.Environ)
+	if err != nil {
+		return err
+	}
+	if pager != nil {
+		pager.first(req.URL)
+	}
+	// api.poll: 重复请求直到满足条件
+	poll, err := newPoller(cfg.API, env)
+	if err != nil {
+		return err
+	}
+	// 添加认证信息，凭据不会出现在 spinner、trace 和错误信息中
+	creds := liveCredentials{ctx: ctx, env: env, sc: sc}
+	secrets, err := applyAuth(req, cfg.API.Auth, creds, false)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(withSecrets(ctx, secrets))
+	signer, err := newSigner(cfg.API.Sign, creds, sc)
+	if err != nil {
+		return err
+	}
+	// 最后再打开 body_file、multipart 文件和 stdin，之前的步骤出错时无需关闭
+	if err := body.open(req, env); err != nil {
+		return err
+	}
+
+	// --trace / --har: 记录每一跳请求，结束后输出重定向链并写出 HAR
+	client := env.httpClient()
+	if rec := newHTTPRecorder(env, client.Transport); rec != nil {
+		client.Transport = rec
+		defer func() {
+			if ferr := rec.finish(); ferr != nil && err == nil {
+				err = ferr
+			}
+		}()
+	}
+	// api.sign: 在 recorder 之外签名，trace 和 HAR 中可以看到签名后的 Header
+	if signer != nil {
+		client.Transport = &signingTransport{next: client.Transport, signer: signer, host: req.URL.Host, now: env.Now}
+	}
+
+	// Spinner 在发送请求时显示 --- 只在 stderr 是终端时显示，避免污染被重定向或捕获的输出
+	// 开启 --trace 时不显示，以免与追踪信息交错
+	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond, spinner.WithWriter(env.Stderr)) // 14号是常用的点点点风格
+	s.Suffix = fmt.Sprintf(" Requesting %s...", redact(maskURL(req.URL), secrets))
+	s.Color("cyan") // Mac 终端对 cyan 支持很好
+	spin := isTerminal(env.Stderr) && env.Trace == nil
+	defer s.Stop()
+
+	// 5. 发送请求 (按 api.retry 重试)，重试提示输出到 stderr
+	notify := func(msg string) {
+		active := s.Active()
+		s.Stop()
+		fmt.Fprintf(env.Stderr, "⚠️  %s\n", redact(msg, secrets))
+		if active {
+			s.Start()
+		}
+	}
+	send := func(req *http.Request, label string) (*http.Response, *http.Request, error) {
+		if label != "" {
+			s.Stop()
+			s.Suffix = " " + redact(label, secrets) + "..."
+		}
+		if spin {
+			s.Start()
+		}
+		defer s.Stop()
+		resp, err := doWithRetry(ctx, env, client, req, cfg.API, notify)
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && cfg.API.Auth.Type == AuthOAuth2 && canReplay(req) {
+			// 缓存的 token 可能已被吊销: 刷新 token 后重试一次
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if req, err = cloneRequest(req); err != nil {
+				return nil, nil, err
+			}
+			if secrets, err = applyAuth(req, cfg.API.Auth, creds, true); err != nil {
+				return nil, nil, err
+			}
+			req = req.WithContext(withSecrets(ctx, secrets))
+			resp, err = doWithRetry(ctx, env, client, req, cfg.API, notify)
+		}
+		if err != nil {
+			return nil, nil, redactError(err, secrets)
+		}
+		return resp, req, nil
+	}
+	resp, req, err := send(req, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if dl != nil && (dl.complete(resp) || isSuccess(cfg.API, resp.StatusCode)) {
+		return dl.save(resp)
+	}
+	// 只有状态码为 2xx (或 api.success_status 中的状态码) 时才认为是“成功”，才执行管道命令
+	// 否则直接输出错误信息或原始 Body，避免 jq 解析 HTML 报错
+	check := func(resp *http.Response) error {
+		if isSuccess(cfg.API, resp.StatusCode) {
+			return nil
+		}
+		// 依然输出 Body 以便调试错误信息
+		_, _ = io.Copy(env.Stdout, resp.Body)
+		fmt.Fprintln(env.Stdout)
+		return &ExitError{
+			Code: httpExitCode(cfg.API.ExitCodes, resp.StatusCode),
+			Err:  fmt.Errorf("http request failed with status: %s", resp.Status),
+		}
+	}
+	// 轮询时由 poll 按 until_status、fail_status 判断每一次的响应
+	if poll == nil {
+		if err := check(resp); err != nil {
+			return err
+		}
+	}
+
+	// api.paginate: 拼接各页的结果数组，管道和输出格式化看到的是一个完整的数组
+	var out io.ReadCloser = resp.Body
+	if pager != nil {
+		data, err := pager.collect(resp, req, send, check)
+		if err != nil {
+			return err
+		}
+		out = io.NopCloser(bytes.NewReader(data))
+	}
+
+	// api.poll: 按间隔重新请求直到满足条件，最后一次的响应交给管道
+	if poll != nil {
+		// 轮询进度: 终端中显示在 spinner 上 (等待期间也保持转动)，否则逐行输出
+		status := func(msg string) {
+			msg = redact(msg, secrets)
+			if !spin {
+				fmt.Fprintln(env.Stderr, msg)
+				return
+			}
+			s.Stop()
+			s.Suffix = " " + msg
+			s.Start()
+		}
+		data, err := poll.wait(ctx, resp, req, send, check, status)
+		if err != nil {
+			return err
+		}
+		out = io.NopCloser(bytes.NewReader(data))
+	}
+
+	// 多级管道处理逻辑
+	if len(cfg.API.Pipes) > 0 {
+		return runPipes(ctx, env, cfg.API.Pipes, sc, out)
+	}
+
+	// 未配置管道命令，直接输出原始 Body
+	_, err = io.Copy(env.Stdout, out)
+	fmt.Fprintln(env.Stdout)
+	return err
+}
+
+// runPipes 将响应体依次交给管道中的各个阶段处理
+// 外部命令与内置阶段 (builtin) 可以任意混合，阶段之间通过 os.Pipe 连接
+func runPipes(ctx context.Context, env *Env, pipes []config.PipeConfig, sc *scope, body io.ReadCloser) error {
+	// 1. 先渲染参数、编译内置阶段，出错时不启动任何命令
+	argvs := make([][]string, len(pipes))
+	filters := make([]*jqFilter, len(pipes))
+	for i, pipeCfg := range pipes {
+		var err error
+		if pipeCfg.Builtin != "" {
+			filters[i], err = builtinFilter(pipeCfg, sc)
+			if err != nil {
+				return fmt.Errorf("pipe #%d: %w", i+1, err)
+			}
+			continue
+		}
+		// 准备命令参数 (支持模板和环境变量)
+		if argvs[i], err = pipeArgv(pipeCfg, sc); err != nil {
+			return err
+		}
+	}
+
+	// 每个管道命令运行在独立进程组中，Ctrl-C 或任一阶段失败时整条管道都会被清理
+	pipeline := &procGroup{}
+
+	// currentStdin 作为一个“接力棒”，初始值为 HTTP Response Body
+	var currentStdin io.ReadCloser = body
+	for i := range pipes {
+		// 2. 链接输出流: 不是最后一个阶段时，创建一个管道作为下一个阶段的输入；否则直接输出
+		var stdout io.Writer = env.Stdout
+		var next *os.File
+		if i < len(pipes)-1 {
+			pr, pw, err := os.Pipe()
+			if err != nil {
+				for _, c := range pipeline.closeAfterStart {
+					_ = c.Close()
+				}
+				return fmt.Errorf("failed to create pipe: %w", err)
+			}
+			stdout, next = pw, pr
+		}
+
+		if f := filters[i]; f != nil {
+			// 内置阶段在 goroutine 中运行，结束或被终止时关闭自己持有的管道端，让上下游感知，
+			// 并取消 stageCtx 中断仍在进行的计算
+			in, out := currentStdin, next != nil
+			stageCtx, cancel := context.WithCancel(ctx)
+			closeAll := func() {
+				cancel()
+				_ = in.Close()
+				if out {
+					_ = stdout.(io.Closer).Close()
+				}
+			}
+			pipeline.addFunc("builtin "+pipes[i].Builtin, func() error {
+				defer closeAll()
+				return f.run(stageCtx, in, stdout)
+			}, closeAll)
+		} else {
+			cmd := exec.Command(argvs[i][0], argvs[i][1:]...)
+			cmd.Env = env.Environ
+			cmd.Dir = env.Dir
+			cmd.Stdin = currentStdin
+			// 错误流统一输出到标准错误，方便调试
+			cmd.Stderr = env.Stderr
+			cmd.Stdout = stdout
+			pipeline.add(cmd, false)
+
+			// 交给子进程的管道端由子进程持有，父进程在启动后关闭
+			if i > 0 {
+				pipeline.closeAfterStart = append(pipeline.closeAfterStart, currentStdin)
+			}
+			if next != nil {
+				pipeline.closeAfterStart = append(pipeline.closeAfterStart, stdout.(io.Closer))
+			}
+		}
+		if next != nil {
+			currentStdin = next // 将接力棒传给下一位
+		}
+	}
+
+	// 3. 启动并等待所有阶段，退出码取最后一个失败的阶段 (与 pipefail 一致)
+	return pipeline.run(ctx)
+}
+
+// builtinFilter 编译内置处理阶段，jq 表达式支持模板但不展开 $ENV (与 jq 的变量语法冲突)
+// 插入的值按 jq 字面量转义，参数无法改变表达式本身
+func builtinFilter(p config.PipeConfig, sc *scope) (*jqFilter, error) {
+	if p.Builtin != "jq" {
+		return nil, fmt.Errorf("unknown builtin %q (supported: jq)", p.Builtin)
+	}
+	expr, err := renderTemplate(p.Expr, sc.data, ctxJQ)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render jq expr '%s': %w", p.Expr, err)
+	}
+	if strings.TrimSpace(expr) == "" {
+		expr = "."
+	}
+	f, err := compileJQ(expr, sc.env.Environ)
+	if err != nil {
+		return nil, err
+	}
+	f.raw, f.compact = p.Raw, p.Compact
+	return f, nil
+}
+
+// pipeArgv 渲染管道命令的参数 (支持模板和环境变量)
+func pipeArgv(p config.PipeConfig, sc *scope) ([]string, error) {
+	argv := []string{p.Command}
+	for _, arg := range p.Args {
+		val, err := sc.interpolate(arg, ctxNone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render pipe arg '%s': %w", arg, err)
+		}
+		argv = append(argv, val)
+	}
+	return argv, nil
+}
+
+// buildRequest 根据 APIConfig 构建 HTTP 请求，同时返回渲染后的 Body
+func buildRequest(api config.APIConfig, sc *scope) (*http.Request, *requestBody, error) {
+	method, err := sc.interpolate(api.Method, ctxNone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("render method error: %w", err)
+	}
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	rawURL, err := sc.interpolate(api.URL, ctxURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("render url error: %w", err)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid url %q: %w", rawURL, err)
+	}
+	if err := applyQueryParams(u, api.QueryParams, sc); err != nil {
+		return nil, nil, err
+	}
+
+	body, err := renderBody(api, sc)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 文件、标准输入和 multipart 在发送前由 requestBody.open 设置
+	var r io.Reader = http.NoBody
+	if body.inMemory() {
+		r = strings.NewReader(body.data)
+	}
+	req, err := http.NewRequest(method, u.String(), r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for k, v := range api.Headers {
+		val, err := sc.interpolate(v, ctxNone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("render header %s error: %w", k, err)
+		}
+		req.Header.Set(k, val)
+	}
+	body.setContentType(req.Header)
+	return req, body, nil
+}
+
+// applyQueryParams 将 query_params 合并进 URL
+// 列表值会生成重复的 key (?tag=a&tag=b)，渲染后为空的值会被忽略
+func applyQueryParams(u *url.URL, params map[string]config.StringList, sc *scope) error {
+	if len(params) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	query := u.Query()
+	for _, k := range keys {
+		for _, v := range params[k] {
+			val, err := sc.interpolate(v, ctxNone)
+			if err != nil {
+				return fmt.Errorf("render query param %s error: %w", k, err)
+			}
+			if val == "" {
+				continue
+			}
+			query.Add(k, val)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return nil
+}
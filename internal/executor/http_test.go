@@ -0,0 +1,78 @@
+package executor
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sl-cli/internal/config"
+)
+
+func TestApplyQueryParams(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.RawQuery
+	}))
+	defer srv.Close()
+
+	cfg := config.CommandConfig{Params: []config.ParamConfig{{Name: "q"}, {Name: "page"}}}
+	in := Input{Params: map[string]interface{}{"q": "a b&c", "page": ""}}
+	tests := []struct {
+		name   string
+		url    string
+		params map[string]config.StringList
+		want   string
+	}{
+		{"none", "/search", nil, ""},
+		{"encoded", "/search", map[string]config.StringList{"q": {"{{.args.q}}"}}, "q=a+b%26c"},
+		{"list values repeat the key", "/search", map[string]config.StringList{"tag": {"x", "y"}}, "tag=x&tag=y"},
+		{"empty values are skipped", "/search", map[string]config.StringList{"page": {"{{.args.page}}"}, "q": {"x"}}, "q=x"},
+		{"merged with the url query", "/search?sort=desc&tag=a", map[string]config.StringList{"tag": {"b"}, "limit": {"10"}}, "limit=10&sort=desc&tag=a&tag=b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := config.APIConfig{URL: srv.URL + tt.url, QueryParams: tt.params}
+			req, _, err := buildRequest(api, newScope(DefaultEnv(), cfg, in))
+			if err != nil {
+				t.Fatalf("buildRequest error: %v", err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got != tt.want {
+				t.Errorf("query = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRequestInterpolatesEveryField(t *testing.T) {
+	env, _, _ := testEnv(t, "SL_TEST_HOST=api.example.com", "SL_TEST_TOKEN=t0k")
+	cfg := config.CommandConfig{Params: []config.ParamConfig{{Name: "id"}, {Name: "method"}}}
+	in := Input{Params: map[string]interface{}{"id": "42", "method": "PATCH"}}
+	api := config.APIConfig{
+		URL:     "https://${SL_TEST_HOST}/items/{{.args.id}}",
+		Method:  "{{.args.method}}",
+		Headers: map[string]string{"Authorization": "Bearer ${SL_TEST_TOKEN}", "X-Item": "{{.args.id}}"},
+		Body:    `{"id": "{{.args.id}}"}`,
+	}
+	req, _, err := buildRequest(api, newScope(env, cfg, in))
+	if err != nil {
+		t.Fatalf("buildRequest error: %v", err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	for name, tt := range map[string]struct{ got, want string }{
+		"url":           {req.URL.String(), "https://api.example.com/items/42"},
+		"method":        {req.Method, "PATCH"},
+		"authorization": {req.Header.Get("Authorization"), "Bearer t0k"},
+		"header":        {req.Header.Get("X-Item"), "42"},
+		"body":          {string(body), `{"id": "42"}`},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", name, tt.got, tt.want)
+		}
+	}
+}
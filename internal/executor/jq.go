@@ -0,0 +1,135 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// ================= Builtin jq =================
+
+// jqFilter 是编译好的内置 jq 处理阶段 (基于 gojq，纯 Go 实现)
+type jqFilter struct {
+	expr    string
+	code    *gojq.Code
+	raw     bool
+	compact bool
+}
+
+// compileJQ 解析并编译 jq 表达式，语法错误会指出出错的位置
+// environ 作为 $ENV / env 的来源
+func compileJQ(expr string, environ []string) (*jqFilter, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, jqParseError(expr, err)
+	}
+	code, err := gojq.Compile(query, gojq.WithEnvironLoader(func() []string { return environ }))
+	if err != nil {
+		return nil, fmt.Errorf("jq: invalid expression %q: %w", expr, err)
+	}
+	return &jqFilter{expr: expr, code: code}, nil
+}
+
+// CheckJQ 只编译不执行，用于 config check
+func CheckJQ(expr string) error {
+	_, err := compileJQ(expr, nil)
+	return err
+}
+
+// jqParseError 在错误信息中附带表达式和出错的位置 (行、列从 1 开始)
+func jqParseError(expr string, err error) error {
+	var pe *gojq.ParseError
+	if !errors.As(err, &pe) {
+		return fmt.Errorf("jq: invalid expression %q: %w", expr, err)
+	}
+	// Offset 指向出错的 token 之后
+	pos := min(max(pe.Offset-len(pe.Token), 0), len(expr))
+	line := strings.Count(expr[:pos], "\n") + 1
+	col := pos - strings.LastIndex(expr[:pos], "\n")
+	if line > 1 {
+		return fmt.Errorf("jq: invalid expression %q: %w at line %d, column %d", expr, err, line, col)
+	}
+	return fmt.Errorf("jq: invalid expression %q: %w at column %d", expr, err, col)
+}
+
+// run 逐个读取输入中的 JSON 值 (支持多个连续的值，如 NDJSON)，输出每个结果
+// 表达式本身可能长时间运行而不读写 (如 [range(1e10)] | length)，ctx 取消时停止计算
+func (f *jqFilter) run(ctx context.Context, in io.Reader, out io.Writer) error {
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	dec := json.NewDecoder(in)
+	dec.UseNumber()
+	for {
+		var v interface{}
+		if err := dec.Decode(&v); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("jq: invalid JSON input: %w", err)
+		}
+
+		iter := f.code.RunWithContext(ctx, v)
+		for {
+			result, ok := iter.Next()
+			if !ok {
+				break
+			}
+			if err, ok := result.(error); ok {
+				return f.resultError(err)
+			}
+			if err := f.write(w, result); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// write 按 jq 的默认格式输出结果: 缩进 2 空格的 JSON，raw 时字符串原样输出
+func (f *jqFilter) write(w *bufio.Writer, v interface{}) error {
+	if s, ok := v.(string); ok && f.raw {
+		_, err := w.WriteString(s + "\n")
+		return err
+	}
+	data, err := gojq.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if !f.compact {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, "", "  "); err == nil {
+			data = buf.Bytes()
+		}
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return w.WriteByte('\n')
+}
+
+// resultError 转换执行期的错误
+// halt 会停止处理 (返回 nil 或携带退出码)，halt_error 沿用其退出码和消息
+func (f *jqFilter) resultError(err error) error {
+	var halt *gojq.HaltError
+	if !errors.As(err, &halt) {
+		return fmt.Errorf("jq: error evaluating %q: %w", f.expr, err)
+	}
+	switch v := halt.Value().(type) {
+	case nil:
+		if halt.ExitCode() == 0 {
+			return nil
+		}
+		return &ExitError{Code: halt.ExitCode(), Err: errors.New("jq: halted"), Quiet: true}
+	case string:
+		return &ExitError{Code: halt.ExitCode(), Err: errors.New(strings.TrimSuffix(v, "\n"))}
+	default:
+		data, _ := gojq.Marshal(v)
+		return &ExitError{Code: halt.ExitCode(), Err: errors.New(string(data))}
+	}
+}
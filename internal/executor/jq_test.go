@@ -0,0 +1,177 @@
+package executor
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sl-cli/internal/config"
+)
+
+func TestJQFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		raw     bool
+		compact bool
+		input   string
+		want    string
+	}{
+		{"indented by default", ".a", false, false, `{"a": {"b": [1, 2]}}`, "{\n  \"b\": [\n    1,\n    2\n  ]\n}\n"},
+		{"compact", ".a", false, true, `{"a": {"b": [1, 2]}}`, "{\"b\":[1,2]}\n"},
+		{"raw strings", ".[].name", true, false, `[{"name": "x"}, {"name": "y"}]`, "x\ny\n"},
+		{"raw keeps non-strings as json", ".[]", true, true, `["x", 1, {"a": null}]`, "x\n1\n{\"a\":null}\n"},
+		{"multiple input values", ".n", false, false, "{\"n\": 1}\n{\"n\": 2}\n", "1\n2\n"},
+		{"large numbers are preserved", ".id", false, false, `{"id": 12345678901234567890}`, "12345678901234567890\n"},
+		{"env", "$ENV.SL_TEST_JQ", true, false, "null", "from env\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := compileJQ(tt.expr, []string{"SL_TEST_JQ=from env"})
+			if err != nil {
+				t.Fatalf("compileJQ error: %v", err)
+			}
+			f.raw, f.compact = tt.raw, tt.compact
+			var out bytes.Buffer
+			if err := f.run(t.Context(), strings.NewReader(tt.input), &out); err != nil {
+				t.Fatalf("run error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestJQErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		input    string
+		wantErr  string
+		wantCode int
+	}{
+		{"parse error column", ".a | ]", "", `invalid expression ".a | ]"`, 1},
+		{"parse error position", ".a |\n  ]", "", "at line 2, column 3", 1},
+		{"unknown function", "nope(1)", "", "function not defined: nope/1", 1},
+		{"invalid json input", ".", "{", "invalid JSON input", 1},
+		{"runtime error", ".a.b", `{"a": 1}`, "error evaluating", 1},
+		{"halt_error", `"boom" | halt_error(3)`, "null", "boom", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := compileJQ(tt.expr, nil)
+			if err == nil {
+				err = f.run(t.Context(), strings.NewReader(tt.input), &bytes.Buffer{})
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+			if got := ExitCode(err); got != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", got, tt.wantCode)
+			}
+		})
+	}
+
+	// halt 不带错误时正常结束
+	f, _ := compileJQ("halt", nil)
+	if err := f.run(t.Context(), strings.NewReader("1"), &bytes.Buffer{}); err != nil {
+		t.Errorf("halt error = %v, want nil", err)
+	}
+}
+
+func TestBuiltinPipeline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items": [{"name": "cherry", "n": 3}, {"name": "apple", "n": 1}, {"name": "banana", "n": 2}]}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		pipes []config.PipeConfig
+		want  string
+	}{
+		{"builtin only", []config.PipeConfig{
+			{Builtin: "jq", Expr: ".items[].name", Raw: true},
+		}, "cherry\napple\nbanana\n"},
+		{"builtin then command", []config.PipeConfig{
+			{Builtin: "jq", Expr: ".items[].name", Raw: true},
+			{Command: "sort"},
+		}, "apple\nbanana\ncherry\n"},
+		{"command between builtins", []config.PipeConfig{
+			{Builtin: "jq", Expr: ".items[]", Compact: true},
+			{Command: "grep", Args: []string{"an"}},
+			{Builtin: "jq", Expr: `.name + "={{index .args 0}}"`, Raw: true},
+		}, "banana=x\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, stdout, _ := testEnv(t)
+			api := config.APIConfig{URL: srv.URL, Pipes: tt.pipes}
+			if err := runAPI(t, env, api, Input{Args: []string{"x"}}); err != nil {
+				t.Fatalf("run error: %v", err)
+			}
+			if stdout.String() != tt.want {
+				t.Errorf("output = %q, want %q", stdout.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinPipelineErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"a": 1}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		pipes    []config.PipeConfig
+		wantErr  string
+		wantCode int
+	}{
+		{"compile error before running", []config.PipeConfig{{Builtin: "jq", Expr: ".a |"}}, "invalid expression", 1},
+		{"unknown builtin", []config.PipeConfig{{Builtin: "yq", Expr: "."}}, "unknown builtin", 1},
+		{"failing stage after builtin", []config.PipeConfig{{Builtin: "jq", Expr: ".a"}, {Command: "sh", Args: []string{"-c", "cat >/dev/null; exit 4"}}}, "", 4},
+		{"halt_error exit code", []config.PipeConfig{{Builtin: "jq", Expr: `"stop" | halt_error(5)`}, {Command: "cat"}}, "stop", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, _, _ := testEnv(t)
+			err := runAPI(t, env, config.APIConfig{URL: srv.URL, Pipes: tt.pipes}, Input{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+			if got := ExitCode(err); got != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestValidateBuiltin(t *testing.T) {
+	tests := []struct {
+		name string
+		pipe config.PipeConfig
+		want string
+	}{
+		{"valid", config.PipeConfig{Builtin: "jq", Expr: ".items[]"}, ""},
+		{"template is checked after rendering", config.PipeConfig{Builtin: "jq", Expr: ".[{{.args.i}}]"}, ""},
+		{"invalid template", config.PipeConfig{Builtin: "jq", Expr: ".[{{.args.i]"}, "invalid expr template"},
+		{"invalid expression", config.PipeConfig{Builtin: "jq", Expr: ".a |"}, "invalid expression"},
+		{"unknown builtin", config.PipeConfig{Builtin: "yq"}, "unknown builtin 'yq'"},
+		{"command and builtin", config.PipeConfig{Builtin: "jq", Command: "jq"}, "cannot be used together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.CommandConfig{API: config.APIConfig{URL: "http://x", Pipes: []config.PipeConfig{tt.pipe}}}
+			problems := strings.Join(httpRunner{}.Validate(cfg), "; ")
+			if (tt.want == "") != (problems == "") || !strings.Contains(problems, tt.want) {
+				t.Errorf("Validate() = %q, want %q", problems, tt.want)
+			}
+		})
+	}
+}
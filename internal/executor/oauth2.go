@@ -0,0 +1,507 @@
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"sl-cli/internal/config"
+)
+
+// ================= OAuth2 =================
+
+// 支持的 OAuth2 授权流程
+const (
+	FlowClientCredentials = "client_credentials"
+	FlowDeviceCode        = "device_code"
+	FlowAuthorizationCode = "authorization_code"
+)
+
+const (
+	// tokenExpiryDelta 提前认为 token 过期，避免请求途中过期
+	tokenExpiryDelta = 30 * time.Second
+	// authorizeTimeout 是等待用户在浏览器中完成授权的最长时间
+	authorizeTimeout = 5 * time.Minute
+	// defaultRedirectURL 是 PKCE 的本地回调地址，端口 0 表示随机端口
+	defaultRedirectURL = "http://127.0.0.1:0/callback"
+)
+
+// validateOAuth2 校验 oauth2 认证配置
+func validateOAuth2(a config.AuthConfig) []string {
+	var problems []string
+	if a.TokenURL == "" {
+		problems = append(problems, "oauth2 auth requires 'token_url'")
+	}
+	if a.ClientID == "" {
+		problems = append(problems, "oauth2 auth requires 'client_id'")
+	}
+	switch a.Flow {
+	case "", FlowClientCredentials:
+		if a.ClientSecret == "" {
+			problems = append(problems, "oauth2 client_credentials flow requires 'client_secret'")
+		}
+	case FlowDeviceCode:
+		if a.DeviceURL == "" {
+			problems = append(problems, "oauth2 device_code flow requires 'device_url'")
+		}
+	case FlowAuthorizationCode:
+		if a.AuthURL == "" {
+			problems = append(problems, "oauth2 authorization_code flow requires 'auth_url'")
+		}
+		if a.RedirectURL != "" {
+			if u, err := url.Parse(a.RedirectURL); err != nil || u.Scheme != "http" || u.Port() == "" {
+				problems = append(problems, fmt.Sprintf("invalid redirect_url '%s', must be http://127.0.0.1:<port>/<path>", a.RedirectURL))
+			}
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("invalid oauth2 flow '%s', must be one of client_credentials, device_code, authorization_code", a.Flow))
+	}
+	return problems
+}
+
+// oauthToken 是缓存在磁盘上的 token
+type oauthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry"` // 零值表示没有过期时间，直到服务端返回 401
+}
+
+func (t *oauthToken) valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || now.Add(tokenExpiryDelta).Before(t.Expiry))
+}
+
+// oauthError 是 token endpoint 返回的错误 (RFC 6749 5.2)
+type oauthError struct {
+	Code        string
+	Description string
+}
+
+func (e *oauthError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth2: %s: %s", e.Code, e.Description)
+	}
+	return "oauth2: " + e.Code
+}
+
+// oauthClient 为一个 oauth2 认证配置获取、缓存和刷新 token
+type oauthClient struct {
+	creds liveCredentials
+	cfg   config.AuthConfig
+
+	secret   string // 解析后的 client_secret，设备码轮询时不必反复执行 cmd: 引用
+	resolved bool
+}
+
+// oauthToken 优先使用缓存中未过期的 token，其次用 refresh token 刷新，最后按配置的流程重新授权
+func (c liveCredentials) oauthToken(a config.AuthConfig, refresh bool) (string, error) {
+	o := &oauthClient{creds: c, cfg: a}
+	cached := o.load()
+	if !refresh && cached.valid(c.env.Now()) {
+		return cached.AccessToken, nil
+	}
+
+	var tok *oauthToken
+	var err error
+	if cached != nil && cached.RefreshToken != "" {
+		tok, err = o.refresh(cached.RefreshToken)
+	}
+	if tok == nil {
+		// 没有 refresh token 或刷新失败 (如已被吊销)，重新授权
+		if tok, err = o.obtain(); err != nil {
+			return "", err
+		}
+	}
+	if err := o.save(tok); err != nil {
+		fmt.Fprintf(c.env.Stderr, "⚠️  Failed to cache oauth2 token: %s\n", err)
+	}
+	return tok.AccessToken, nil
+}
+
+func (o *oauthClient) obtain() (*oauthToken, error) {
+	switch o.cfg.Flow {
+	case FlowDeviceCode:
+		return o.deviceCode()
+	case FlowAuthorizationCode:
+		return o.authorizationCode()
+	default:
+		return o.exchange(url.Values{"grant_type": {"client_credentials"}}, true)
+	}
+}
+
+func (o *oauthClient) refresh(refreshToken string) (*oauthToken, error) {
+	tok, err := o.exchange(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refreshToken}}, true)
+	if err != nil {
+		return nil, err
+	}
+	// 服务端可以不返回新的 refresh token，此时继续使用原来的
+	if tok.RefreshToken == "" {
+		tok.RefreshToken = refreshToken
+	}
+	return tok, nil
+}
+
+// ---------- Token Cache ----------
+
+// unsafeFileChars 匹配不适合出现在缓存文件名中的字符
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// cachePath 返回 token 缓存文件: ~/.config/sl-cli/tokens/<名称>-<hash>.json
+// hash 由 token_url、client_id、流程和 scopes 计算，修改这些配置后不会误用旧的 token
+func (o *oauthClient) cachePath() (string, error) {
+	home := o.creds.env.Getenv("HOME")
+	if home == "" {
+		var err error
+		if home, err = os.UserHomeDir(); err != nil {
+			return "", err
+		}
+	}
+	h := sha256.Sum256([]byte(strings.Join([]string{o.cfg.TokenURL, o.cfg.ClientID, o.cfg.Flow, strings.Join(o.cfg.Scopes, " ")}, "\n")))
+	name := o.cfg.Use
+	if name == "" {
+		name = "oauth2"
+	}
+	name = unsafeFileChars.ReplaceAllString(name, "_")
+	return filepath.Join(home, ".config", "sl-cli", "tokens", name+"-"+hex.EncodeToString(h[:6])+".json"), nil
+}
+
+func (o *oauthClient) load() *oauthToken {
+	path, err := o.cachePath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var tok oauthToken
+	if json.Unmarshal(data, &tok) != nil || tok.AccessToken == "" {
+		return nil
+	}
+	return &tok
+}
+
+// save 写入缓存，token 文件只有当前用户可读
+func (o *oauthClient) save(tok *oauthToken) error {
+	path, err := o.cachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(tok, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ---------- Token Endpoint ----------
+
+// exchange 向 token endpoint 请求 token
+// 配置了 client_secret 时使用 HTTP Basic 认证客户端，否则在表单中携带 client_id (公共客户端)
+func (o *oauthClient) exchange(form url.Values, withScope bool) (*oauthToken, error) {
+	if withScope && len(o.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.cfg.Scopes, " "))
+	}
+	var values map[string]interface{}
+	if err := o.post(o.cfg.TokenURL, form, &values); err != nil {
+		return nil, err
+	}
+	access, _ := values["access_token"].(string)
+	if access == "" {
+		return nil, errors.New("oauth2: token endpoint returned no access_token")
+	}
+	tok := &oauthToken{AccessToken: access}
+	tok.RefreshToken, _ = values["refresh_token"].(string)
+	if secs := number(values["expires_in"]); secs > 0 {
+		tok.Expiry = o.creds.env.Now().Add(time.Duration(secs) * time.Second)
+	}
+	return tok, nil
+}
+
+// post 以表单提交请求并解析响应 (JSON 或表单编码)，OAuth2 错误转换为 oauthError
+// token 请求不经过 --trace / --har，以免 token 出现在输出中
+func (o *oauthClient) post(endpoint string, form url.Values, out *map[string]interface{}) error {
+	if !o.resolved && o.cfg.ClientSecret != "" {
+		secret, err := o.creds.secret(o.cfg.ClientSecret)
+		if err != nil {
+			return fmt.Errorf("failed to resolve auth client_secret: %w", err)
+		}
+		o.secret, o.resolved = secret, true
+	}
+	secret := o.secret
+	if secret == "" {
+		form.Set("client_id", o.cfg.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(o.creds.ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if secret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.cfg.ClientID), url.QueryEscape(secret))
+	}
+
+	resp, err := o.creds.env.httpClient().Do(req)
+	if err != nil {
+		return redactError(err, []string{secret})
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt == "application/x-www-form-urlencoded" || mt == "text/plain" {
+		parsed, _ := url.ParseQuery(string(body))
+		for k := range parsed {
+			values[k] = parsed.Get(k)
+		}
+	} else if err := json.Unmarshal(body, &values); err != nil && resp.StatusCode < 300 {
+		return fmt.Errorf("oauth2: invalid response from %s: %w", endpoint, err)
+	}
+	if code, _ := values["error"].(string); code != "" {
+		desc, _ := values["error_description"].(string)
+		return &oauthError{Code: code, Description: desc}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("oauth2: %s returned %s", endpoint, resp.Status)
+	}
+	*out = values
+	return nil
+}
+
+// number 读取 JSON 中的数字字段，部分服务端会以字符串返回
+func number(v interface{}) int64 {
+	switch n := v.(type) {
+	case float64:
+		return int64(n)
+	case string:
+		i, _ := strconv.ParseInt(n, 10, 64)
+		return i
+	}
+	return 0
+}
+
+// ---------- Device Code (RFC 8628) ----------
+
+func (o *oauthClient) deviceCode() (*oauthToken, error) {
+	form := url.Values{}
+	if len(o.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.cfg.Scopes, " "))
+	}
+	var values map[string]interface{}
+	if err := o.post(o.cfg.DeviceURL, form, &values); err != nil {
+		return nil, err
+	}
+	deviceCode, _ := values["device_code"].(string)
+	userCode, _ := values["user_code"].(string)
+	uri, _ := values["verification_uri"].(string)
+	if uri == "" {
+		uri, _ = values["verification_url"].(string) // 部分服务端 (如 Google) 使用的字段名
+	}
+	if deviceCode == "" || uri == "" {
+		return nil, errors.New("oauth2: device authorization response is missing device_code or verification_uri")
+	}
+	interval := time.Duration(number(values["interval"])) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	expires := time.Duration(number(values["expires_in"])) * time.Second
+	if expires <= 0 {
+		expires = authorizeTimeout
+	}
+
+	env := o.creds.env
+	if complete, _ := values["verification_uri_complete"].(string); complete != "" {
+		fmt.Fprintf(env.Stderr, "To authorize sl-cli, open %s\n(or visit %s and enter code %s)\n", complete, uri, userCode)
+	} else {
+		fmt.Fprintf(env.Stderr, "To authorize sl-cli, visit %s and enter code %s\n", uri, userCode)
+	}
+
+	ctx, cancel := context.WithTimeout(o.creds.ctx, expires)
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			if o.creds.ctx.Err() != nil {
+				return nil, o.creds.ctx.Err()
+			}
+			return nil, errors.New("oauth2: device code expired before authorization completed")
+		case <-time.After(interval):
+		}
+		tok, err := o.exchange(url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {deviceCode},
+		}, false)
+		var oerr *oauthError
+		switch {
+		case err == nil:
+			return tok, nil
+		case errors.As(err, &oerr) && oerr.Code == "authorization_pending":
+		case errors.As(err, &oerr) && oerr.Code == "slow_down":
+			interval += 5 * time.Second
+		default:
+			return nil, err
+		}
+	}
+}
+
+// ---------- Authorization Code + PKCE (RFC 7636) ----------
+
+func (o *oauthClient) authorizationCode() (*oauthToken, error) {
+	redirect := o.cfg.RedirectURL
+	if redirect == "" {
+		redirect = defaultRedirectURL
+	}
+	redirectURL, err := url.Parse(redirect)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect_url: %w", err)
+	}
+	if redirectURL.Path == "" {
+		redirectURL.Path = "/"
+	}
+
+	// 在回调地址上启动本地监听，端口为 0 时由系统分配
+	ln, err := net.Listen("tcp", redirectURL.Host)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot listen on %s: %w", redirectURL.Host, err)
+	}
+	defer ln.Close()
+	redirectURL.Host = ln.Addr().String()
+
+	verifier, state := randomToken(), randomToken()
+	challenge := sha256.Sum256([]byte(verifier))
+	authURL, err := url.Parse(o.cfg.AuthURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth_url: %w", err)
+	}
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", o.cfg.ClientID)
+	q.Set("redirect_uri", redirectURL.String())
+	q.Set("state", state)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	if len(o.cfg.Scopes) > 0 {
+		q.Set("scope", strings.Join(o.cfg.Scopes, " "))
+	}
+	authURL.RawQuery = q.Encode()
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != redirectURL.Path {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		var res result
+		switch {
+		case q.Get("state") != state:
+			res.err = errors.New("oauth2: state mismatch in authorization callback")
+		case q.Get("error") != "":
+			res.err = &oauthError{Code: q.Get("error"), Description: q.Get("error_description")}
+		case q.Get("code") == "":
+			res.err = errors.New("oauth2: authorization callback has no code")
+		default:
+			res.code = q.Get("code")
+		}
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "sl-cli: authorization complete, you can close this window.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})}
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Close()
+
+	env := o.creds.env
+	fmt.Fprintf(env.Stderr, "To authorize sl-cli, open this URL in your browser:\n%s\n", authURL)
+	if isTerminal(env.Stderr) {
+		openBrowser(authURL.String())
+	}
+
+	var res result
+	select {
+	case res = <-results:
+	case <-o.creds.ctx.Done():
+		return nil, o.creds.ctx.Err()
+	case <-time.After(authorizeTimeout):
+		return nil, errors.New("oauth2: timed out waiting for authorization")
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+	return o.exchange(url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {res.code},
+		"redirect_uri":  {redirectURL.String()},
+		"code_verifier": {verifier},
+	}, false)
+}
+
+// randomToken 生成 PKCE code verifier 和 state 使用的随机字符串
+func randomToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// openBrowser 尝试用系统默认浏览器打开 URL，失败时用户仍可手动复制终端中的地址
+func openBrowser(u string) {
+	var name string
+	switch runtime.GOOS {
+	case "darwin":
+		name = "open"
+	case "windows":
+		return
+	default:
+		for _, candidate := range []string{"xdg-open", "termux-open-url"} {
+			if _, err := exec.LookPath(candidate); err == nil {
+				name = candidate
+				break
+			}
+		}
+	}
+	if name == "" {
+		return
+	}
+	cmd := exec.Command(name, u)
+	if cmd.Start() == nil {
+		go func() { _ = cmd.Wait() }()
+	}
+}
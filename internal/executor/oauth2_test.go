@@ -0,0 +1,361 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sl-cli/internal/config"
+)
+
+// tokenServer 是本地的 OAuth2 授权服务器替身，同时提供需要 Bearer token 的 /api
+type tokenServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	grants   []string        // 每次 token 请求的 grant_type
+	scopes   []string        // 每次 token 请求的 scope
+	valid    map[string]bool // 当前有效的 access token
+	issued   int
+	polls    int // 设备码流程中 token 请求的次数
+	pending  int // 设备码流程中返回 authorization_pending 的次数
+	errorFor string
+
+	// 授权码流程: /authorize 记录 PKCE challenge，并按 callback 中的参数重定向回 sl-cli
+	challenge string
+	callback  url.Values
+}
+
+func newTokenServer(t *testing.T) *tokenServer {
+	ts := &tokenServer{valid: map[string]bool{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", ts.token)
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code": "dev-1", "user_code": "ABCD-EFGH", "verification_uri": "https://example.com/device", "interval": 1,
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		ts.mu.Lock()
+		if q.Get("code_challenge_method") == "S256" && q.Get("client_id") == "cli" {
+			ts.challenge = q.Get("code_challenge")
+		}
+		back := url.Values{"code": {"code-1"}, "state": {q.Get("state")}}
+		for k, v := range ts.callback {
+			back[k] = v
+		}
+		ts.mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?"+back.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		ts.mu.Lock()
+		ok := ts.valid[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		ts.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	})
+	ts.Server = httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *tokenServer) token(w http.ResponseWriter, r *http.Request) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	_ = r.ParseForm()
+	grant := r.PostForm.Get("grant_type")
+	ts.grants = append(ts.grants, grant)
+	ts.scopes = append(ts.scopes, r.PostForm.Get("scope"))
+	w.Header().Set("Content-Type", "application/json")
+
+	fail := func(code, desc string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": desc})
+	}
+	switch grant {
+	case "client_credentials":
+		if user, pass, ok := r.BasicAuth(); !ok || user != "cli" || pass != "s3cr3t" {
+			fail("invalid_client", "bad client credentials")
+			return
+		}
+	case "refresh_token":
+		if r.PostForm.Get("refresh_token") != "refresh-1" {
+			fail("invalid_grant", "unknown refresh token")
+			return
+		}
+	case "authorization_code":
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "code-1" || ts.challenge == "" || base64.RawURLEncoding.EncodeToString(sum[:]) != ts.challenge {
+			fail("invalid_grant", "bad code or verifier")
+			return
+		}
+	case "urn:ietf:params:oauth:grant-type:device_code":
+		// 公共客户端在表单中携带 client_id
+		if r.PostForm.Get("client_id") != "cli" || r.PostForm.Get("device_code") != "dev-1" {
+			fail("invalid_request", "bad device code request")
+			return
+		}
+		if ts.polls++; ts.polls <= ts.pending {
+			fail("authorization_pending", "")
+			return
+		}
+	default:
+		fail("unsupported_grant_type", grant)
+		return
+	}
+	if ts.errorFor == grant {
+		fail("invalid_grant", "rejected by test")
+		return
+	}
+	ts.issued++
+	access := fmt.Sprintf("access-%d", ts.issued)
+	ts.valid[access] = true
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": access, "token_type": "Bearer", "expires_in": 3600, "refresh_token": "refresh-1",
+	})
+}
+
+// revokeAll 吊销已经发放的 access token，模拟服务端返回 401
+func (ts *tokenServer) revokeAll() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.valid = map[string]bool{}
+}
+
+func (ts *tokenServer) grantLog() string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return strings.Join(ts.grants, ",")
+}
+
+// oauthStep 是同一缓存下的一次执行：先调用 before (可选)，执行后检查累计的 token 请求
+type oauthStep struct {
+	before     func(ts *tokenServer, clock *time.Time)
+	wantGrants string
+	wantErr    string
+}
+
+func TestOAuth2(t *testing.T) {
+	tests := []struct {
+		name  string
+		auth  func(ts *tokenServer) config.AuthConfig
+		steps []oauthStep
+	}{
+		{
+			name: "client credentials are cached",
+			auth: func(ts *tokenServer) config.AuthConfig {
+				return config.AuthConfig{Type: AuthOAuth2, TokenURL: ts.URL + "/token", ClientID: "cli", ClientSecret: "env:CLIENT_SECRET", Scopes: config.StringList{"read", "write"}}
+			},
+			steps: []oauthStep{
+				{wantGrants: "client_credentials"},
+				{wantGrants: "client_credentials"},
+			},
+		},
+		{
+			name: "expired token is refreshed",
+			auth: func(ts *tokenServer) config.AuthConfig {
+				return config.AuthConfig{Type: AuthOAuth2, TokenURL: ts.URL + "/token", ClientID: "cli", ClientSecret: "env:CLIENT_SECRET"}
+			},
+			steps: []oauthStep{
+				{wantGrants: "client_credentials"},
+				{before: func(ts *tokenServer, clock *time.Time) { *clock = clock.Add(2 * time.Hour) }, wantGrants: "client_credentials,refresh_token"},
+			},
+		},
+		{
+			name: "revoked token is refreshed after 401",
+			auth: func(ts *tokenServer) config.AuthConfig {
+				return config.AuthConfig{Type: AuthOAuth2, TokenURL: ts.URL + "/token", ClientID: "cli", ClientSecret: "env:CLIENT_SECRET"}
+			},
+			steps: []oauthStep{
+				{wantGrants: "client_credentials"},
+				{before: func(ts *tokenServer, _ *time.Time) { ts.revokeAll() }, wantGrants: "client_credentials,refresh_token"},
+			},
+		},
+		{
+			name: "failed refresh falls back to a new grant",
+			auth: func(ts *tokenServer) config.AuthConfig {
+				return config.AuthConfig{Type: AuthOAuth2, TokenURL: ts.URL + "/token", ClientID: "cli", ClientSecret: "env:CLIENT_SECRET"}
+			},
+			steps: []oauthStep{
+				{wantGrants: "client_credentials"},
+				{
+					before: func(ts *tokenServer, clock *time.Time) {
+						ts.errorFor = "refresh_token"
+						*clock = clock.Add(2 * time.Hour)
+					},
+					wantGrants: "client_credentials,refresh_token,client_credentials",
+				},
+			},
+		},
+		{
+			name: "token endpoint error",
+			auth: func(ts *tokenServer) config.AuthConfig {
+				return config.AuthConfig{Type: AuthOAuth2, TokenURL: ts.URL + "/token", ClientID: "cli", ClientSecret: "wrong"}
+			},
+			steps: []oauthStep{
+				{wantGrants: "client_credentials", wantErr: "oauth2: invalid_client: bad client credentials"},
+			},
+		},
+		{
+			name: "device code",
+			auth: func(ts *tokenServer) config.AuthConfig {
+				return config.AuthConfig{Type: AuthOAuth2, Flow: FlowDeviceCode, TokenURL: ts.URL + "/token", DeviceURL: ts.URL + "/device", ClientID: "cli"}
+			},
+			steps: []oauthStep{
+				{
+					before:     func(ts *tokenServer, _ *time.Time) { ts.pending = 1 },
+					wantGrants: "urn:ietf:params:oauth:grant-type:device_code,urn:ietf:params:oauth:grant-type:device_code",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTokenServer(t)
+			env, stdout, stderr := testEnv(t, "CLIENT_SECRET=s3cr3t")
+			clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			env.Now = func() time.Time { return clock }
+			api := config.APIConfig{URL: ts.URL + "/api", Auth: tt.auth(ts)}
+
+			for i, step := range tt.steps {
+				if step.before != nil {
+					step.before(ts, &clock)
+				}
+				stdout.Reset()
+				err := runAPI(t, env, api, Input{})
+				if step.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), step.wantErr) {
+						t.Fatalf("step %d: error = %v, want %q", i+1, err, step.wantErr)
+					}
+				} else if err != nil {
+					t.Fatalf("step %d: run error: %v (stderr %q)", i+1, err, stderr.String())
+				} else if !strings.Contains(stdout.String(), `"ok":true`) {
+					t.Fatalf("step %d: stdout = %q", i+1, stdout.String())
+				}
+				if got := ts.grantLog(); got != step.wantGrants {
+					t.Errorf("step %d: token requests = %s, want %s", i+1, got, step.wantGrants)
+				}
+			}
+			if strings.Contains(stderr.String(), "s3cr3t") {
+				t.Errorf("stderr leaks the client secret: %q", stderr.String())
+			}
+		})
+	}
+}
+
+func TestOAuth2Scopes(t *testing.T) {
+	ts := newTokenServer(t)
+	env, _, _ := testEnv(t)
+	auth := config.AuthConfig{Type: AuthOAuth2, TokenURL: ts.URL + "/token", ClientID: "cli", ClientSecret: "s3cr3t", Scopes: config.StringList{"read", "write"}}
+	if err := runAPI(t, env, config.APIConfig{URL: ts.URL + "/api", Auth: auth}, Input{}); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if got := strings.Join(ts.scopes, "|"); got != "read write" {
+		t.Errorf("scope = %q, want %q", got, "read write")
+	}
+}
+
+// fakeBrowser 从 sl-cli 输出的提示中找到授权地址并访问它，代替用户在浏览器中完成授权
+type fakeBrowser struct {
+	mu   sync.Mutex
+	seen bool
+}
+
+func (b *fakeBrowser) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, field := range strings.Fields(string(p)) {
+		if strings.Contains(field, "/authorize?") && !b.seen {
+			b.seen = true
+			go func() {
+				if resp, err := http.Get(field); err == nil {
+					resp.Body.Close()
+				}
+			}()
+		}
+	}
+	return len(p), nil
+}
+
+func TestOAuth2AuthorizationCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		callback url.Values
+		wantErr  string
+	}{
+		{"pkce", nil, ""},
+		{"state mismatch", url.Values{"state": {"forged"}}, "state mismatch"},
+		{"denied", url.Values{"error": {"access_denied"}, "error_description": {"user said no"}}, "access_denied: user said no"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTokenServer(t)
+			ts.callback = tt.callback
+			env, stdout, _ := testEnv(t)
+			env.Stderr = &fakeBrowser{}
+			auth := config.AuthConfig{Type: AuthOAuth2, Flow: FlowAuthorizationCode, AuthURL: ts.URL + "/authorize", TokenURL: ts.URL + "/token", ClientID: "cli"}
+			err := runAPI(t, env, config.APIConfig{URL: ts.URL + "/api", Auth: auth}, Input{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run error: %v", err)
+			}
+			if !strings.Contains(stdout.String(), `"ok":true`) || ts.grantLog() != "authorization_code" {
+				t.Errorf("stdout = %q, token requests = %s", stdout, ts.grantLog())
+			}
+		})
+	}
+}
+
+func TestValidateOAuth2(t *testing.T) {
+	base := config.AuthConfig{Type: AuthOAuth2, TokenURL: "https://x/token", ClientID: "cli"}
+	with := func(f func(a *config.AuthConfig)) config.AuthConfig {
+		a := base
+		f(&a)
+		return a
+	}
+	tests := []struct {
+		name string
+		auth config.AuthConfig
+		want string
+	}{
+		{"client credentials", with(func(a *config.AuthConfig) { a.ClientSecret = "env:S" }), ""},
+		{"missing secret", base, "client_credentials flow requires 'client_secret'"},
+		{"missing token url", with(func(a *config.AuthConfig) { a.TokenURL, a.ClientSecret = "", "s" }), "requires 'token_url'"},
+		{"missing client id", with(func(a *config.AuthConfig) { a.ClientID, a.ClientSecret = "", "s" }), "requires 'client_id'"},
+		{"device code", with(func(a *config.AuthConfig) { a.Flow, a.DeviceURL = FlowDeviceCode, "https://x/device" }), ""},
+		{"device code without url", with(func(a *config.AuthConfig) { a.Flow = FlowDeviceCode }), "requires 'device_url'"},
+		{"authorization code", with(func(a *config.AuthConfig) { a.Flow, a.AuthURL = FlowAuthorizationCode, "https://x/auth" }), ""},
+		{"authorization code without url", with(func(a *config.AuthConfig) { a.Flow = FlowAuthorizationCode }), "requires 'auth_url'"},
+		{"fixed redirect port", with(func(a *config.AuthConfig) {
+			a.Flow, a.AuthURL, a.RedirectURL = FlowAuthorizationCode, "https://x/auth", "http://127.0.0.1:8085/cb"
+		}), ""},
+		{"redirect without port", with(func(a *config.AuthConfig) {
+			a.Flow, a.AuthURL, a.RedirectURL = FlowAuthorizationCode, "https://x/auth", "https://localhost/cb"
+		}), "invalid redirect_url"},
+		{"unknown flow", with(func(a *config.AuthConfig) { a.Flow = "implicit" }), "invalid oauth2 flow 'implicit'"},
+	}
+	for _, tt := range tests {
+		problems := strings.Join(validateOAuth2(tt.auth), "; ")
+		if (tt.want == "") != (problems == "") || !strings.Contains(problems, tt.want) {
+			t.Errorf("%s: validateOAuth2 = %q, want %q", tt.name, problems, tt.want)
+		}
+	}
+}
@@ -0,0 +1,516 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"sl-cli/internal/config"
+
+	"github.com/itchyny/gojq"
+	"gopkg.in/yaml.v3"
+)
+
+// ================= Output Formatter =================
+
+// 支持的输出格式
+const (
+	OutputRaw      = "raw"
+	OutputJSON     = "json"
+	OutputYAML     = "yaml"
+	OutputTable    = "table"
+	OutputCSV      = "csv"
+	OutputTemplate = "template"
+)
+
+var outputFormats = []string{OutputRaw, OutputJSON, OutputYAML, OutputTable, OutputCSV, OutputTemplate}
+
+// ApplyOutputFlag 用 --output 的值覆盖配置中的输出格式，支持:
+// raw、json、yaml、table、csv、table=<列,...>、csv=<列,...>、template=<Go 模板>
+func ApplyOutputFlag(o config.OutputConfig, flag string) (config.OutputConfig, error) {
+	format, arg, hasArg := strings.Cut(flag, "=")
+	o.Format = format
+	switch format {
+	case OutputTable, OutputCSV:
+		if hasArg {
+			o.Columns = strings.Split(arg, ",")
+		}
+	case OutputTemplate:
+		if hasArg {
+			o.Template = arg
+		}
+	case OutputRaw, OutputJSON, OutputYAML:
+		if hasArg {
+			return o, fmt.Errorf("output format %q does not take an argument", format)
+		}
+	default:
+		return o, fmt.Errorf("invalid output format %q: must be one of %s", format, strings.Join(outputFormats, ", "))
+	}
+	if problems := ValidateOutput(o); len(problems) > 0 {
+		return o, fmt.Errorf("invalid output: %s", problems[0])
+	}
+	return o, nil
+}
+
+// ValidateOutput 校验 output 配置，返回问题描述
+func ValidateOutput(o config.OutputConfig) []string {
+	var problems []string
+	switch o.Format {
+	case "", OutputRaw, OutputJSON, OutputYAML:
+	case OutputTable, OutputCSV:
+		for _, c := range o.Columns {
+			if _, err := compileColumn(c); err != nil {
+				problems = append(problems, fmt.Sprintf("invalid output column '%s': %s", c, err))
+			}
+		}
+	case OutputTemplate:
+		if o.Template == "" {
+			problems = append(problems, "output format is template but 'output.template' is missing")
+		} else if _, err := template.New("output").Funcs(outputFuncs).Parse(o.Template); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid output template: %s", err))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("invalid output format '%s', must be one of %s", o.Format, strings.Join(outputFormats, ", ")))
+	}
+	return problems
+}
+
+// outputFormatter 先收集命令的全部输出 (包括管道处理之后的结果)，命令成功后再统一格式化
+// 格式化需要完整的 JSON，且失败时要能回退为原始内容，所以输出全部缓存在内存中；
+// raw 格式不经过 formatter，直接流式写出
+type outputFormatter struct {
+	cfg   config.OutputConfig
+	out   io.Writer
+	color bool
+	buf   bytes.Buffer
+}
+
+// newOutputFormatter 在需要格式化时返回 formatter，raw 或未配置时返回 nil
+// 只有输出到终端且未设置 NO_COLOR 时才使用颜色
+func newOutputFormatter(cfg config.OutputConfig, env *Env) *outputFormatter {
+	if cfg.Format == "" || cfg.Format == OutputRaw {
+		return nil
+	}
+	return &outputFormatter{
+		cfg:   cfg,
+		out:   env.Stdout,
+		color: isTerminal(env.Stdout) && env.Getenv("NO_COLOR") == "",
+	}
+}
+
+func (f *outputFormatter) Write(p []byte) (int, error) {
+	return f.buf.Write(p)
+}
+
+// flush 输出结果: 命令失败时原样输出，格式化失败时输出原始内容并返回错误
+func (f *outputFormatter) flush(ok bool) error {
+	if !ok {
+		_, err := f.out.Write(f.buf.Bytes())
+		return err
+	}
+
+	var formatted bytes.Buffer
+	if err := f.format(&formatted); err != nil {
+		_, _ = f.out.Write(f.buf.Bytes())
+		return fmt.Errorf("failed to format output as %s: %w", f.cfg.Format, err)
+	}
+	_, err := f.out.Write(formatted.Bytes())
+	return err
+}
+
+func (f *outputFormatter) format(w *bytes.Buffer) error {
+	docs, err := splitJSON(f.buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	switch f.cfg.Format {
+	case OutputJSON:
+		for _, doc := range docs {
+			if err := writeJSON(w, doc, f.color); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OutputYAML:
+		for i, doc := range docs {
+			if i > 0 {
+				w.WriteString("---\n")
+			}
+			if err := writeYAML(w, doc); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OutputTable, OutputCSV:
+		return f.writeRows(w, docs)
+	case OutputTemplate:
+		return f.writeTemplate(w, docs)
+	}
+	return fmt.Errorf("unknown output format %q", f.cfg.Format)
+}
+
+// splitJSON 将输出拆分为一个或多个连续的 JSON 值 (支持 NDJSON)
+func splitJSON(data []byte) ([]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var docs []json.RawMessage
+	for {
+		var doc json.RawMessage
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("output is not valid JSON: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("output is empty")
+	}
+	return docs, nil
+}
+
+// decodeJSON 解码为通用值，数字保留原始精度
+func decodeJSON(doc json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	return v, err
+}
+
+// ---------- JSON ----------
+
+// jq 风格的配色
+const (
+	colorKey    = "34;1"
+	colorString = "32"
+	colorNumber = "36"
+	colorBool   = "33"
+	colorNull   = "90"
+	colorHeader = "1"
+)
+
+func paint(color, s string, enabled bool) string {
+	if !enabled {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
+
+// writeJSON 以 2 空格缩进输出 JSON，保留对象中键的原始顺序
+func writeJSON(w *bytes.Buffer, doc json.RawMessage, color bool) error {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	p := &jsonPrinter{w: w, dec: dec, color: color}
+	if err := p.value(0); err != nil {
+		return err
+	}
+	w.WriteByte('\n')
+	return nil
+}
+
+type jsonPrinter struct {
+	w     *bytes.Buffer
+	dec   *json.Decoder
+	color bool
+}
+
+func (p *jsonPrinter) value(depth int) error {
+	tok, err := p.dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		closing := byte(']')
+		if t == '{' {
+			closing = '}'
+		}
+		p.w.WriteByte(byte(t))
+		empty := true
+		for p.dec.More() {
+			if !empty {
+				p.w.WriteByte(',')
+			}
+			empty = false
+			p.newline(depth + 1)
+			if t == '{' {
+				key, err := p.dec.Token()
+				if err != nil {
+					return err
+				}
+				p.w.WriteString(paint(colorKey, jsonQuote(key.(string)), p.color))
+				p.w.WriteString(": ")
+			}
+			if err := p.value(depth + 1); err != nil {
+				return err
+			}
+		}
+		if _, err := p.dec.Token(); err != nil {
+			return err
+		}
+		if !empty {
+			p.newline(depth)
+		}
+		p.w.WriteByte(closing)
+	case string:
+		p.w.WriteString(paint(colorString, jsonQuote(t), p.color))
+	case json.Number:
+		p.w.WriteString(paint(colorNumber, t.String(), p.color))
+	case bool:
+		p.w.WriteString(paint(colorBool, fmt.Sprint(t), p.color))
+	case nil:
+		p.w.WriteString(paint(colorNull, "null", p.color))
+	}
+	return nil
+}
+
+func (p *jsonPrinter) newline(depth int) {
+	p.w.WriteByte('\n')
+	p.w.WriteString(strings.Repeat("  ", depth))
+}
+
+func jsonQuote(s string) string {
+	return `"` + jsonStringContent(s) + `"`
+}
+
+// ---------- YAML ----------
+
+// writeYAML 通过 yaml.Node 转换，保留键的顺序，并统一使用块格式输出
+func writeYAML(w *bytes.Buffer, doc json.RawMessage) error {
+	var node yaml.Node
+	if err := yaml.Unmarshal(doc, &node); err != nil {
+		return err
+	}
+	resetStyle(&node)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// resetStyle 清除 JSON 带来的 flow 风格和引号，由编码器按需加引号
+// 编码器只按 YAML 1.2 判断，yes/on、1:20 之类在 YAML 1.1 中不是字符串的值需要保留引号
+func resetStyle(n *yaml.Node) {
+	n.Style = 0
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" && isYAML11Scalar(n.Value) {
+		n.Style = yaml.DoubleQuotedStyle
+	}
+	for _, c := range n.Content {
+		resetStyle(c)
+	}
+}
+
+// yaml11Base60 匹配 YAML 1.1 的六十进制数字，如 1:20 (80) 或 1:20.5
+var yaml11Base60 = regexp.MustCompile(`^[-+]?[0-9][0-9_]*(:[0-5]?[0-9])+(\.[0-9_]*)?$`)
+
+// isYAML11Scalar 判断不加引号时 YAML 1.1 解析器是否会把字符串读成布尔值或数字
+func isYAML11Scalar(s string) bool {
+	switch s {
+	case "y", "Y", "yes", "Yes", "YES", "n", "N", "no", "No", "NO",
+		"on", "On", "ON", "off", "Off", "OFF":
+		return true
+	}
+	return yaml11Base60.MatchString(s)
+}
+
+// ---------- Table / CSV ----------
+
+// column 是 table/csv 中的一列
+type column struct {
+	header string
+	code   *gojq.Code
+}
+
+// compileColumn 编译列定义: 字段名 (name) 或 jq 路径 (.status.phase)
+func compileColumn(spec string) (*column, error) {
+	spec = strings.TrimSpace(spec)
+	expr := spec
+	if !strings.HasPrefix(expr, ".") {
+		expr = "." + jsonQuote(expr)
+	}
+	f, err := compileJQ(expr, nil)
+	if err != nil {
+		return nil, err
+	}
+	header := strings.ToUpper(strings.TrimPrefix(spec, "."))
+	if header == "" {
+		header = "VALUE"
+	}
+	return &column{header: header, code: f.code}, nil
+}
+
+// cell 计算单元格的值: 字符串原样输出，null 为空，对象和数组输出为单行 JSON
+func (c *column) cell(row interface{}) string {
+	v, ok := c.code.Run(row).Next()
+	if !ok {
+		return ""
+	}
+	switch val := v.(type) {
+	case error, nil:
+		return ""
+	case string:
+		return val
+	default:
+		data, _ := gojq.Marshal(val)
+		return string(data)
+	}
+}
+
+// rows 将输出展开为行: 数组的每个元素为一行，其他值各自为一行
+func rows(docs []json.RawMessage) ([]json.RawMessage, error) {
+	var out []json.RawMessage
+	for _, doc := range docs {
+		trimmed := bytes.TrimSpace(doc)
+		if len(trimmed) > 0 && trimmed[0] == '[' {
+			var items []json.RawMessage
+			if err := json.Unmarshal(trimmed, &items); err != nil {
+				return nil, err
+			}
+			out = append(out, items...)
+			continue
+		}
+		out = append(out, doc)
+	}
+	return out, nil
+}
+
+// objectKeys 按原始顺序返回 JSON 对象的顶层键，不是对象时返回 nil
+func objectKeys(doc json.RawMessage) []string {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return keys
+		}
+		keys = append(keys, tok.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return keys
+		}
+	}
+	return keys
+}
+
+func (f *outputFormatter) writeRows(w *bytes.Buffer, docs []json.RawMessage) error {
+	items, err := rows(docs)
+	if err != nil {
+		return err
+	}
+
+	// 未指定列时使用第一行的全部字段
+	specs := f.cfg.Columns
+	if len(specs) == 0 && len(items) > 0 {
+		specs = objectKeys(items[0])
+	}
+	if len(specs) == 0 {
+		specs = []string{"."}
+	}
+	cols := make([]*column, len(specs))
+	for i, spec := range specs {
+		if cols[i], err = compileColumn(spec); err != nil {
+			return fmt.Errorf("invalid column '%s': %w", spec, err)
+		}
+	}
+
+	records := make([][]string, 0, len(items)+1)
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.header
+	}
+	records = append(records, header)
+	for _, item := range items {
+		v, err := decodeJSON(item)
+		if err != nil {
+			return err
+		}
+		record := make([]string, len(cols))
+		for i, c := range cols {
+			record[i] = c.cell(v)
+		}
+		records = append(records, record)
+	}
+
+	if f.cfg.Format == OutputCSV {
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(records); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	// 自行计算列宽而不使用 tabwriter: 表头的颜色控制符不能计入宽度
+	widths := make([]int, len(cols))
+	for _, record := range records {
+		for j := range record {
+			// 单元格中的换行和制表符会破坏对齐
+			record[j] = strings.NewReplacer("\n", " ", "\t", " ").Replace(record[j])
+			if n := utf8.RuneCountInString(record[j]); n > widths[j] {
+				widths[j] = n
+			}
+		}
+	}
+	for i, record := range records {
+		for j, cell := range record {
+			text := cell
+			if i == 0 {
+				text = paint(colorHeader, cell, f.color)
+			}
+			w.WriteString(text)
+			if j < len(record)-1 {
+				w.WriteString(strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell)+2))
+			}
+		}
+		w.WriteByte('\n')
+	}
+	return nil
+}
+
+// ---------- Template ----------
+
+// outputFuncs 是 output 模板中可用的函数
+var outputFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := gojq.Marshal(v)
+		return string(data), err
+	},
+}
+
+// writeTemplate 用 Go 模板渲染输出，单个 JSON 值直接作为数据，多个值 (NDJSON) 作为列表
+func (f *outputFormatter) writeTemplate(w *bytes.Buffer, docs []json.RawMessage) error {
+	tmpl, err := template.New("output").Funcs(outputFuncs).Parse(f.cfg.Template)
+	if err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		if values[i], err = decodeJSON(doc); err != nil {
+			return err
+		}
+	}
+	var data interface{} = values
+	if len(values) == 1 {
+		data = values[0]
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(w.Bytes(), []byte("\n")) {
+		w.WriteByte('\n')
+	}
+	return nil
+}
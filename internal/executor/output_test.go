@@ -0,0 +1,158 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"sl-cli/internal/config"
+)
+
+const outputInput = `[
+  {"name": "api", "status": {"phase": "Running"}, "replicas": 3, "tags": ["a", "b"], "note": null},
+  {"name": "db", "status": {"phase": "Pending"}, "replicas": 12345678901234567890, "tags": [], "note": "x\ty"}
+]`
+
+func TestOutputFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		output config.OutputConfig
+		input  string
+		want   string
+	}{
+		{"raw passes through", config.OutputConfig{Format: OutputRaw}, "not json\n", "not json\n"},
+		{"json keeps key order and precision", config.OutputConfig{Format: OutputJSON}, `{"z": 1, "a": {"n": 12345678901234567890, "e": []}, "s": "<&>"}`, `{
+  "z": 1,
+  "a": {
+    "n": 12345678901234567890,
+    "e": []
+  },
+  "s": "<&>"
+}
+`},
+		{"json ndjson", config.OutputConfig{Format: OutputJSON}, "{\"a\":1}\n{\"a\":2}\n", "{\n  \"a\": 1\n}\n{\n  \"a\": 2\n}\n"},
+		{"yaml", config.OutputConfig{Format: OutputYAML}, `{"name": "api", "ports": [80, 443], "meta": {"empty": "", "n": "1"}}`, `name: api
+ports:
+  - 80
+  - 443
+meta:
+  empty: ""
+  "n": "1"
+`},
+		{"yaml ndjson", config.OutputConfig{Format: OutputYAML}, "1\n\"two\"\n", "1\n---\ntwo\n"},
+		// YAML 1.1 中的布尔值和六十进制数字保留引号，真正的布尔值不加
+		{"yaml 1.1 scalars", config.OutputConfig{Format: OutputYAML}, `{"on": "yes", "n": "NO", "t": "1:20", "b": true, "s": "yesterday"}`, `"on": "yes"
+"n": "NO"
+t: "1:20"
+b: true
+s: yesterday
+`},
+		{"table with all fields", config.OutputConfig{Format: OutputTable}, outputInput, `NAME  STATUS               REPLICAS              TAGS       NOTE
+api   {"phase":"Running"}  3                     ["a","b"]  
+db    {"phase":"Pending"}  12345678901234567890  []         x y
+`},
+		{"table with columns", config.OutputConfig{Format: OutputTable, Columns: []string{"name", ".status.phase", ".tags[0]"}}, outputInput, `NAME  STATUS.PHASE  TAGS[0]
+api   Running       a
+db    Pending       
+`},
+		{"table of scalars", config.OutputConfig{Format: OutputTable}, `["x", "y"]`, "VALUE\nx\ny\n"},
+		{"csv", config.OutputConfig{Format: OutputCSV, Columns: []string{"name", "note"}}, outputInput, "NAME,NOTE\napi,\ndb,x\ty\n"},
+		{"csv quoting", config.OutputConfig{Format: OutputCSV}, `{"a": "x,y", "b": "say \"hi\""}`, "A,B\n\"x,y\",\"say \"\"hi\"\"\"\n"},
+		{"template", config.OutputConfig{Format: OutputTemplate, Template: `{{range .}}{{.name}}={{.replicas}} {{end}}`}, outputInput, "api=3 db=12345678901234567890 \n"},
+		{"template json func", config.OutputConfig{Format: OutputTemplate, Template: `{{range .}}{{json .tags}}{{end}}`}, "{\"tags\":[1]}\n{\"tags\":[2,3]}", "[1][2,3]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, stdout, _ := testEnv(t, "SL_TEST_OUTPUT="+tt.input)
+			cfg := config.CommandConfig{Type: "shell", Script: `printf '%s' "$SL_TEST_OUTPUT"`, Output: tt.output}
+			if err := Run(context.Background(), env, cfg, Input{}); err != nil {
+				t.Fatalf("Run error: %v", err)
+			}
+			if stdout.String() != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", stdout, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		output   config.OutputConfig
+		want     string
+		wantErr  string
+		wantCode int
+	}{
+		// 输出不是 JSON 时原样输出，并报告格式化失败
+		{"not json", "echo plain", config.OutputConfig{Format: OutputJSON}, "plain\n", "output is not valid JSON", 1},
+		{"empty", "true", config.OutputConfig{Format: OutputTable}, "", "output is empty", 1},
+		// 命令失败时不格式化，保留原始输出和退出码
+		{"command failed", `echo '{"a":1}'; exit 3`, config.OutputConfig{Format: OutputYAML}, "{\"a\":1}\n", "", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, stdout, _ := testEnv(t)
+			cfg := config.CommandConfig{Type: "shell", Script: tt.script, Output: tt.output}
+			err := Run(context.Background(), env, cfg, Input{})
+			if got := ExitCode(err); got != tt.wantCode {
+				t.Fatalf("exit code = %d (%v), want %d", got, err, tt.wantCode)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+			if stdout.String() != tt.want {
+				t.Errorf("output = %q, want %q", stdout, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyOutputFlag(t *testing.T) {
+	base := config.OutputConfig{Format: OutputTable, Columns: []string{"name"}}
+	tests := []struct {
+		flag    string
+		want    config.OutputConfig
+		wantErr string
+	}{
+		{"json", config.OutputConfig{Format: OutputJSON, Columns: []string{"name"}}, ""},
+		{"table", base, ""},
+		{"csv=a,.b.c", config.OutputConfig{Format: OutputCSV, Columns: []string{"a", ".b.c"}}, ""},
+		{"template={{.a}}", config.OutputConfig{Format: OutputTemplate, Columns: []string{"name"}, Template: "{{.a}}"}, ""},
+		{"template", config.OutputConfig{}, "'output.template' is missing"},
+		{"json=x", config.OutputConfig{}, "does not take an argument"},
+		{"xml", config.OutputConfig{}, `invalid output format "xml"`},
+		{"table=.a |", config.OutputConfig{}, "invalid output column"},
+	}
+	for _, tt := range tests {
+		got, err := ApplyOutputFlag(base, tt.flag)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ApplyOutputFlag(%q) error = %v, want %q", tt.flag, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got.Format != tt.want.Format || got.Template != tt.want.Template || strings.Join(got.Columns, ",") != strings.Join(tt.want.Columns, ",") {
+			t.Errorf("ApplyOutputFlag(%q) = %+v, %v; want %+v", tt.flag, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidateOutput(t *testing.T) {
+	tests := []struct {
+		output config.OutputConfig
+		want   string
+	}{
+		{config.OutputConfig{}, ""},
+		{config.OutputConfig{Format: OutputTable, Columns: []string{"name", ".a.b"}}, ""},
+		{config.OutputConfig{Format: "xml"}, "invalid output format 'xml'"},
+		{config.OutputConfig{Format: OutputCSV, Columns: []string{".["}}, "invalid output column '.['"},
+		{config.OutputConfig{Format: OutputTemplate, Template: "{{.a"}, "invalid output template"},
+	}
+	for _, tt := range tests {
+		problems := strings.Join(ValidateOutput(tt.output), "; ")
+		if (tt.want == "") != (problems == "") || !strings.Contains(problems, tt.want) {
+			t.Errorf("ValidateOutput(%+v) = %q, want %q", tt.output, problems, tt.want)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sl-cli/internal/config"
+
+	"github.com/itchyny/gojq"
+)
+
+// ================= Pagination =================
+
+// 支持的分页方式
+const (
+	PaginateLink   = "link"
+	PaginateCursor = "cursor"
+	PaginatePage   = "page"
+	PaginateOffset = "offset"
+)
+
+// ApplyPaginateFlags 将全局 --all、--max-pages 合并到配置中；指定 --max-pages 时同样会获取后续页
+func ApplyPaginateFlags(cfg config.CommandConfig, all bool, maxPages int) (config.CommandConfig, error) {
+	if cfg.Type != "http" {
+		return cfg, fmt.Errorf("--all and --max-pages are not supported for %s commands", cfg.Type)
+	}
+	if cfg.API.Paginate.Type == "" {
+		return cfg, fmt.Errorf("--all and --max-pages require 'api.paginate' in the command config")
+	}
+	if maxPages < 0 {
+		return cfg, fmt.Errorf("invalid --max-pages %d: must not be negative", maxPages)
+	}
+	cfg.API.Paginate.All = all || maxPages > 0
+	if maxPages > 0 {
+		cfg.API.Paginate.MaxPages = maxPages
+	}
+	return cfg, nil
+}
+
+// validatePaginate 校验 api.paginate
+func validatePaginate(api config.APIConfig) []string {
+	p := api.Paginate
+	if p.Type == "" {
+		return nil
+	}
+	var problems []string
+	switch p.Type {
+	case PaginateLink, PaginatePage, PaginateOffset:
+		if p.Cursor != "" {
+			problems = append(problems, fmt.Sprintf("paginate: 'cursor' is only used with type cursor (got %s)", p.Type))
+		}
+	case PaginateCursor:
+		if p.Cursor == "" {
+			problems = append(problems, "paginate: type cursor requires 'cursor', the jq path of the next cursor such as .meta.next_cursor")
+		} else if err := CheckJQ(p.Cursor); err != nil {
+			problems = append(problems, "paginate: cursor: "+err.Error())
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("Invalid paginate type '%s'. Use link, cursor, page or offset", p.Type))
+	}
+	if p.Items != "" {
+		if err := CheckJQ(p.Items); err != nil {
+			problems = append(problems, "paginate: items: "+err.Error())
+		}
+	}
+	if p.Size < 0 || p.MaxPages < 0 {
+		problems = append(problems, "paginate: 'size' and 'max_pages' must not be negative")
+	}
+	if api.BodyFromStdin {
+		problems = append(problems, "paginate: body_from_stdin can only be sent once and cannot be paginated")
+	}
+	if api.Download.Enabled {
+		problems = append(problems, "'download' and 'paginate' cannot be used together")
+	}
+	return problems
+}
+
+// paginator 按 api.paginate 依次请求后续页，并拼接各页的结果数组
+type paginator struct {
+	cfg       config.PaginateConfig
+	items     []string   // 简单路径 (.data.items) 的各级键，保留对象中键的原始顺序
+	itemsCode *gojq.Code // 其他 jq 表达式
+	cursor    *gojq.Code
+	param     string
+	sizeParam string
+	next      int // 当前页码或偏移量
+}
+
+// simplePath 匹配只由字段名组成的 jq 路径
+var simplePath = regexp.MustCompile(`^(\.[A-Za-z_][A-Za-z0-9_]*)+$`)
+
+// newPaginator 编译 api.paginate；未配置分页或处于下载模式 (保存原始响应) 时返回 nil
+func newPaginator(api config.APIConfig, environ []string) (*paginator, error) {
+	p := api.Paginate
+	if p.Type == "" || api.Download.Enabled {
+		return nil, nil
+	}
+	pg := &paginator{cfg: p, param: p.Param, sizeParam: p.SizeParam}
+	switch p.Type {
+	case PaginateCursor:
+		f, err := compileJQ(p.Cursor, environ)
+		if err != nil {
+			return nil, fmt.Errorf("paginate: cursor: %w", err)
+		}
+		pg.cursor = f.code
+		if pg.param == "" {
+			pg.param = "cursor"
+		}
+	case PaginatePage:
+		pg.next = 1
+		if pg.param == "" {
+			pg.param = "page"
+		}
+		if pg.sizeParam == "" {
+			pg.sizeParam = "per_page"
+		}
+	case PaginateOffset:
+		if pg.param == "" {
+			pg.param = "offset"
+		}
+		if pg.sizeParam == "" {
+			pg.sizeParam = "limit"
+		}
+	case PaginateLink:
+	default:
+		return nil, fmt.Errorf("invalid paginate type %q: must be link, cursor, page or offset", p.Type)
+	}
+	if p.Start != nil {
+		pg.next = *p.Start
+	}
+
+	switch items := strings.TrimSpace(p.Items); {
+	case items == "" || items == ".":
+	case simplePath.MatchString(items):
+		pg.items = strings.Split(items, ".")[1:]
+	default:
+		f, err := compileJQ(items, environ)
+		if err != nil {
+			return nil, fmt.Errorf("paginate: items: %w", err)
+		}
+		pg.itemsCode = f.code
+	}
+	return pg, nil
+}
+
+// first 为第一页设置页码 (或偏移量) 和每页数量；URL 中已有的值优先，可以从指定的页开始
+func (p *paginator) first(u *url.URL) {
+	if p.cfg.Type != PaginatePage && p.cfg.Type != PaginateOffset {
+		return
+	}
+	q := u.Query()
+	if v := q.Get(p.param); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			p.next = n
+		}
+	} else {
+		q.Set(p.param, strconv.Itoa(p.next))
+	}
+	if p.cfg.Size > 0 && q.Get(p.sizeParam) == "" {
+		q.Set(p.sizeParam, strconv.Itoa(p.cfg.Size))
+	}
+	u.RawQuery = q.Encode()
+}
+
+// describe 返回 dry-run 中显示的分页方式
+func (p *paginator) describe() string {
+	mode := "first page only, use --all for more"
+	if p.cfg.All {
+		mode = "all pages"
+		if p.cfg.MaxPages > 0 {
+			mode = fmt.Sprintf("up to %d pages", p.cfg.MaxPages)
+		}
+	}
+	return fmt.Sprintf("# paginate: %s (%s)", p.cfg.Type, mode)
+}
+
+// sendFunc 发送请求 (分页、轮询的后续请求)，返回响应和实际发送的请求 (认证刷新后可能不同)
+// label 非空时替换 spinner 上显示的文字
+type sendFunc func(req *http.Request, label string) (*http.Response, *http.Request, error)
+
+// collect 读取第一页的响应，按配置继续请求后续页，返回拼接后的 JSON 数组
+// 后续页由 check 判断是否成功，失败时 check 负责输出 Body 并返回对应的退出码
+func (p *paginator) collect(resp *http.Response, req *http.Request, send sendFunc, check func(*http.Response) error) ([]byte, error) {
+	all := []json.RawMessage{}
+	seen := map[string]bool{req.URL.String(): true}
+	var prev []byte
+	for page := 1; ; page++ {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		items, err := p.extract(body)
+		if err != nil {
+			return nil, fmt.Errorf("paginate: page %d: %w", page, err)
+		}
+		// 服务端忽略了分页参数时每页都相同，避免无限请求
+		key, _ := json.Marshal(items)
+		if page > 1 && len(items) > 0 && bytes.Equal(key, prev) {
+			break
+		}
+		prev = key
+		all = append(all, items...)
+
+		if !p.cfg.All || (p.cfg.MaxPages > 0 && page >= p.cfg.MaxPages) {
+			break
+		}
+		next, err := p.nextURL(req.URL, resp, body, len(items))
+		if err != nil {
+			return nil, fmt.Errorf("paginate: page %d: %w", page, err)
+		}
+		if next == nil || seen[next.String()] {
+			break
+		}
+		if next.Host != req.URL.Host || next.Scheme != req.URL.Scheme {
+			// 认证信息只应发送给原来的 host，且不能从 https 降级为 http
+			return nil, fmt.Errorf("paginate: next page %s is on a different host or scheme", maskURL(next))
+		}
+		seen[next.String()] = true
+
+		nextReq, err := cloneRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		nextReq.URL = next
+		if resp, req, err = send(nextReq, fmt.Sprintf("Requesting page %d of %s", page+1, maskURL(next))); err != nil {
+			return nil, err
+		}
+		if err := check(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	out, err := json.Marshal(all)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// extract 取出一页中的结果数组；路径不存在或为 null 时视为空页
+func (p *paginator) extract(body []byte) ([]json.RawMessage, error) {
+	doc := json.RawMessage(bytes.TrimSpace(body))
+	switch {
+	case p.items != nil:
+		for _, key := range p.items {
+			if len(doc) == 0 || string(doc) == "null" {
+				break
+			}
+			var obj map[string]json.RawMessage
+			if err := json.Unmarshal(doc, &obj); err != nil {
+				return nil, fmt.Errorf("items %s: response is not a JSON object", p.cfg.Items)
+			}
+			doc = obj[key]
+		}
+	case p.itemsCode != nil:
+		v, err := decodeBody(body)
+		if err != nil {
+			return nil, err
+		}
+		result, _ := p.itemsCode.Run(v).Next()
+		if err, ok := result.(error); ok {
+			return nil, fmt.Errorf("items %s: %w", p.cfg.Items, err)
+		}
+		if doc, err = gojq.Marshal(result); err != nil {
+			return nil, err
+		}
+	}
+	if len(doc) == 0 || string(doc) == "null" {
+		return nil, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(doc, &items); err != nil {
+		if p.cfg.Items == "" {
+			return nil, fmt.Errorf("response is not a JSON array, set 'items' to the path of the result array")
+		}
+		return nil, fmt.Errorf("items %s is not an array", p.cfg.Items)
+	}
+	return items, nil
+}
+
+// nextURL 计算下一页的 URL，没有下一页时返回 nil
+func (p *paginator) nextURL(cur *url.URL, resp *http.Response, body []byte, count int) (*url.URL, error) {
+	switch p.cfg.Type {
+	case PaginateLink:
+		next := linkNext(resp.Header.Values("Link"))
+		if next == "" {
+			return nil, nil
+		}
+		u, err := cur.Parse(next)
+		if err != nil {
+			return nil, fmt.Errorf("invalid next link %q: %w", next, err)
+		}
+		return u, nil
+
+	case PaginateCursor:
+		v, err := decodeBody(body)
+		if err != nil {
+			return nil, err
+		}
+		result, _ := p.cursor.Run(v).Next()
+		var cursor string
+		switch val := result.(type) {
+		case error:
+			return nil, fmt.Errorf("cursor %s: %w", p.cfg.Cursor, val)
+		case nil, bool:
+			return nil, nil
+		case string:
+			cursor = val
+		case json.Number:
+			cursor = val.String()
+		default:
+			data, _ := gojq.Marshal(val)
+			cursor = string(data)
+		}
+		if cursor == "" {
+			return nil, nil
+		}
+		if strings.HasPrefix(cursor, "http://") || strings.HasPrefix(cursor, "https://") || strings.HasPrefix(cursor, "/") {
+			// 部分接口直接返回下一页的 URL
+			return cur.Parse(cursor)
+		}
+		return withQuery(cur, p.param, cursor), nil
+
+	case PaginatePage, PaginateOffset:
+		if count == 0 || (p.cfg.Size > 0 && count < p.cfg.Size) {
+			return nil, nil
+		}
+		if p.cfg.Type == PaginatePage {
+			p.next++
+		} else {
+			p.next += count
+		}
+		return withQuery(cur, p.param, strconv.Itoa(p.next)), nil
+	}
+	return nil, nil
+}
+
+// withQuery 返回设置了 query 参数的 URL 副本
+func withQuery(u *url.URL, key, value string) *url.URL {
+	next := *u
+	q := next.Query()
+	q.Set(key, value)
+	next.RawQuery = q.Encode()
+	return &next
+}
+
+// linkEntry 匹配 Link Header 中的一项: <url>; rel="next"; ...
+var linkEntry = regexp.MustCompile(`<([^>]*)>((?:\s*;\s*[^;,]+)*)`)
+
+// linkNext 从 Link Header (RFC 8288) 中取出 rel="next" 的 URL
+func linkNext(values []string) string {
+	for _, v := range values {
+		for _, m := range linkEntry.FindAllStringSubmatch(v, -1) {
+			for _, param := range strings.Split(m[2], ";") {
+				name, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(val), `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return m[1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// decodeBody 解析响应体供 jq 使用，数字保持原样
+func decodeBody(body []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON response: %w", err)
+	}
+	return v, nil
+}
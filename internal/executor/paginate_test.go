@@ -0,0 +1,140 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"sl-cli/internal/config"
+)
+
+// pageServer 以不同的分页方式返回 1..total，每页 2 条
+func pageServer(t *testing.T, total int, requests *atomic.Int32) *httptest.Server {
+	const size = 2
+	items := func(from int) []int {
+		out := []int{}
+		for i := from; i < from+size && i <= total; i++ {
+			out = append(out, i)
+		}
+		return out
+	}
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		q := r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/link":
+			page, _ := strconv.Atoi(q.Get("page"))
+			if page == 0 {
+				page = 1
+			}
+			if page*size < total {
+				w.Header().Set("Link", fmt.Sprintf(`</link?page=%d>; rel="next", </link?page=1>; rel="first"`, page+1))
+			}
+			json.NewEncoder(w).Encode(items((page-1)*size + 1))
+		case "/cursor":
+			from, _ := strconv.Atoi(strings.TrimPrefix(q.Get("after"), "c"))
+			from++
+			next := ""
+			if from+size <= total {
+				next = fmt.Sprintf("c%d", from+size-1)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": items(from), "meta": map[string]string{"next": next}})
+		case "/page":
+			page, _ := strconv.Atoi(q.Get("page"))
+			if q.Get("per_page") != strconv.Itoa(size) {
+				http.Error(w, "missing per_page", http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"result": map[string]interface{}{"items": items((page-1)*size + 1)}})
+		case "/offset":
+			offset, _ := strconv.Atoi(q.Get("offset"))
+			json.NewEncoder(w).Encode(items(offset + 1))
+		case "/ignored":
+			// 忽略分页参数，总是返回第一页
+			json.NewEncoder(w).Encode(items(1))
+		case "/other-host":
+			w.Header().Set("Link", `<http://other.invalid/link?page=2>; rel="next"`)
+			json.NewEncoder(w).Encode(items(1))
+		case "/other-scheme":
+			w.Header().Set("Link", "<"+strings.Replace(srv.URL, "http://", "https://", 1)+`/link?page=2>; rel="next"`)
+			json.NewEncoder(w).Encode(items(1))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		paginate     config.PaginateConfig
+		want         string
+		wantRequests int32
+		wantErr      string
+	}{
+		{"link", "/link", config.PaginateConfig{Type: PaginateLink, All: true}, "[1,2,3,4,5]", 3, ""},
+		{"first page only", "/link", config.PaginateConfig{Type: PaginateLink}, "[1,2]", 1, ""},
+		{"max pages", "/link", config.PaginateConfig{Type: PaginateLink, All: true, MaxPages: 2}, "[1,2,3,4]", 2, ""},
+		{"cursor", "/cursor", config.PaginateConfig{Type: PaginateCursor, Cursor: ".meta.next", Param: "after", Items: ".data", All: true}, "[1,2,3,4,5]", 3, ""},
+		{"page", "/page", config.PaginateConfig{Type: PaginatePage, Size: 2, Items: ".result.items", All: true}, "[1,2,3,4,5]", 3, ""},
+		{"offset", "/offset", config.PaginateConfig{Type: PaginateOffset, All: true}, "[1,2,3,4,5]", 4, ""},
+		{"identical pages", "/ignored", config.PaginateConfig{Type: PaginatePage, All: true}, "[1,2]", 2, ""},
+		{"different host", "/other-host", config.PaginateConfig{Type: PaginateLink, All: true}, "", 1, "different host or scheme"},
+		{"different scheme", "/other-scheme", config.PaginateConfig{Type: PaginateLink, All: true}, "", 1, "different host or scheme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := pageServer(t, 5, &requests)
+			env, stdout, _ := testEnv(t)
+			err := runAPI(t, env, config.APIConfig{URL: srv.URL + tt.path, Paginate: tt.paginate}, Input{})
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run error: %v", err)
+			}
+			var got []int
+			if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+				t.Fatalf("stdout is not a JSON array: %q", stdout.String())
+			}
+			if s, _ := json.Marshal(got); string(s) != tt.want {
+				t.Errorf("items = %s, want %s", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkNext(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{[]string{`<https://x/?page=2>; rel="next", <https://x/?page=9>; rel="last"`}, "https://x/?page=2"},
+		{[]string{`<https://x/?page=1>; rel="prev"`, `<https://x/?page=3>; rel=next`}, "https://x/?page=3"},
+		{[]string{`<https://x/?a=1,2>; rel="prefetch next"`}, "https://x/?a=1,2"},
+		{[]string{`<https://x/?page=9>; rel="last"`}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := linkNext(tt.values); got != tt.want {
+			t.Errorf("linkNext(%q) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"sl-cli/internal/config"
+
+	"github.com/itchyny/gojq"
+)
+
+// ================= Polling =================
+
+// defaultPollInterval 是未配置 poll.interval 时的轮询间隔
+const defaultPollInterval = 2 * time.Second
+
+// pollEnabled 判断是否配置了轮询 (设置了成功条件)
+func pollEnabled(p config.PollConfig) bool {
+	return p.Until != "" || len(p.UntilStatus) > 0
+}
+
+// validatePoll 校验 api.poll
+func validatePoll(api config.APIConfig) []string {
+	p := api.Poll
+	if !pollEnabled(p) {
+		if p.FailIf != "" || len(p.FailStatus) > 0 || p.Interval != "" || p.MaxDuration != "" || p.Progress != "" {
+			return []string{"poll: 'until' or 'until_status' is required"}
+		}
+		return nil
+	}
+	var problems []string
+	for _, d := range []struct{ name, value string }{{"interval", p.Interval}, {"max_duration", p.MaxDuration}} {
+		if _, err := parsePollDuration(d.name, d.value); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	for _, e := range []struct{ name, expr string }{{"until", p.Until}, {"fail_if", p.FailIf}, {"progress", p.Progress}} {
+		if e.expr == "" {
+			continue
+		}
+		if err := CheckJQ(e.expr); err != nil {
+			problems = append(problems, fmt.Sprintf("poll: %s: %s", e.name, err))
+		}
+	}
+	for _, s := range append(append([]string{}, p.UntilStatus...), p.FailStatus...) {
+		if !exitCodeKey.MatchString(s) {
+			problems = append(problems, fmt.Sprintf("poll: invalid status '%s'. Use a status code (200) or a class (2xx)", s))
+		}
+	}
+	if api.BodyFromStdin {
+		problems = append(problems, "poll: body_from_stdin can only be sent once and cannot be polled")
+	}
+	if api.Paginate.Type != "" || api.Download.Enabled {
+		problems = append(problems, "'poll' cannot be used together with 'paginate' or 'download'")
+	}
+	return problems
+}
+
+// parsePollDuration 解析轮询的时间配置，空字符串返回 0
+func parsePollDuration(name, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("poll: invalid %s %q: must be a positive duration such as 5s or 10m", name, s)
+	}
+	return d, nil
+}
+
+// poller 重复发送请求，直到满足成功或失败条件
+type poller struct {
+	cfg         config.PollConfig
+	env         *Env
+	interval    time.Duration
+	maxDuration time.Duration
+	until       *gojq.Code
+	failIf      *gojq.Code
+	progress    *gojq.Code
+}
+
+// newPoller 编译 api.poll；未配置轮询或处于下载模式时返回 nil
+func newPoller(api config.APIConfig, env *Env) (*poller, error) {
+	p := api.Poll
+	if !pollEnabled(p) || api.Download.Enabled {
+		return nil, nil
+	}
+	if api.Paginate.Type != "" {
+		return nil, errors.New("'poll' cannot be used together with 'paginate'")
+	}
+	pl := &poller{cfg: p, env: env}
+	var err error
+	if pl.interval, err = parsePollDuration("interval", p.Interval); err != nil {
+		return nil, err
+	}
+	if pl.interval == 0 {
+		pl.interval = defaultPollInterval
+	}
+	if pl.maxDuration, err = parsePollDuration("max_duration", p.MaxDuration); err != nil {
+		return nil, err
+	}
+	for _, e := range []struct {
+		name, expr string
+		code       **gojq.Code
+	}{{"until", p.Until, &pl.until}, {"fail_if", p.FailIf, &pl.failIf}, {"progress", p.Progress, &pl.progress}} {
+		if e.expr == "" {
+			continue
+		}
+		f, err := compileJQ(e.expr, env.Environ)
+		if err != nil {
+			return nil, fmt.Errorf("poll: %s: %w", e.name, err)
+		}
+		*e.code = f.code
+	}
+	return pl, nil
+}
+
+// describe 返回 dry-run 中显示的轮询条件
+func (p *poller) describe() string {
+	var until []string
+	if p.cfg.Until != "" {
+		until = append(until, p.cfg.Until)
+	}
+	if len(p.cfg.UntilStatus) > 0 {
+		until = append(until, "status "+strings.Join(p.cfg.UntilStatus, "|"))
+	}
+	line := fmt.Sprintf("# poll: every %s until %s", p.interval, strings.Join(until, " or "))
+	if p.maxDuration > 0 {
+		line += fmt.Sprintf(" (max %s)", p.maxDuration)
+	}
+	return line
+}
+
+// wait 检查第一次的响应，不满足条件时按间隔重新发送，返回最后一次响应的 Body
+// 既不满足条件、也不是成功状态码的响应由 check 处理 (输出 Body 并返回对应的退出码)
+// status 用于显示进度: 终端中显示在 spinner 上，否则逐行输出到 stderr
+func (p *poller) wait(ctx context.Context, resp *http.Response, req *http.Request, send sendFunc, check func(*http.Response) error, status func(string)) ([]byte, error) {
+	start := p.env.Now()
+	for attempt := 1; ; attempt++ {
+		body, done, err := p.evaluate(resp, check)
+		if err != nil || done {
+			return body, err
+		}
+
+		elapsed := p.env.Now().Sub(start)
+		msg := fmt.Sprintf("Polling %s: attempt %d, %s elapsed, %s", maskURL(req.URL), attempt, elapsed.Round(time.Second), resp.Status)
+		if v := p.progressValue(body); v != "" {
+			msg += ", " + v
+		}
+		status(msg)
+
+		wait := p.interval
+		if d, ok := retryAfter(resp.Header.Get("Retry-After"), p.env.Now()); ok {
+			wait = d
+		}
+		if p.maxDuration > 0 && elapsed+wait > p.maxDuration {
+			return nil, &ExitError{Code: timeoutExitCode, Err: fmt.Errorf("poll: condition not met after %s (%d attempts, last status %s)", p.maxDuration, attempt, resp.Status)}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		next, err := cloneRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp, req, err = send(next, ""); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// evaluate 按 fail_status、until_status、状态码、fail_if、until 的顺序判断一次响应
+func (p *poller) evaluate(resp *http.Response, check func(*http.Response) error) (body []byte, done bool, err error) {
+	defer resp.Body.Close()
+	if matchStatus(p.cfg.FailStatus, resp.StatusCode) {
+		_, _ = io.Copy(p.env.Stdout, resp.Body)
+		fmt.Fprintln(p.env.Stdout)
+		return nil, false, fmt.Errorf("poll failed: got status %s", resp.Status)
+	}
+	untilStatus := matchStatus(p.cfg.UntilStatus, resp.StatusCode)
+	if !untilStatus {
+		if err := check(resp); err != nil {
+			return nil, false, err
+		}
+	}
+	if body, err = io.ReadAll(resp.Body); err != nil {
+		return nil, false, err
+	}
+	if untilStatus {
+		return body, true, nil
+	}
+
+	if p.failIf == nil && p.until == nil {
+		return body, false, nil
+	}
+	// 任务未完成时部分接口返回空 Body (如 202)，按 null 处理
+	var v interface{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if v, err = decodeBody(body); err != nil {
+			return nil, false, fmt.Errorf("poll: %w", err)
+		}
+	}
+	if failed, err := truthy(p.failIf, v); err != nil {
+		return nil, false, fmt.Errorf("poll: fail_if: %w", err)
+	} else if failed {
+		_, _ = p.env.Stdout.Write(body)
+		fmt.Fprintln(p.env.Stdout)
+		return nil, false, fmt.Errorf("poll failed: fail_if matched (%s)", p.cfg.FailIf)
+	}
+	ok, err := truthy(p.until, v)
+	if err != nil {
+		return nil, false, fmt.Errorf("poll: until: %w", err)
+	}
+	return body, ok, nil
+}
+
+// truthy 按 jq 的规则判断条件: 第一个结果不是 false 或 null 即为真
+func truthy(code *gojq.Code, v interface{}) (bool, error) {
+	if code == nil {
+		return false, nil
+	}
+	result, ok := code.Run(v).Next()
+	if !ok {
+		return false, nil
+	}
+	if err, ok := result.(error); ok {
+		var halt *gojq.HaltError
+		if errors.As(err, &halt) {
+			return false, nil
+		}
+		return false, err
+	}
+	return result != nil && result != false, nil
+}
+
+// progressValue 计算 poll.progress，失败时不显示
+func (p *poller) progressValue(body []byte) string {
+	if p.progress == nil {
+		return ""
+	}
+	v, err := decodeBody(body)
+	if err != nil {
+		return ""
+	}
+	result, ok := p.progress.Run(v).Next()
+	if !ok {
+		return ""
+	}
+	switch val := result.(type) {
+	case error, nil:
+		return ""
+	case string:
+		return p.cfg.Progress + " = " + val
+	default:
+		data, _ := gojq.Marshal(val)
+		return p.cfg.Progress + " = " + string(data)
+	}
+}
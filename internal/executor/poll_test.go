@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sl-cli/internal/config"
+)
+
+// jobServer 前 pending 次返回 202 和 PENDING，之后返回 status 和 final
+func jobServer(t *testing.T, pending int32, status int, final string, requests *atomic.Int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if requests.Add(1) <= pending {
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"state":"PENDING"}`))
+			return
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"state":"` + final + `"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPoll(t *testing.T) {
+	tests := []struct {
+		name         string
+		pending      int32
+		status       int
+		final        string
+		poll         config.PollConfig
+		wantCode     int
+		wantRequests int32
+		wantOut      string
+	}{
+		{"until", 2, http.StatusOK, "DONE", config.PollConfig{Interval: "10ms", Until: `.state == "DONE"`}, 0, 3, `"DONE"`},
+		{"already done", 0, http.StatusOK, "DONE", config.PollConfig{Interval: "10ms", Until: `.state == "DONE"`}, 0, 1, `"DONE"`},
+		{"fail_if", 1, http.StatusOK, "FAILED", config.PollConfig{Interval: "10ms", Until: `.state == "DONE"`, FailIf: `.state == "FAILED"`}, 1, 2, `"FAILED"`},
+		{"until_status", 2, http.StatusOK, "DONE", config.PollConfig{Interval: "10ms", UntilStatus: config.StringList{"200"}}, 0, 3, `"DONE"`},
+		{"fail_status", 1, http.StatusConflict, "CONFLICT", config.PollConfig{Interval: "10ms", UntilStatus: config.StringList{"200"}, FailStatus: config.StringList{"4xx"}}, 1, 2, `"CONFLICT"`},
+		{"error status", 1, http.StatusInternalServerError, "BROKEN", config.PollConfig{Interval: "10ms", Until: `.state == "DONE"`}, 1, 2, `"BROKEN"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := jobServer(t, tt.pending, tt.status, tt.final, &requests)
+			env, stdout, stderr := testEnv(t)
+			err := runAPI(t, env, config.APIConfig{URL: srv.URL, Poll: tt.poll}, Input{})
+			if got := ExitCode(err); got != tt.wantCode {
+				t.Fatalf("exit code = %d (err %v, stderr %q), want %d", got, err, stderr.String(), tt.wantCode)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			if !strings.Contains(stdout.String(), tt.wantOut) {
+				t.Errorf("stdout = %q, want %s", stdout.String(), tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestPollTimeout(t *testing.T) {
+	var requests atomic.Int32
+	srv := jobServer(t, 1000, http.StatusOK, "DONE", &requests)
+	env, _, _ := testEnv(t)
+	poll := config.PollConfig{Interval: "10ms", MaxDuration: "50ms", Until: `.state == "DONE"`}
+	err := runAPI(t, env, config.APIConfig{URL: srv.URL, Poll: poll}, Input{})
+	if got := ExitCode(err); got != timeoutExitCode {
+		t.Fatalf("exit code = %d (err %v), want %d", got, err, timeoutExitCode)
+	}
+	if requests.Load() < 2 {
+		t.Errorf("requests = %d, want polling until max_duration", requests.Load())
+	}
+}
+
+// TestPollMaxDuration 通过 env.Now 控制时间: 每次读取时钟前进一分钟，实际只等待 interval
+func TestPollMaxDuration(t *testing.T) {
+	var requests atomic.Int32
+	srv := jobServer(t, 1000, http.StatusOK, "DONE", &requests)
+	env, _, _ := testEnv(t)
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	env.Now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+	poll := config.PollConfig{Interval: "10ms", MaxDuration: "5m", Until: `.state == "DONE"`}
+	err := runAPI(t, env, config.APIConfig{URL: srv.URL, Poll: poll}, Input{})
+	if got := ExitCode(err); got != timeoutExitCode {
+		t.Fatalf("exit code = %d (err %v), want %d", got, err, timeoutExitCode)
+	}
+	if !strings.Contains(err.Error(), "condition not met after 5m0s") {
+		t.Errorf("error = %v", err)
+	}
+	if got := requests.Load(); got < 2 || got > 5 {
+		t.Errorf("requests = %d, want 2..5 with a clock advancing a minute per read", got)
+	}
+}
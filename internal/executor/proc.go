@@ -0,0 +1,245 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// ================= Process Management =================
+
+// killGrace 是发送 SIGTERM 后等待子进程自行退出的时间，超时后强制 kill
+const killGrace = 3 * time.Second
+
+// child 是一个由 sl-cli 启动并管理的子进程，或一个在进程内运行的处理阶段 (如内置 jq)
+type child struct {
+	cmd         *exec.Cmd
+	interactive bool // 与 sl-cli 共享终端所在的进程组
+	started     bool
+	exited      bool
+	killed      bool // 由 sl-cli 主动终止，其退出状态不计入结果
+
+	// 进程内阶段: name 用于错误信息，fn 在独立的 goroutine 中运行，stop 负责让 fn 尽快返回
+	name string
+	fn   func() error
+	stop func()
+	done chan error
+}
+
+func (c *child) String() string {
+	if c.fn != nil {
+		return c.name
+	}
+	return c.cmd.Path
+}
+
+func (c *child) start() error {
+	if c.fn == nil {
+		return c.cmd.Start()
+	}
+	c.done = make(chan error, 1)
+	go func() { c.done <- c.fn() }()
+	return nil
+}
+
+func (c *child) wait() error {
+	if c.fn == nil {
+		return c.cmd.Wait()
+	}
+	return <-c.done
+}
+
+// signal 向子进程发送信号；进程内阶段没有信号的概念，收到任何信号都直接停止
+func (c *child) signal(sig os.Signal) {
+	if c.fn != nil {
+		c.stop()
+		return
+	}
+	_ = signalChild(c, sig)
+}
+
+// procGroup 管理一组子进程 (单个命令或一条管道) 的生命周期:
+//   - 非交互的子进程运行在独立的进程组中，终止时整组清理，不会遗留孙进程
+//   - 交互式子进程 (stdin 为终端) 留在 sl-cli 的前台进程组，才能正常读写终端，
+//     Ctrl-C 等终端信号会直接送达，因此只转发 SIGTERM
+//   - SIGINT/SIGTERM/SIGHUP 会转发给子进程，sl-cli 等待子进程退出后再返回
+//   - 任意一个子进程启动失败或运行失败时，终止其余子进程
+//   - ctx 取消 (如超时) 时，终止所有子进程
+type procGroup struct {
+	mu       sync.Mutex
+	children []*child
+
+	// closeAfterStart 是交给子进程的管道端，启动后父进程需要关闭自己持有的副本，
+	// 否则下游读不到 EOF
+	closeAfterStart []io.Closer
+}
+
+// add 登记一个待启动的子进程
+func (g *procGroup) add(cmd *exec.Cmd, interactive bool) {
+	setProcAttr(cmd, !interactive)
+	g.children = append(g.children, &child{cmd: cmd, interactive: interactive})
+}
+
+// addFunc 登记一个在进程内运行的处理阶段
+// stop 会在终止管道时被调用，通常通过关闭输入输出让 fn 返回
+func (g *procGroup) addFunc(name string, fn func() error, stop func()) {
+	g.children = append(g.children, &child{name: name, fn: fn, stop: stop})
+}
+
+// run 启动所有子进程并等待结束，返回最后一个 (最靠右) 失败的子进程的错误
+func (g *procGroup) run(ctx context.Context) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	stop := make(chan struct{})
+	defer func() {
+		signal.Stop(signals)
+		close(stop)
+	}()
+	go g.forward(ctx, signals, stop)
+
+	// 必须先全部 Start，再 Wait，才能形成流式处理
+	// 启动期间持有锁，避免信号转发读到尚未启动完成的子进程
+	var startErr error
+	g.mu.Lock()
+	for i, c := range g.children {
+		if err := c.start(); err != nil {
+			startErr = childExit(fmt.Errorf("failed to start command %s: %w", c, err))
+			g.children = g.children[:i]
+			break
+		}
+		c.started = true
+	}
+	g.mu.Unlock()
+	for _, closer := range g.closeAfterStart {
+		_ = closer.Close()
+	}
+	if startErr != nil || ctx.Err() != nil {
+		g.terminate()
+	}
+
+	waitErr := g.wait()
+	if startErr != nil {
+		return startErr
+	}
+	// 因 ctx 取消或超时被终止的子进程没有正常完成，不能报告为成功
+	if waitErr == nil && ctx.Err() != nil && g.interrupted() {
+		return ctx.Err()
+	}
+	return waitErr
+}
+
+// interrupted 判断是否有子进程被 sl-cli 主动终止
+func (g *procGroup) interrupted() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, c := range g.children {
+		if c.killed {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *procGroup) wait() error {
+	type result struct {
+		idx int
+		err error
+	}
+	results := make(chan result, len(g.children))
+	for i, c := range g.children {
+		go func(i int, c *child) {
+			results <- result{i, c.wait()}
+		}(i, c)
+	}
+
+	errs := make([]error, len(g.children))
+	for range g.children {
+		r := <-results
+		c := g.children[r.idx]
+		g.mu.Lock()
+		c.exited = true
+		killed := c.killed
+		g.mu.Unlock()
+		if r.err != nil && !killed {
+			errs[r.idx] = fmt.Errorf("command %s failed: %w", c, r.err)
+			g.terminate()
+		}
+	}
+
+	for i := len(errs) - 1; i >= 0; i-- {
+		if errs[i] != nil {
+			return childExit(errs[i])
+		}
+	}
+	return nil
+}
+
+// terminate 向仍在运行的子进程发送 SIGTERM，超过 killGrace 仍未退出则强制 kill
+func (g *procGroup) terminate() {
+	g.mu.Lock()
+	var alive []*child
+	for _, c := range g.children {
+		if !c.exited && !c.killed && c.started {
+			c.killed = true
+			alive = append(alive, c)
+		}
+	}
+	g.mu.Unlock()
+	if len(alive) == 0 {
+		return
+	}
+
+	for _, c := range alive {
+		c.signal(terminateSignal)
+	}
+	time.AfterFunc(killGrace, func() {
+		for _, c := range alive {
+			g.mu.Lock()
+			exited := c.exited
+			g.mu.Unlock()
+			// 进程内阶段已经在 signal 中停止，没有可以 kill 的进程
+			if !exited && c.fn == nil {
+				_ = signalChild(c, os.Kill)
+			}
+		}
+	})
+}
+
+// forward 将 sl-cli 收到的信号转发给仍在运行的子进程，ctx 取消时终止所有子进程
+func (g *procGroup) forward(ctx context.Context, signals <-chan os.Signal, stop <-chan struct{}) {
+	done := ctx.Done()
+	for {
+		select {
+		case <-done:
+			done = nil // 只需终止一次，之后继续转发信号直到子进程全部退出
+			g.terminate()
+		case sig := <-signals:
+			g.mu.Lock()
+			var targets []*child
+			for _, c := range g.children {
+				if !c.exited && c.started && (!c.interactive || sig == terminateSignal) {
+					targets = append(targets, c)
+				}
+			}
+			g.mu.Unlock()
+			for _, c := range targets {
+				c.signal(sig)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// isTerminal 判断输入或输出流是否为终端
+func isTerminal(stream interface{}) bool {
+	f, ok := stream.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,26 @@
+//go:build !unix
+
+package executor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+var terminateSignal os.Signal = os.Kill
+
+// setProcAttr 在不支持进程组的平台上不做任何处理
+func setProcAttr(cmd *exec.Cmd, ownGroup bool) {}
+
+// signalChild 在不支持 POSIX 信号的平台上只能直接结束子进程
+func signalChild(c *child, sig os.Signal) error {
+	return c.cmd.Process.Kill()
+}
+
+// execReplace 在不支持 exec 的平台上返回错误
+func execReplace(path string, argv []string, env []string) error {
+	return errors.New("exec mode is not supported on this platform")
+}
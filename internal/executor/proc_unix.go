@@ -0,0 +1,34 @@
+//go:build unix
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+
+var terminateSignal os.Signal = syscall.SIGTERM
+
+// setProcAttr 设置子进程是否运行在独立的进程组中
+func setProcAttr(cmd *exec.Cmd, ownGroup bool) {
+	if ownGroup {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+}
+
+// signalChild 向子进程发送信号; 独立进程组的子进程会连同其后代一起收到
+func signalChild(c *child, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok || c.interactive {
+		return c.cmd.Process.Signal(sig)
+	}
+	return syscall.Kill(-c.cmd.Process.Pid, s)
+}
+
+// execReplace 用目标程序替换当前 sl-cli 进程，成功时不会返回
+func execReplace(path string, argv []string, env []string) error {
+	return syscall.Exec(path, argv, env)
+}
@@ -0,0 +1,118 @@
+//go:build unix
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// waitFile 等待文件出现并返回其内容
+func waitFile(t *testing.T, path string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
+			return strings.TrimSpace(string(data))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", path)
+	return ""
+}
+
+// processAlive 判断进程是否仍在运行; 尚未被回收的僵尸进程视为已退出
+func processAlive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return true
+	}
+	fields := strings.Fields(string(data[bytes.LastIndexByte(data, ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
+
+func TestProcGroupKillsWholeGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	var g procGroup
+	// 第一个命令在后台留下一个孙进程; 第二个命令失败后整组都应被终止
+	g.add(exec.Command("/bin/sh", "-c", `sleep 30 & echo $! > "$0"; wait`, pidFile), false)
+	g.add(exec.Command("/bin/sh", "-c", `while [ ! -s "$0" ]; do sleep 0.01; done; exit 3`, pidFile), false)
+
+	start := time.Now()
+	err := g.run(context.Background())
+	if got := ExitCode(err); got != 3 {
+		t.Fatalf("exit code = %d (%v), want 3", got, err)
+	}
+	if d := time.Since(start); d > killGrace {
+		t.Errorf("run took %s, want the group terminated promptly", d)
+	}
+
+	pid, err := strconv.Atoi(waitFile(t, pidFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("grandchild %d survived the group termination", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProcGroupForwardsSignals(t *testing.T) {
+	dir := t.TempDir()
+	ready, out := filepath.Join(dir, "ready"), filepath.Join(dir, "out")
+	var g procGroup
+	g.add(exec.Command("/bin/sh", "-c", `trap 'echo hup > "$1"; exit 0' HUP; echo ok > "$0"; while :; do sleep 0.05; done`, ready, out), false)
+
+	done := make(chan error, 1)
+	go func() { done <- g.run(context.Background()) }()
+	waitFile(t, ready)
+	// sl-cli 收到的信号会转发给子进程，而不是终止 sl-cli 本身
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("run error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("child did not exit after the forwarded signal")
+	}
+	if got := waitFile(t, out); got != "hup" {
+		t.Errorf("child saw %q, want hup", got)
+	}
+}
+
+func TestProcGroupCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	var g procGroup
+	g.add(exec.Command("/bin/sh", "-c", "sleep 10"), false)
+	g.add(exec.Command("/bin/sh", "-c", "sleep 10"), false)
+	start := time.Now()
+	err := g.run(ctx)
+	if d := time.Since(start); d > killGrace {
+		t.Errorf("run took %s, want the children terminated on cancellation", d)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("run error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,309 @@
+package executor
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"sl-cli/internal/config"
+)
+
+// ================= Dry Run & Copy As =================
+
+// 支持的 --as 格式
+const (
+	RenderCurl   = "curl"
+	RenderHTTPie = "httpie"
+	RenderWget   = "wget"
+)
+
+// RenderFormats 是 --as 支持的全部格式
+var RenderFormats = []string{RenderCurl, RenderHTTPie, RenderWget}
+
+// Renderer 是 Runner 的可选接口，将命令渲染为等价的单行命令 (如 curl)，便于粘贴到工单中
+type Renderer interface {
+	RenderAs(env *Env, cfg config.CommandConfig, in Input, format string) (string, error)
+}
+
+// RenderAs 将命令渲染为指定格式的单行命令，env 为 nil 时使用当前进程的环境
+func RenderAs(env *Env, cfg config.CommandConfig, in Input, format string) (string, error) {
+	r, ok := Lookup(cfg.Type)
+	if !ok {
+		return "", fmt.Errorf("unknown command type: %s", cfg.Type)
+	}
+	rr, ok := r.(Renderer)
+	if !ok {
+		return "", fmt.Errorf("--as is not supported for %s commands", cfg.Type)
+	}
+	return rr.RenderAs(env.withDefaults(), cfg, in, format)
+}
+
+// RenderAs 输出等价的 curl/httpie/wget 命令，管道阶段以 | 连接在后面
+func (httpRunner) RenderAs(env *Env, cfg config.CommandConfig, in Input, format string) (string, error) {
+	sc := newScope(env, cfg, in)
+	req, body, err := buildRequest(cfg.API, sc)
+	if err != nil {
+		return "", err
+	}
+	// 只渲染第一页的请求
+	pager, err := newPaginator(cfg.API, env.Environ)
+	if err != nil {
+		return "", err
+	}
+	if pager != nil {
+		pager.first(req.URL)
+	}
+	if err := applyMaskedAuth(env, cfg.API, req, sc); err != nil {
+		return "", err
+	}
+	if body.kind == bodyMultipart {
+		// 由目标工具生成 boundary
+		req.Header.Del("Content-Type")
+	}
+
+	var argv []string
+	suffix := ""
+	switch format {
+	case RenderCurl:
+		argv = curlArgv(req, body)
+	case RenderHTTPie:
+		argv = httpieArgv(req, body)
+		if body.kind == bodyFile {
+			// httpie 从标准输入读取 Body
+			suffix = " < " + shellJoin([]string{body.path})
+		}
+	case RenderWget:
+		if body.kind == bodyMultipart {
+			return "", fmt.Errorf("--as wget does not support multipart")
+		}
+		argv = wgetArgv(req, body)
+	default:
+		return "", fmt.Errorf("invalid --as format %q: must be one of %s", format, strings.Join(RenderFormats, ", "))
+	}
+	extra, err := transportArgs(format, cfg.API, req.URL)
+	if err != nil {
+		return "", err
+	}
+	dl, err := newDownload(cfg.API, sc, req.URL)
+	if err != nil {
+		return "", err
+	}
+	if dl != nil {
+		args, err := downloadArgs(format, dl)
+		if err != nil {
+			return "", err
+		}
+		if format == RenderWget {
+			argv[1] = "-q" // 替换 -qO-，不再输出到 stdout
+		}
+		extra = append(extra, args...)
+	}
+	argv = append(argv[:1], append(extra, argv[1:]...)...)
+
+	// 下载模式下响应保存为文件，不经过管道
+	var pipes []string
+	if dl == nil {
+		if pipes, err = pipeLines(cfg.API.Pipes, sc); err != nil {
+			return "", err
+		}
+	}
+	line := shellJoin(argv) + suffix
+	for _, p := range pipes {
+		line += " | " + p
+	}
+	return line + "\n", nil
+}
+
+func curlArgv(req *http.Request, body *requestBody) []string {
+	argv := []string{"curl"}
+	if req.Method != http.MethodGet || body.kind != bodyRaw || body.data != "" {
+		argv = append(argv, "-X", req.Method)
+	}
+	for _, k := range sortedHeaderKeys(req.Header) {
+		for _, v := range req.Header[k] {
+			argv = append(argv, "-H", k+": "+maskHeader(k, v))
+		}
+	}
+	switch body.kind {
+	case bodyMultipart:
+		for _, p := range body.parts {
+			if p.file != "" {
+				argv = append(argv, "-F", p.name+"=@"+p.file)
+			} else {
+				argv = append(argv, "--form-string", p.name+"="+p.value)
+			}
+		}
+	case bodyFile:
+		argv = append(argv, "--data-binary", "@"+body.path)
+	case bodyStdin:
+		argv = append(argv, "--data-binary", "@-")
+	default:
+		if body.data != "" {
+			argv = append(argv, "--data-raw", body.data)
+		}
+	}
+	return append(argv, maskURL(req.URL))
+}
+
+func httpieArgv(req *http.Request, body *requestBody) []string {
+	argv := []string{"http"}
+	switch body.kind {
+	case bodyMultipart:
+		argv = append(argv, "--multipart")
+	case bodyFile, bodyStdin:
+		// Body 由标准输入提供 (body_file 重定向到 stdin)
+	default:
+		if body.data != "" {
+			argv = append(argv, "--raw", body.data)
+		}
+	}
+	argv = append(argv, req.Method, maskURL(req.URL))
+	for _, k := range sortedHeaderKeys(req.Header) {
+		for _, v := range req.Header[k] {
+			argv = append(argv, k+":"+maskHeader(k, v))
+		}
+	}
+	for _, p := range body.parts {
+		if p.file != "" {
+			argv = append(argv, p.name+"@"+p.file)
+		} else {
+			argv = append(argv, p.name+"="+p.value)
+		}
+	}
+	return argv
+}
+
+func wgetArgv(req *http.Request, body *requestBody) []string {
+	argv := []string{"wget", "-qO-"}
+	if req.Method != http.MethodGet {
+		argv = append(argv, "--method="+req.Method)
+	}
+	for _, k := range sortedHeaderKeys(req.Header) {
+		for _, v := range req.Header[k] {
+			argv = append(argv, "--header="+k+": "+maskHeader(k, v))
+		}
+	}
+	switch body.kind {
+	case bodyFile:
+		argv = append(argv, "--body-file="+body.path)
+	case bodyStdin:
+		argv = append(argv, "--body-file=/dev/stdin")
+	default:
+		if body.data != "" {
+			argv = append(argv, "--body-data="+body.data)
+		}
+	}
+	return append(argv, maskURL(req.URL))
+}
+
+// pipeLines 渲染管道阶段的命令行；内置 jq 渲染为等价的 jq 命令
+func pipeLines(pipes []config.PipeConfig, sc *scope) ([]string, error) {
+	lines := make([]string, 0, len(pipes))
+	for _, p := range pipes {
+		if p.Builtin != "" {
+			f, err := builtinFilter(p, sc)
+			if err != nil {
+				return nil, err
+			}
+			argv := []string{p.Builtin}
+			if f.raw {
+				argv = append(argv, "-r")
+			}
+			if f.compact {
+				argv = append(argv, "-c")
+			}
+			lines = append(lines, shellJoin(append(argv, f.expr)))
+			continue
+		}
+		argv, err := pipeArgv(p, sc)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, shellJoin(argv))
+	}
+	return lines, nil
+}
+
+func sortedHeaderKeys(h http.Header) []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ---------- Secret Masking ----------
+
+// maskedValue 替代被隐藏的敏感值
+const maskedValue = "****"
+
+// secretWords 出现在 Header 或 query 参数名中时，认为其值是敏感信息
+var secretWords = []string{"auth", "token", "secret", "password", "passwd", "apikey", "credential", "session", "cookie", "signature"}
+
+// isSecretName 判断 Header 或 query 参数名是否可能携带敏感信息
+func isSecretName(name string) bool {
+	n := strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(name))
+	if n == "key" || n == "sig" {
+		return true
+	}
+	for _, w := range secretWords {
+		if strings.Contains(n, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskHeader 隐藏敏感 Header 的值；Authorization 保留认证方案 (如 "Bearer ****")
+// Referer、Location 等值为 URL 的 Header 按 maskURL 处理
+func maskHeader(name, value string) string {
+	switch strings.ToLower(name) {
+	case "referer", "location", "content-location":
+		if u, err := url.Parse(value); err == nil {
+			return maskURL(u)
+		}
+	}
+	if !isSecretName(name) || value == "" {
+		return value
+	}
+	if scheme, _, ok := strings.Cut(value, " "); ok && strings.Contains(strings.ToLower(name), "authorization") {
+		return scheme + " " + maskedValue
+	}
+	return maskedValue
+}
+
+// maskURL 隐藏 URL 中的密码和敏感 query 参数，其余部分保持原样
+func maskURL(u *url.URL) string {
+	masked := *u
+	userinfo := ""
+	if masked.User != nil {
+		if _, ok := masked.User.Password(); ok {
+			// 直接交给 url.URL 会把 * 转义为 %2A，这里手工拼接
+			userinfo = url.User(masked.User.Username()).String() + ":" + maskedValue + "@"
+			masked.User = nil
+		}
+	}
+	if masked.RawQuery != "" {
+		parts := strings.Split(masked.RawQuery, "&")
+		for i, part := range parts {
+			k, _, ok := strings.Cut(part, "=")
+			name, err := url.QueryUnescape(k)
+			if err != nil {
+				name = k
+			}
+			if ok && isSecretName(name) {
+				parts[i] = k + "=" + maskedValue
+			}
+		}
+		masked.RawQuery = strings.Join(parts, "&")
+	}
+	if userinfo != "" {
+		if scheme, rest, ok := strings.Cut(masked.String(), "://"); ok {
+			return scheme + "://" + userinfo + rest
+		}
+	}
+	return masked.String()
+}
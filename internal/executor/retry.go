@@ -0,0 +1,225 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"sl-cli/internal/config"
+)
+
+// ================= HTTP Retry =================
+
+const (
+	defaultBackoff    = time.Second
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// defaultRetryStatus 是未配置 on_status 时触发重试的状态码
+var defaultRetryStatus = []string{"429", "502", "503", "504"}
+
+// retryPolicy 是解析后的 api.retry
+type retryPolicy struct {
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	onStatus   []string
+}
+
+// parseRetry 解析重试配置，未配置时 attempts 为 1 (不重试)
+func parseRetry(rc config.RetryConfig) (retryPolicy, error) {
+	p := retryPolicy{attempts: rc.Attempts, backoff: defaultBackoff, maxBackoff: defaultMaxBackoff, onStatus: rc.OnStatus}
+	if p.attempts < 1 {
+		p.attempts = 1
+	}
+	if rc.Backoff != "" {
+		d, err := time.ParseDuration(rc.Backoff)
+		if err != nil || d < 0 {
+			return p, fmt.Errorf("invalid retry.backoff %q", rc.Backoff)
+		}
+		p.backoff = d
+	}
+	if rc.MaxBackoff != "" {
+		d, err := time.ParseDuration(rc.MaxBackoff)
+		if err != nil || d < 0 {
+			return p, fmt.Errorf("invalid retry.max_backoff %q", rc.MaxBackoff)
+		}
+		p.maxBackoff = d
+	}
+	if len(p.onStatus) == 0 {
+		p.onStatus = defaultRetryStatus
+	}
+	return p, nil
+}
+
+// validateRetry 校验 api.retry 和 api.success_status，供 config check 使用
+func validateRetry(api config.APIConfig) []string {
+	var problems []string
+	if api.Retry.Attempts < 0 {
+		problems = append(problems, "retry.attempts must not be negative")
+	}
+	if _, err := parseRetry(api.Retry); err != nil {
+		problems = append(problems, err.Error())
+	}
+	for _, s := range api.Retry.OnStatus {
+		if !exitCodeKey.MatchString(s) {
+			problems = append(problems, fmt.Sprintf("Invalid retry.on_status '%s'. Use a status code (503) or a class (5xx)", s))
+		}
+	}
+	for _, s := range api.SuccessStatus {
+		if !exitCodeKey.MatchString(s) {
+			problems = append(problems, fmt.Sprintf("Invalid success_status '%s'. Use a status code (404) or a class (2xx)", s))
+		}
+	}
+	return problems
+}
+
+// matchStatus 判断状态码是否命中列表中的状态码 ("404") 或类别 ("4xx")
+func matchStatus(patterns []string, status int) bool {
+	code, class := strconv.Itoa(status), fmt.Sprintf("%dxx", status/100)
+	for _, p := range patterns {
+		if p == code || p == class {
+			return true
+		}
+	}
+	return false
+}
+
+// isSuccess 判断响应是否成功，未配置 success_status 时只有 2xx 视为成功
+func isSuccess(api config.APIConfig, status int) bool {
+	if len(api.SuccessStatus) == 0 {
+		return status >= 200 && status < 300
+	}
+	return matchStatus(api.SuccessStatus, status)
+}
+
+// idempotent 判断请求能否在连接错误后安全地重新发送
+// 连接错误时无法确定服务端是否已经处理了请求，POST、PATCH 只有带 Idempotency-Key 或开启 non_idempotent 时才重试
+func idempotent(req *http.Request, rc config.RetryConfig) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return rc.NonIdempotent || req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// doWithRetry 发送请求，遇到连接错误或 on_status 中的状态码时按策略重试
+// notify 在每次等待前被调用，用于提示用户
+func doWithRetry(ctx context.Context, env *Env, client *http.Client, req *http.Request, api config.APIConfig, notify func(string)) (*http.Response, error) {
+	policy, err := parseRetry(api.Retry)
+	if err != nil {
+		return nil, err
+	}
+	if !canReplay(req) {
+		// 标准输入等流式 Body 只能发送一次
+		policy.attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		r := req
+		if attempt > 1 {
+			if r, err = cloneRequest(req); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := client.Do(r)
+
+		var reason string
+		var wait time.Duration
+		hasWait := false
+		switch {
+		case err != nil:
+			// 超时或用户取消时不再重试
+			if ctx.Err() != nil || errors.Is(err, context.Canceled) || !idempotent(req, api.Retry) {
+				return nil, err
+			}
+			reason = err.Error()
+		case !isSuccess(api, resp.StatusCode) && matchStatus(policy.onStatus, resp.StatusCode):
+			reason = resp.Status
+			wait, hasWait = retryAfter(resp.Header.Get("Retry-After"), env.Now())
+			// Retry-After 同样不超过 max_backoff，避免服务端让命令挂起数小时
+			wait = min(wait, policy.maxBackoff)
+		default:
+			return resp, nil
+		}
+
+		if attempt >= policy.attempts {
+			return resp, err
+		}
+		if resp != nil {
+			// 读完并关闭 Body 才能复用连接
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if !hasWait {
+			wait = policy.delay(attempt)
+		}
+		notify(fmt.Sprintf("%s, retrying in %s (attempt %d/%d)", reason, wait.Round(time.Millisecond), attempt+1, policy.attempts))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// cloneRequest 复制请求用于重新发送，原请求的 Body 已被读取，需要一份新的
+func cloneRequest(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+// canReplay 判断请求能否重新发送: 没有 Body，或 Body 可以通过 GetBody 重新读取
+func canReplay(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// delay 计算第 attempt 次失败后的等待时间: backoff * 2^(attempt-1)，不超过 max_backoff，
+// 再在 [d/2, d] 之间随机取值，避免大量客户端同时重试
+func (p retryPolicy) delay(attempt int) time.Duration {
+	d := p.backoff
+	for i := 1; i < attempt && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryAfter 解析 Retry-After (秒数或 HTTP 日期)，没有或无法解析时 ok 为 false
+func retryAfter(value string, now time.Time) (d time.Duration, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	// 超出范围的秒数按最大值处理 (Atoi 返回截断后的值)，先限制范围再相乘，避免溢出为负数
+	if secs, err := strconv.Atoi(value); err == nil || errors.Is(err, strconv.ErrRange) {
+		const maxSecs = int(math.MaxInt64 / time.Second)
+		return time.Duration(min(max(secs, 0), maxSecs)) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
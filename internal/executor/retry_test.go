@@ -0,0 +1,258 @@
+package executor
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"sl-cli/internal/config"
+)
+
+func TestRetryOnStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int32 // 前几次返回 503
+		retry        config.RetryConfig
+		method       string
+		wantAttempts int32
+		wantCode     int
+	}{
+		{"no retry by default", 1, config.RetryConfig{}, http.MethodGet, 1, 1},
+		{"recovers", 2, config.RetryConfig{Attempts: 3, Backoff: "1ms"}, http.MethodGet, 3, 0},
+		{"gives up", 5, config.RetryConfig{Attempts: 3, Backoff: "1ms"}, http.MethodGet, 3, 1},
+		{"post retried on status", 1, config.RetryConfig{Attempts: 2, Backoff: "1ms"}, http.MethodPost, 2, 0},
+		{"status not in on_status", 1, config.RetryConfig{Attempts: 3, Backoff: "1ms", OnStatus: config.StringList{"429"}}, http.MethodGet, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1) <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte(`{"ok":true}`))
+			}))
+			defer srv.Close()
+
+			env, stdout, _ := testEnv(t)
+			err := runAPI(t, env, config.APIConfig{URL: srv.URL, Method: tt.method, Body: `{"a":1}`, Retry: tt.retry}, Input{})
+			if got := ExitCode(err); got != tt.wantCode {
+				t.Fatalf("exit code = %d (err %v), want %d", got, err, tt.wantCode)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+			if tt.wantCode == 0 && !strings.Contains(stdout.String(), `"ok":true`) {
+				t.Errorf("stdout = %q", stdout.String())
+			}
+		})
+	}
+}
+
+// flakyListener 关闭前 n 个连接，模拟连接被重置
+type flakyListener struct {
+	net.Listener
+	drop atomic.Int32
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil || l.drop.Add(-1) < 0 {
+			return c, err
+		}
+		c.Close()
+	}
+}
+
+func TestRetryConnectionErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		retry    config.RetryConfig
+		wantCode int
+	}{
+		{"get is retried", http.MethodGet, nil, config.RetryConfig{Attempts: 2, Backoff: "1ms"}, 0},
+		{"no retry by default", http.MethodGet, nil, config.RetryConfig{}, 1},
+		{"put is retried", http.MethodPut, nil, config.RetryConfig{Attempts: 2, Backoff: "1ms"}, 0},
+		{"post is not retried", http.MethodPost, nil, config.RetryConfig{Attempts: 2, Backoff: "1ms"}, 1},
+		{"patch is not retried", http.MethodPatch, nil, config.RetryConfig{Attempts: 2, Backoff: "1ms"}, 1},
+		{"post with non_idempotent", http.MethodPost, nil, config.RetryConfig{Attempts: 2, Backoff: "1ms", NonIdempotent: true}, 0},
+		{"post with idempotency key", http.MethodPost, map[string]string{"Idempotency-Key": "k1"}, config.RetryConfig{Attempts: 2, Backoff: "1ms"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			}))
+			ln := &flakyListener{Listener: srv.Listener}
+			ln.drop.Store(1)
+			srv.Listener = ln
+			srv.Start()
+			defer srv.Close()
+
+			env, _, stderr := testEnv(t)
+			api := config.APIConfig{URL: srv.URL, Method: tt.method, Headers: tt.headers, Body: "x", Retry: tt.retry}
+			// 关闭连接复用，保证每次尝试都建立新连接
+			env.Transport = &http.Transport{DisableKeepAlives: true}
+			err := runAPI(t, env, api, Input{})
+			if got := ExitCode(err); got != tt.wantCode {
+				t.Fatalf("exit code = %d (err %v, stderr %q), want %d", got, err, stderr.String(), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, true},
+		// 乘以 time.Second 会溢出的值限制为最大的 Duration，而不是变成负数或很短的等待
+		{"9300000000", time.Duration(math.MaxInt64/time.Second) * time.Second, true},
+		{"99999999999999999999", time.Duration(math.MaxInt64/time.Second) * time.Second, true},
+		{"-99999999999999999999", 0, true},
+		{"Mon, 01 Jan 2024 00:00:30 GMT", 30 * time.Second, true},
+		{"Sun, 31 Dec 2023 23:59:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("retryAfter(%q) = %s, %v; want %s, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := retryPolicy{backoff: 100 * time.Millisecond, maxBackoff: time.Second}
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{10, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := p.delay(tt.attempt); d < tt.max/2 || d > tt.max {
+				t.Fatalf("delay(%d) = %s, want between %s and %s", tt.attempt, d, tt.max/2, tt.max)
+			}
+		}
+	}
+}
+
+func TestRetryAfterClampedToMaxBackoff(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	env, _, stderr := testEnv(t)
+	start := time.Now()
+	err := runAPI(t, env, config.APIConfig{URL: srv.URL, Retry: config.RetryConfig{Attempts: 2, MaxBackoff: "10ms"}}, Input{})
+	if err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("waited %s, Retry-After should be clamped to max_backoff", elapsed)
+	}
+	if !strings.Contains(stderr.String(), "retrying in 10ms") {
+		t.Errorf("stderr = %q, want retry notice with 10ms", stderr.String())
+	}
+}
+
+func TestSuccessStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"found":false}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		success  config.StringList
+		wantCode int
+	}{
+		{"default only 2xx", nil, 1},
+		{"exact status", config.StringList{"200", "404"}, 0},
+		{"status class", config.StringList{"2xx", "4xx"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, stdout, _ := testEnv(t)
+			err := runAPI(t, env, config.APIConfig{URL: srv.URL, SuccessStatus: tt.success}, Input{})
+			if got := ExitCode(err); got != tt.wantCode {
+				t.Fatalf("exit code = %d (err %v), want %d", got, err, tt.wantCode)
+			}
+			// 不论是否视为成功，响应 Body 都会输出
+			if stdout.String() != "{\"found\":false}\n" {
+				t.Errorf("stdout = %q", stdout)
+			}
+		})
+	}
+}
+
+func TestRetryStopsAtTimeout(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	env, _, _ := testEnv(t)
+	cfg := config.CommandConfig{Type: "http", Timeout: "100ms", API: config.APIConfig{URL: srv.URL, Retry: config.RetryConfig{Attempts: 100, Backoff: "1s"}}}
+	start := time.Now()
+	err := Run(context.Background(), env, cfg, Input{})
+	if got := ExitCode(err); got != timeoutExitCode {
+		t.Fatalf("exit code = %d (err %v), want %d", got, err, timeoutExitCode)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Run took %s, the backoff wait should stop at the timeout", d)
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
+
+func TestValidateRetry(t *testing.T) {
+	tests := []struct {
+		api  config.APIConfig
+		want string
+	}{
+		{config.APIConfig{Retry: config.RetryConfig{Attempts: 3, Backoff: "200ms", MaxBackoff: "5s", OnStatus: config.StringList{"429", "5xx"}}, SuccessStatus: config.StringList{"404"}}, ""},
+		{config.APIConfig{Retry: config.RetryConfig{Attempts: -1}}, "retry.attempts must not be negative"},
+		{config.APIConfig{Retry: config.RetryConfig{Backoff: "fast"}}, `invalid retry.backoff "fast"`},
+		{config.APIConfig{Retry: config.RetryConfig{MaxBackoff: "-1s"}}, `invalid retry.max_backoff "-1s"`},
+		{config.APIConfig{Retry: config.RetryConfig{OnStatus: config.StringList{"5XX"}}}, "Invalid retry.on_status '5XX'"},
+		{config.APIConfig{SuccessStatus: config.StringList{"600"}}, "Invalid success_status '600'"},
+	}
+	for _, tt := range tests {
+		problems := strings.Join(validateRetry(tt.api), "; ")
+		if (tt.want == "") != (problems == "") || !strings.Contains(problems, tt.want) {
+			t.Errorf("validateRetry(%+v) = %q, want %q", tt.api, problems, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"sl-cli/internal/config"
+)
+
+// ================= Runner Registry =================
+
+// Runner 实现一种命令类型 (配置中的 type)
+// 内置 http、shell、system 三种类型；其他类型可以通过 Register 由 Go 代码或插件注册
+type Runner interface {
+	// Run 执行命令
+	Run(ctx context.Context, env *Env, cfg config.CommandConfig, in Input) error
+
+	// Validate 校验该类型特有的配置字段，返回问题描述 (供 config check 输出)
+	Validate(cfg config.CommandConfig) []string
+
+	// DryRun 渲染将要执行的内容 (请求、脚本或命令行)，但不实际执行
+	DryRun(env *Env, cfg config.CommandConfig, in Input) (string, error)
+
+	// Complete 为位置参数提供补全候选，返回 nil 时使用默认的文件名补全
+	Complete(cfg config.CommandConfig, args []string, toComplete string) []string
+}
+
+// ArgsPassthrough 是 Runner 的可选接口
+// PassthroughArgs 返回 true 时，未声明 flags 的命令不解析 flag，
+// 多余的位置参数也会原样交给 Runner (例如 shell 的 "$@"、system 的追加参数)
+type ArgsPassthrough interface {
+	PassthroughArgs() bool
+}
+
+// OwnOutput 是 Runner 的可选接口
+// WritesOwnOutput 返回 true 时命令不把结果写到 stdout (例如 http 下载模式把响应写入文件)，
+// 此时不应用 output 格式化
+type OwnOutput interface {
+	WritesOwnOutput(cfg config.CommandConfig) bool
+}
+
+var (
+	runnersMu sync.RWMutex
+	runners   = make(map[string]Runner)
+)
+
+// Register 注册一种命令类型，重复注册同一类型会 panic
+func Register(typ string, r Runner) {
+	runnersMu.Lock()
+	defer runnersMu.Unlock()
+	if typ == "" || r == nil {
+		panic("executor: Register with empty type or nil runner")
+	}
+	if _, dup := runners[typ]; dup {
+		panic("executor: Register called twice for type " + typ)
+	}
+	runners[typ] = r
+}
+
+// Lookup 返回类型对应的 Runner
+func Lookup(typ string) (Runner, bool) {
+	runnersMu.RLock()
+	defer runnersMu.RUnlock()
+	r, ok := runners[typ]
+	return r, ok
+}
+
+// Types 返回已注册的全部类型 (已排序)
+func Types() []string {
+	runnersMu.RLock()
+	defer runnersMu.RUnlock()
+	types := make([]string, 0, len(runners))
+	for t := range runners {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// PassthroughArgs 判断类型是否原样透传参数，见 ArgsPassthrough
+func PassthroughArgs(typ string) bool {
+	r, ok := Lookup(typ)
+	if !ok {
+		return false
+	}
+	p, ok := r.(ArgsPassthrough)
+	return ok && p.PassthroughArgs()
+}
+
+// DryRun 渲染命令将要执行的内容，env 为 nil 时使用当前进程的环境
+func DryRun(env *Env, cfg config.CommandConfig, in Input) (string, error) {
+	r, ok := Lookup(cfg.Type)
+	if !ok {
+		return "", fmt.Errorf("unknown command type: %s", cfg.Type)
+	}
+	return r.DryRun(env.withDefaults(), cfg, in)
+}
@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"sl-cli/internal/config"
+)
+
+// stubRunner 记录收到的调用，用于测试注册表的分发
+type stubRunner struct {
+	ran *Input
+}
+
+func (s stubRunner) Run(ctx context.Context, env *Env, cfg config.CommandConfig, in Input) error {
+	*s.ran = in
+	return nil
+}
+
+func (stubRunner) Validate(cfg config.CommandConfig) []string { return nil }
+
+func (stubRunner) DryRun(env *Env, cfg config.CommandConfig, in Input) (string, error) {
+	return "stub " + cfg.Command, nil
+}
+
+func (stubRunner) Complete(cfg config.CommandConfig, args []string, toComplete string) []string {
+	return nil
+}
+
+var stubRan Input
+
+func init() {
+	Register("test-stub", stubRunner{ran: &stubRan})
+}
+
+func TestRegistry(t *testing.T) {
+	for _, typ := range []string{"http", "shell", "system", "test-stub"} {
+		if _, ok := Lookup(typ); !ok {
+			t.Errorf("Lookup(%q) not found", typ)
+		}
+	}
+	if _, ok := Lookup("nope"); ok {
+		t.Error("Lookup(nope) found a runner")
+	}
+	types := strings.Join(Types(), ",")
+	if !strings.Contains(types, "http,shell,system") {
+		t.Errorf("Types() = %s, want a sorted list including the builtin types", types)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  string
+		r    Runner
+	}{
+		{"duplicate", "shell", shellRunner{}},
+		{"empty type", "", shellRunner{}},
+		{"nil runner", "test-nil", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%q) did not panic", tt.typ)
+				}
+			}()
+			Register(tt.typ, tt.r)
+		})
+	}
+}
+
+func TestRunDispatch(t *testing.T) {
+	in := Input{Args: []string{"a"}}
+	if err := Run(context.Background(), nil, config.CommandConfig{Type: "test-stub"}, in); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if len(stubRan.Args) != 1 || stubRan.Args[0] != "a" {
+		t.Errorf("runner got %+v, want %+v", stubRan, in)
+	}
+
+	err := Run(context.Background(), nil, config.CommandConfig{Type: "nope"}, in)
+	if err == nil || !strings.Contains(err.Error(), "unknown command type: nope") {
+		t.Errorf("Run(nope) error = %v", err)
+	}
+}
+
+func TestDryRunDispatch(t *testing.T) {
+	got, err := DryRun(nil, config.CommandConfig{Type: "test-stub", Command: "x"}, Input{})
+	if err != nil || got != "stub x" {
+		t.Errorf("DryRun = %q, %v; want %q", got, err, "stub x")
+	}
+	if _, err := DryRun(nil, config.CommandConfig{Type: "nope"}, Input{}); err == nil {
+		t.Error("DryRun(nope) succeeded")
+	}
+}
+
+func TestPassthroughArgs(t *testing.T) {
+	tests := map[string]bool{"shell": true, "system": true, "http": false, "test-stub": false, "nope": false}
+	for typ, want := range tests {
+		if got := PassthroughArgs(typ); got != want {
+			t.Errorf("PassthroughArgs(%q) = %v, want %v", typ, got, want)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sl-cli/internal/config"
+)
+
+// ================= Shell Processor =================
+
+func init() {
+	Register("shell", shellRunner{})
+}
+
+// shellRunner 执行 type: shell 的命令 (内联脚本或 script_file)
+type shellRunner struct{}
+
+func (shellRunner) Run(ctx context.Context, env *Env, cfg config.CommandConfig, in Input) error {
+	return runShell(ctx, env, cfg, in)
+}
+
+// Validate 校验脚本来源: script 与 script_file 二选一，且必须能被解析为模板
+func (shellRunner) Validate(cfg config.CommandConfig) []string {
+	switch {
+	case cfg.Script == "" && cfg.ScriptFile == "":
+		return []string{"Type is shell but 'script' or 'script_file' is missing"}
+	case cfg.Script != "" && cfg.ScriptFile != "":
+		return []string{"'script' and 'script_file' cannot be used together"}
+	}
+
+	script, err := LoadScript(cfg)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var problems []string
+	if err := CheckTemplate(script); err != nil {
+		problems = append(problems, fmt.Sprintf("Invalid script template: %s", err))
+	}
+	if cfg.Strict && !IsShellScript(cfg, script) {
+		problems = append(problems, "'strict' is only supported for shell interpreters (sh/bash/zsh)")
+	}
+	return problems
+}
+
+// DryRun 输出解释器和渲染后的脚本
+func (shellRunner) DryRun(env *Env, cfg config.CommandConfig, in Input) (string, error) {
+	script, _, err := renderScript(env, cfg, in)
+	if err != nil {
+		return "", err
+	}
+	prefix, shebang := resolveInterpreter(cfg, script)
+	if shebang {
+		return script, nil
+	}
+	return "#!" + strings.Join(prefix, " ") + "\n" + script, nil
+}
+
+func (shellRunner) Complete(cfg config.CommandConfig, args []string, toComplete string) []string {
+	return nil
+}
+
+// PassthroughArgs 命令行参数作为 $1..$n 传给脚本
+func (shellRunner) PassthroughArgs() bool { return true }
+
+func runShell(ctx context.Context, env *Env, cfg config.CommandConfig, in Input) error {
+	scriptContent, sc, err := renderScript(env, cfg, in)
+	if err != nil {
+		return err
+	}
+
+	// 根据 interpreter / shebang 决定执行方式，命令行参数作为 $1..$n 传入 ("$@")
+	argv, cleanup, err := scriptCommand(cfg, scriptContent, in.Args)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Env = append(env.Environ[:len(env.Environ):len(env.Environ)], scriptEnv(cfg, in, sc)...)
+	cmd.Dir = env.Dir
+
+	// 绑定标准输入输出，支持交互
+	cmd.Stdin = env.Stdin
+	cmd.Stdout = env.Stdout
+	cmd.Stderr = env.Stderr
+
+	proc := &procGroup{}
+	proc.add(cmd, isTerminal(cmd.Stdin))
+	return proc.run(ctx)
+}
+
+// renderScript 加载脚本并渲染模板
+func renderScript(env *Env, cfg config.CommandConfig, in Input) (string, *scope, error) {
+	source, err := LoadScript(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sc := newScope(env, cfg, in)
+	// 允许在脚本中使用模板参数，例如 echo {{index .args 0}}
+	// 只有 shell 解释器才做 POSIX 转义，其他语言建议通过参数或环境变量读取输入
+	scriptCtx := ctxNone
+	if IsShellScript(cfg, source) {
+		scriptCtx = ctxShell
+	}
+	script, err := renderTemplate(source, sc.data, scriptCtx)
+	if err != nil {
+		return "", nil, err
+	}
+	// script = os.ExpandEnv(script)
+	return script, sc, nil
+}
+
+// 解释器的脚本传递方式
+const (
+	inlineShell = "shell" // sh -c script $0 args...
+	inlineFlag  = "flag"  // python3 -c script args... / node -e script -- args...
+	scriptFile  = "file"  // 写入临时文件: interpreter file args...
+)
+
+var versionSuffix = regexp.MustCompile(`[0-9.]+$`)
+
+// interpreterStyle 根据解释器名称判断脚本传递方式及对应的参数
+func interpreterStyle(interp string) (style, flag, ext string) {
+	base := versionSuffix.ReplaceAllString(filepath.Base(interp), "")
+	switch base {
+	case "sh", "bash", "zsh", "dash", "ksh", "ash", "mksh":
+		return inlineShell, "-c", ".sh"
+	case "python":
+		return inlineFlag, "-c", ".py"
+	case "node", "nodejs":
+		return inlineFlag, "-e", ".js"
+	case "perl", "ruby":
+		return inlineFlag, "-e", ""
+	default:
+		return scriptFile, "", ""
+	}
+}
+
+// interpreterArgv 返回配置的解释器命令前缀，默认为 /bin/sh
+// 单个字符串按空白拆分，例如 "python3 -u"
+func interpreterArgv(cfg config.CommandConfig) []string {
+	switch len(cfg.Interpreter) {
+	case 0:
+		return []string{"/bin/sh"}
+	case 1:
+		return strings.Fields(cfg.Interpreter[0])
+	default:
+		return cfg.Interpreter
+	}
+}
+
+// resolveInterpreter 优先使用显式配置的 interpreter；未配置时识别脚本首行的 shebang (#!)
+func resolveInterpreter(cfg config.CommandConfig, script string) (prefix []string, shebang bool) {
+	if len(cfg.Interpreter) == 0 && strings.HasPrefix(script, "#!") {
+		line, _, _ := strings.Cut(script, "\n")
+		return strings.Fields(strings.TrimPrefix(line, "#!")), true
+	}
+	return interpreterArgv(cfg), false
+}
+
+// LoadScript 返回脚本源码: 内联的 script，或 script_file 指向的文件内容
+func LoadScript(cfg config.CommandConfig) (string, error) {
+	if cfg.ScriptFile == "" {
+		return cfg.Script, nil
+	}
+	data, err := os.ReadFile(cfg.ScriptFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read script_file: %w", err)
+	}
+	return string(data), nil
+}
+
+// IsShellScript 判断脚本是否由 POSIX shell 家族执行 (决定模板转义方式，strict 模式也仅对其有效)
+func IsShellScript(cfg config.CommandConfig, script string) bool {
+	prefix, shebang := resolveInterpreter(cfg, script)
+	if len(prefix) == 0 {
+		return false
+	}
+	style, _, _ := interpreterStyle(effectiveInterpreter(prefix, shebang))
+	return style == inlineShell
+}
+
+// effectiveInterpreter 返回真正执行脚本的程序
+// 形如 #!/usr/bin/env bash 时，真正的解释器是 env 的参数: 跳过 env 的选项 (-S、-u NAME 等)
+// 和 NAME=VALUE 赋值后的第一个参数，如 #!/usr/bin/env -S bash -e 为 bash
+func effectiveInterpreter(prefix []string, shebang bool) string {
+	if !shebang || filepath.Base(prefix[0]) != "env" {
+		return prefix[0]
+	}
+	for i := 1; i < len(prefix); i++ {
+		arg := prefix[i]
+		switch {
+		case arg == "-u" || arg == "-C" || arg == "--unset" || arg == "--chdir":
+			i++ // 选项的值
+		case strings.HasPrefix(arg, "-S") && len(arg) > 2:
+			// -Sbash: -S 与解释器连写
+			return arg[2:]
+		case strings.HasPrefix(arg, "-"), strings.Contains(arg, "="):
+		default:
+			return arg
+		}
+	}
+	return prefix[0]
+}
+
+// scriptCommand 构建执行脚本的完整命令行
+// 需要文件路径的解释器会使用临时文件，cleanup 负责删除
+func scriptCommand(cfg config.CommandConfig, script string, args []string) (argv []string, cleanup func(), err error) {
+	cleanup = func() {}
+
+	// shebang 脚本与内核行为一致: 以文件形式交给解释器
+	prefix, useFile := resolveInterpreter(cfg, script)
+	if len(prefix) == 0 {
+		return nil, cleanup, fmt.Errorf("empty interpreter")
+	}
+
+	interp := effectiveInterpreter(prefix, useFile)
+	style, flag, ext := interpreterStyle(interp)
+	if cfg.Strict {
+		if style != inlineShell {
+			return nil, cleanup, fmt.Errorf("strict mode is only supported for shell interpreters, not %s", interp)
+		}
+		if useFile {
+			// 严格模式语句放在 shebang 行之后
+			line, rest, _ := strings.Cut(script, "\n")
+			script = line + "\n" + strictPrelude(interp) + rest
+		} else {
+			script = strictPrelude(interp) + script
+		}
+	}
+
+	if useFile {
+		style = scriptFile
+	}
+	argv = append([]string{}, prefix...)
+	switch style {
+	case inlineShell:
+		argv = append(argv, flag, script, cfg.Name)
+	case inlineFlag:
+		argv = append(argv, flag, script)
+		if flag == "-e" {
+			argv = append(argv, "--")
+		}
+	default:
+		f, err := os.CreateTemp("", "sl-cli-*"+ext)
+		if err != nil {
+			return nil, cleanup, fmt.Errorf("failed to create script file: %w", err)
+		}
+		cleanup = func() { _ = os.Remove(f.Name()) }
+		_, err = f.WriteString(script)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(f.Name(), 0o700)
+		}
+		if err != nil {
+			cleanup()
+			return nil, func() {}, fmt.Errorf("failed to write script file: %w", err)
+		}
+		argv = append(argv, f.Name())
+	}
+	return append(argv, args...), cleanup, nil
+}
+
+// strictPrelude 返回严格模式的前置语句; 普通 sh 不保证支持 pipefail
+func strictPrelude(interp string) string {
+	base := versionSuffix.ReplaceAllString(filepath.Base(interp), "")
+	if base == "sh" || base == "dash" || base == "ash" {
+		return "set -eu\n"
+	}
+	return "set -euo pipefail\n"
+}
+
+// scriptEnv 将参数和变量导出为环境变量，脚本无需模板插值即可读取:
+// SL_ARGC、SL_ARG_1..SL_ARG_n (原始位置参数)、SL_ARG_<NAME> (声明的 params)、
+// SL_FLAG_<NAME> (声明的 flags)、SL_VAR_<NAME> (全局变量)
+func scriptEnv(cfg config.CommandConfig, in Input, sc *scope) []string {
+	env := []string{fmt.Sprintf("SL_ARGC=%d", len(in.Args))}
+	for i, arg := range in.Args {
+		env = append(env, fmt.Sprintf("SL_ARG_%d=%s", i+1, arg))
+	}
+	for _, p := range cfg.Params {
+		env = append(env, fmt.Sprintf("SL_ARG_%s=%s", envName(p.Name), stringify(in.Params[p.Name])))
+	}
+	for _, f := range cfg.Flags {
+		env = append(env, fmt.Sprintf("SL_FLAG_%s=%s", envName(f.Name), stringify(in.Flags[f.Name])))
+	}
+	if vars, ok := sc.data["vars"].(map[string]interface{}); ok {
+		names := make([]string, 0, len(vars))
+		for k := range vars {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			if _, failed := vars[k].(*varError); failed {
+				continue
+			}
+			env = append(env, fmt.Sprintf("SL_VAR_%s=%s", envName(k), stringify(vars[k])))
+		}
+	}
+	return env
+}
+
+// envName 将名称转换为合法的环境变量名: 大写，非字母数字替换为下划线
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
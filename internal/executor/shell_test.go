@@ -0,0 +1,227 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sl-cli/internal/config"
+)
+
+func TestShellArgsAndEnv(t *testing.T) {
+	cfg := config.CommandConfig{
+		Name:   "deploy",
+		Type:   "shell",
+		Params: []config.ParamConfig{{Name: "service-name"}},
+		Flags:  []config.ParamConfig{{Name: "dry-run", Type: config.ParamBool}, {Name: "replicas", Type: config.ParamInt}},
+		Script: `printf '%s|' "$0" "$@"; echo; env | grep -E '^SL_(ARG|FLAG|VAR)' | LC_ALL=C sort`,
+	}
+	in := Input{
+		Args:   []string{"api server", "it's"},
+		Params: map[string]interface{}{"service-name": "api server"},
+		Flags:  map[string]interface{}{"dry-run": true, "replicas": 3},
+		Vars:   map[string]string{"region": "eu-west-1"},
+	}
+	env, stdout, _ := testEnv(t)
+	if err := Run(context.Background(), env, cfg, in); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	want := strings.Join([]string{
+		"deploy|api server|it's|",
+		"SL_ARGC=2",
+		"SL_ARG_1=api server",
+		"SL_ARG_2=it's",
+		"SL_ARG_SERVICE_NAME=api server",
+		"SL_FLAG_DRY_RUN=true",
+		"SL_FLAG_REPLICAS=3",
+		"SL_VAR_REGION=eu-west-1",
+		"",
+	}, "\n")
+	if stdout.String() != want {
+		t.Errorf("script saw:\n%s\nwant:\n%s", stdout, want)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"name", "NAME"},
+		{"dry-run", "DRY_RUN"},
+		{"api.v2", "API_V2"},
+	}
+	for _, tt := range tests {
+		if got := envName(tt.in); got != tt.want {
+			t.Errorf("envName(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestInterpreterStyle(t *testing.T) {
+	tests := []struct {
+		interp                 string
+		style, flag, extension string
+	}{
+		{"/bin/sh", inlineShell, "-c", ".sh"},
+		{"bash", inlineShell, "-c", ".sh"},
+		{"python3", inlineFlag, "-c", ".py"},
+		{"/usr/bin/python3.12", inlineFlag, "-c", ".py"},
+		{"node", inlineFlag, "-e", ".js"},
+		{"ruby", inlineFlag, "-e", ""},
+		{"pwsh", scriptFile, "", ""},
+	}
+	for _, tt := range tests {
+		style, flag, ext := interpreterStyle(tt.interp)
+		if style != tt.style || flag != tt.flag || ext != tt.extension {
+			t.Errorf("interpreterStyle(%q) = %s, %s, %s; want %s, %s, %s", tt.interp, style, flag, ext, tt.style, tt.flag, tt.extension)
+		}
+	}
+}
+
+func TestEffectiveInterpreter(t *testing.T) {
+	tests := []struct {
+		prefix  []string
+		shebang bool
+		want    string
+	}{
+		{[]string{"/bin/bash", "-e"}, true, "/bin/bash"},
+		{[]string{"/usr/bin/env", "bash"}, true, "bash"},
+		{[]string{"/usr/bin/env", "-S", "bash", "-e"}, true, "bash"},
+		{[]string{"/usr/bin/env", "-Sbash", "-e"}, true, "bash"},
+		{[]string{"/usr/bin/env", "-u", "HOME", "python3"}, true, "python3"},
+		{[]string{"/usr/bin/env", "--chdir", "/tmp", "-i", "LANG=C", "sh"}, true, "sh"},
+		{[]string{"/usr/bin/env"}, true, "/usr/bin/env"},
+		// 非 shebang 的 interpreter 配置按原样使用
+		{[]string{"env", "bash"}, false, "env"},
+	}
+	for _, tt := range tests {
+		if got := effectiveInterpreter(tt.prefix, tt.shebang); got != tt.want {
+			t.Errorf("effectiveInterpreter(%q, %v) = %s, want %s", tt.prefix, tt.shebang, got, tt.want)
+		}
+	}
+}
+
+func TestScriptCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.CommandConfig
+		script  string
+		want    []string // 临时文件的路径用 FILE 表示
+		wantErr string
+	}{
+		{"default sh", config.CommandConfig{Name: "c"}, "echo hi", []string{"/bin/sh", "-c", "echo hi", "c", "a1"}, ""},
+		{"interpreter string", config.CommandConfig{Name: "c", Interpreter: config.StringList{"python3 -u"}}, "print(1)", []string{"python3", "-u", "-c", "print(1)", "a1"}, ""},
+		{"node separator", config.CommandConfig{Name: "c", Interpreter: config.StringList{"node"}}, "1", []string{"node", "-e", "1", "--", "a1"}, ""},
+		{"strict bash", config.CommandConfig{Name: "c", Interpreter: config.StringList{"bash"}, Strict: true}, "x", []string{"bash", "-c", "set -euo pipefail\nx", "c", "a1"}, ""},
+		{"strict sh", config.CommandConfig{Name: "c", Strict: true}, "x", []string{"/bin/sh", "-c", "set -eu\nx", "c", "a1"}, ""},
+		{"strict python", config.CommandConfig{Interpreter: config.StringList{"python3"}, Strict: true}, "x", nil, "strict mode is only supported for shell interpreters"},
+		{"shebang uses a file", config.CommandConfig{}, "#!/usr/bin/env bash\necho", []string{"/usr/bin/env", "bash", "FILE", "a1"}, ""},
+		{"unknown interpreter uses a file", config.CommandConfig{Interpreter: config.StringList{"pwsh", "-NoProfile"}}, "x", []string{"pwsh", "-NoProfile", "FILE", "a1"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argv, cleanup, err := scriptCommand(tt.cfg, tt.script, []string{"a1"})
+			defer cleanup()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("scriptCommand error: %v", err)
+			}
+			for i, a := range tt.want {
+				if a == "FILE" && i < len(argv) {
+					data, err := os.ReadFile(argv[i])
+					if err != nil || string(data) != tt.script {
+						t.Errorf("script file %s = %q (%v), want %q", argv[i], data, err, tt.script)
+					}
+					argv[i] = "FILE"
+				}
+			}
+			if strings.Join(argv, "\x00") != strings.Join(tt.want, "\x00") {
+				t.Errorf("argv = %q, want %q", argv, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsShellScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.CommandConfig
+		script string
+		want   bool
+	}{
+		{"default", config.CommandConfig{}, "echo hi", true},
+		{"bash interpreter", config.CommandConfig{Interpreter: config.StringList{"/bin/bash"}}, "echo hi", true},
+		{"env shebang", config.CommandConfig{}, "#!/usr/bin/env bash\necho hi", true},
+		{"env -S shebang", config.CommandConfig{}, "#!/usr/bin/env -S bash -e\necho hi", true},
+		{"python shebang", config.CommandConfig{}, "#!/usr/bin/env python3\nprint(1)", false},
+		{"interpreter wins over shebang", config.CommandConfig{Interpreter: config.StringList{"python3"}}, "#!/bin/sh\nprint(1)", false},
+	}
+	for _, tt := range tests {
+		if got := IsShellScript(tt.cfg, tt.script); got != tt.want {
+			t.Errorf("%s: IsShellScript = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadScript(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "deploy.sh")
+	if err := os.WriteFile(file, []byte("echo {{index .args 0}}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		cfg     config.CommandConfig
+		want    string
+		wantErr string
+	}{
+		{"inline", config.CommandConfig{Script: "echo hi"}, "echo hi", ""},
+		{"file", config.CommandConfig{ScriptFile: file}, "echo {{index .args 0}}\n", ""},
+		{"missing file", config.CommandConfig{ScriptFile: filepath.Join(dir, "nope.sh")}, "", "failed to read script_file"},
+	}
+	for _, tt := range tests {
+		got, err := LoadScript(tt.cfg)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: LoadScript = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestShellRunnerValidate(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ok.sh")
+	if err := os.WriteFile(file, []byte("echo {{index .args 0}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		cfg      config.CommandConfig
+		wantErrs int
+	}{
+		{"inline", config.CommandConfig{Script: "echo hi"}, 0},
+		{"file", config.CommandConfig{ScriptFile: file}, 0},
+		{"missing source", config.CommandConfig{}, 1},
+		{"both sources", config.CommandConfig{Script: "echo", ScriptFile: file}, 1},
+		{"missing file", config.CommandConfig{ScriptFile: filepath.Join(dir, "nope.sh")}, 1},
+		{"invalid template", config.CommandConfig{Script: "echo {{.args"}, 1},
+		{"strict python", config.CommandConfig{Script: "print(1)", Interpreter: config.StringList{"python3"}, Strict: true}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(shellRunner{}.Validate(tt.cfg)); got != tt.wantErrs {
+				t.Errorf("Validate() = %d problems, want %d", got, tt.wantErrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,503 @@
+package executor
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sl-cli/internal/config"
+)
+
+// ================= Request Signing =================
+
+// 支持的签名方式
+const (
+	SignAWSv4 = "aws_sigv4"
+	SignHMAC  = "hmac"
+)
+
+const (
+	defaultSignHeader      = "X-Signature"
+	defaultTimestampHeader = "X-Timestamp"
+	defaultSignFormat      = "{{.signature}}"
+
+	// unsignedPayload 用于无法重复读取的 Body (如流式上传)
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+var hmacAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+var timestampFormats = map[string]func(time.Time) string{
+	"unix":    func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) },
+	"unix_ms": func(t time.Time) string { return strconv.FormatInt(t.UnixMilli(), 10) },
+	"rfc3339": func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+	"http":    func(t time.Time) string { return t.UTC().Format(http.TimeFormat) },
+}
+
+// validateSign 校验 api.sign，返回问题描述 (供 config check 输出)
+func validateSign(api config.APIConfig) []string {
+	s := api.Sign
+	var problems []string
+	switch s.Type {
+	case "":
+		return nil
+	case SignAWSv4:
+		if s.Service == "" {
+			problems = append(problems, "aws_sigv4 sign requires 'service'")
+		}
+		if (s.AccessKey == "") != (s.SecretKey == "") {
+			problems = append(problems, "aws_sigv4 sign requires both 'access_key' and 'secret_key'")
+		}
+		switch api.Auth.Type {
+		case AuthBasic, AuthBearer, AuthOAuth2:
+			problems = append(problems, fmt.Sprintf("aws_sigv4 sign cannot be combined with %s auth (both set Authorization)", api.Auth.Type))
+		}
+	case SignHMAC:
+		if s.Secret == "" {
+			problems = append(problems, "hmac sign requires 'secret'")
+		}
+		if _, ok := hmacAlgorithms[s.Algorithm]; s.Algorithm != "" && !ok {
+			problems = append(problems, fmt.Sprintf("invalid sign algorithm '%s', must be one of sha1, sha256, sha512", s.Algorithm))
+		}
+		if s.Encoding != "" && s.Encoding != "hex" && s.Encoding != "base64" {
+			problems = append(problems, fmt.Sprintf("invalid sign encoding '%s', must be hex or base64", s.Encoding))
+		}
+		if _, ok := timestampFormats[s.Timestamp]; s.Timestamp != "" && !ok {
+			problems = append(problems, fmt.Sprintf("invalid sign timestamp '%s', must be one of unix, unix_ms, rfc3339, http", s.Timestamp))
+		}
+		if err := CheckTemplate(s.Format); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid sign format: %s", err))
+		}
+	default:
+		return []string{fmt.Sprintf("invalid sign type '%s', must be aws_sigv4 or hmac", s.Type)}
+	}
+	for _, field := range []string{s.AccessKey, s.SecretKey, s.SessionToken, s.Secret} {
+		if err := checkSecretRef(field); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// awsCredentials 是 AWS 的访问凭据
+type awsCredentials struct {
+	accessKey, secretKey, sessionToken string
+}
+
+// awsKeys 按顺序查找 AWS 凭据: sign 中的配置、AWS_ACCESS_KEY_ID 等环境变量 (未指定 profile 时)、共享凭据文件
+func (c liveCredentials) awsKeys(s config.SignConfig) (awsCredentials, error) {
+	if s.AccessKey != "" {
+		var keys awsCredentials
+		for _, f := range []struct {
+			dst   *string
+			value string
+		}{{&keys.accessKey, s.AccessKey}, {&keys.secretKey, s.SecretKey}, {&keys.sessionToken, s.SessionToken}} {
+			if f.value == "" {
+				continue
+			}
+			v, err := c.secret(f.value)
+			if err != nil {
+				return keys, fmt.Errorf("failed to resolve sign credentials: %w", err)
+			}
+			*f.dst = v
+		}
+		return keys, nil
+	}
+	if id := c.env.Getenv("AWS_ACCESS_KEY_ID"); id != "" && s.Profile == "" {
+		return awsCredentials{id, c.env.Getenv("AWS_SECRET_ACCESS_KEY"), c.env.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+	return c.awsProfile(s.Profile)
+}
+
+// awsProfile 从共享凭据文件 (默认 ~/.aws/credentials，可由 AWS_SHARED_CREDENTIALS_FILE 指定) 读取 profile
+func (c liveCredentials) awsProfile(profile string) (awsCredentials, error) {
+	if profile == "" {
+		profile = c.env.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+	path := c.env.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home := c.env.Getenv("HOME")
+		if home == "" {
+			var err error
+			if home, err = os.UserHomeDir(); err != nil {
+				return awsCredentials{}, err
+			}
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("no AWS credentials: set sign.access_key, AWS_ACCESS_KEY_ID or %s", path)
+	}
+	defer f.Close()
+
+	var keys awsCredentials
+	found, section := false, ""
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			found = found || section == profile
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || section != profile {
+			continue
+		}
+		switch strings.TrimSpace(k) {
+		case "aws_access_key_id":
+			keys.accessKey = strings.TrimSpace(v)
+		case "aws_secret_access_key":
+			keys.secretKey = strings.TrimSpace(v)
+		case "aws_session_token":
+			keys.sessionToken = strings.TrimSpace(v)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return keys, err
+	}
+	if !found || keys.accessKey == "" || keys.secretKey == "" {
+		return keys, fmt.Errorf("AWS profile '%s' not found or incomplete in %s", profile, path)
+	}
+	return keys, nil
+}
+
+func (maskedCredentials) awsKeys(config.SignConfig) (awsCredentials, error) {
+	return awsCredentials{maskedValue, maskedValue, ""}, nil
+}
+
+// requestSigner 为请求计算签名，凭据在创建时解析
+type requestSigner struct {
+	cfg     config.SignConfig
+	keys    awsCredentials // aws_sigv4
+	region  string         // aws_sigv4
+	service string         // aws_sigv4
+	secret  string         // hmac
+	keyID   string         // hmac
+	masked  bool           // dry-run: 签名显示为 ****
+}
+
+// newSigner 解析签名配置和凭据，未配置 api.sign 时返回 nil
+func newSigner(s config.SignConfig, creds credentials, sc *scope) (*requestSigner, error) {
+	if s.Type == "" {
+		return nil, nil
+	}
+	if problems := validateSign(config.APIConfig{Sign: s}); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid sign: %s", problems[0])
+	}
+	_, masked := creds.(maskedCredentials)
+	signer := &requestSigner{cfg: s, masked: masked}
+	var err error
+	switch s.Type {
+	case SignAWSv4:
+		if signer.region, err = sc.interpolate(s.Region, ctxNone); err != nil {
+			return nil, err
+		}
+		if signer.region == "" {
+			signer.region = sc.env.Getenv("AWS_REGION")
+		}
+		if signer.region == "" {
+			signer.region = sc.env.Getenv("AWS_DEFAULT_REGION")
+		}
+		if signer.region == "" {
+			return nil, fmt.Errorf("aws_sigv4 sign requires 'region' (or AWS_REGION)")
+		}
+		if signer.service, err = sc.interpolate(s.Service, ctxNone); err != nil {
+			return nil, err
+		}
+		if signer.keys, err = creds.awsKeys(s); err != nil {
+			return nil, err
+		}
+	case SignHMAC:
+		if signer.secret, err = creds.secret(s.Secret); err != nil {
+			return nil, fmt.Errorf("failed to resolve sign secret: %w", err)
+		}
+		if signer.keyID, err = sc.interpolate(s.KeyID, ctxNone); err != nil {
+			return nil, err
+		}
+	}
+	return signer, nil
+}
+
+// sign 为请求添加签名相关的 Header，返回需要在 trace 中隐藏的值
+func (s *requestSigner) sign(req *http.Request, now time.Time) ([]string, error) {
+	if s.cfg.Type == SignAWSv4 {
+		return s.signAWS(req, now)
+	}
+	return s.signHMAC(req, now)
+}
+
+// bodyHash 计算 Body 的哈希 (hex)，通过 GetBody 读取副本，不影响请求本身
+// 无法重复读取的 Body 返回 ok 为 false
+func bodyHash(req *http.Request, newHash func() hash.Hash) (sum string, ok bool, err error) {
+	h := newHash()
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return "", false, nil
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return "", false, err
+		}
+		defer body.Close()
+		if _, err := io.Copy(h, body); err != nil {
+			return "", false, err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), true, nil
+}
+
+func hmacSum(newHash func() hash.Hash, key []byte, data string) []byte {
+	m := hmac.New(newHash, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// ---------- AWS Signature V4 ----------
+
+// awsIgnoredHeaders 不参与签名，它们可能被代理或客户端修改
+var awsIgnoredHeaders = map[string]bool{"authorization": true, "user-agent": true, "x-amzn-trace-id": true, "expect": true}
+
+func (s *requestSigner) signAWS(req *http.Request, now time.Time) ([]string, error) {
+	now = now.UTC()
+	amzDate, date := now.Format("20060102T150405Z"), now.Format("20060102")
+
+	payload, ok, err := bodyHash(req, sha256.New)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		payload = unsignedPayload
+	}
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payload)
+	}
+	if s.keys.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.keys.sessionToken)
+	}
+
+	// 规范请求: Method、路径、query、Header、签名的 Header 列表、Body 哈希
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		name := strings.ToLower(k)
+		if awsIgnoredHeaders[name] {
+			continue
+		}
+		values := make([]string, len(v))
+		for i, val := range v {
+			values[i] = strings.Join(strings.Fields(val), " ")
+		}
+		headers[name] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	path = awsEscape(path, false)
+	if s.service != "s3" {
+		// S3 以外的服务要求路径编码两次
+		path = awsEscape(path, false)
+	}
+
+	canonical := strings.Join([]string{req.Method, path, awsQuery(req), canonicalHeaders.String(), signedHeaders, payload}, "\n")
+	scope := strings.Join([]string{date, s.region, s.service, "aws4_request"}, "/")
+	digest := sha256.Sum256([]byte(canonical))
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(digest[:])}, "\n")
+
+	key := hmacSum(sha256.New, []byte("AWS4"+s.keys.secretKey), date)
+	for _, part := range []string{s.region, s.service, "aws4_request"} {
+		key = hmacSum(sha256.New, key, part)
+	}
+	signature := hex.EncodeToString(hmacSum(sha256.New, key, toSign))
+	if s.masked {
+		signature = maskedValue
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.keys.accessKey, scope, signedHeaders, signature))
+	return []string{signature, s.keys.sessionToken}, nil
+}
+
+// awsQuery 返回规范化的 query: 参数按编码后的名称排序，名称相同时按值排序，按 RFC 3986 编码
+// 不能直接对 "名称=值" 排序，否则 a-b 会因为 '-' < '=' 排在 a 之前
+func awsQuery(req *http.Request) string {
+	query := req.URL.Query()
+	pairs := make([][2]string, 0, len(query))
+	for k, values := range query {
+		for _, v := range values {
+			pairs = append(pairs, [2]string{awsEscape(k, true), awsEscape(v, true)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	parts := make([]string, len(pairs))
+	for i, p := range pairs {
+		parts[i] = p[0] + "=" + p[1]
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape 按 SigV4 的要求编码: 只保留 A-Z a-z 0-9 - _ . ~，encodeSlash 为 false 时保留 /
+func awsEscape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// ---------- HMAC ----------
+
+func (s *requestSigner) signHMAC(req *http.Request, now time.Time) ([]string, error) {
+	newHash := hmacAlgorithms[s.cfg.Algorithm]
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	format := timestampFormats[s.cfg.Timestamp]
+	if format == nil {
+		format = timestampFormats["unix"]
+	}
+	timestamp := format(now)
+	tsHeader := s.cfg.TimestampHeader
+	if tsHeader == "" {
+		tsHeader = defaultTimestampHeader
+	}
+	req.Header.Set(tsHeader, timestamp)
+
+	// 待签名字符串: Method、路径和 query、时间戳、配置的 Header、Body 哈希，以换行分隔
+	lines := []string{req.Method, req.URL.RequestURI(), timestamp}
+	names := make([]string, len(s.cfg.Headers))
+	for i, name := range s.cfg.Headers {
+		value := req.Header.Get(name)
+		if strings.EqualFold(name, "host") {
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		}
+		names[i] = strings.ToLower(name)
+		lines = append(lines, names[i]+":"+strings.TrimSpace(value))
+	}
+	if s.cfg.BodyHash {
+		sum, ok, err := bodyHash(req, newHash)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("hmac sign with body_hash requires a body that can be read twice")
+		}
+		lines = append(lines, sum)
+	}
+
+	mac := hmacSum(newHash, []byte(s.secret), strings.Join(lines, "\n"))
+	signature := hex.EncodeToString(mac)
+	if s.cfg.Encoding == "base64" {
+		signature = base64.StdEncoding.EncodeToString(mac)
+	}
+	if s.masked {
+		signature = maskedValue
+	}
+
+	tpl := s.cfg.Format
+	if tpl == "" {
+		tpl = defaultSignFormat
+	}
+	algorithm := s.cfg.Algorithm
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+	value, err := renderTemplate(tpl, map[string]interface{}{
+		"signature": signature,
+		"key_id":    s.keyID,
+		"timestamp": timestamp,
+		"algorithm": algorithm,
+		"headers":   strings.Join(names, ";"),
+	}, ctxNone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render sign format: %w", err)
+	}
+	header := s.cfg.Header
+	if header == "" {
+		header = defaultSignHeader
+	}
+	req.Header.Set(header, value)
+	return []string{signature}, nil
+}
+
+// signingTransport 在每次发送前 (包括重试) 为请求签名，时间戳总是最新的
+// 只对原始的 host 签名，重定向到其他 host 时不带签名
+type signingTransport struct {
+	next   http.RoundTripper
+	signer *requestSigner
+	host   string
+	now    func() time.Time
+}
+
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.next.RoundTrip(req)
+	}
+	// RoundTripper 不能修改传入的请求
+	r := req.Clone(req.Context())
+	secrets, err := t.signer.sign(r, t.now())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+	r = r.WithContext(withSecrets(r.Context(), append(slices.Clone(secretsFrom(r.Context())), secrets...)))
+	return t.next.RoundTrip(r)
+}
@@ -0,0 +1,156 @@
+package executor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"sl-cli/internal/config"
+)
+
+// TestSignAWSv4 使用 AWS SigV4 测试套件 (aws-sig-v4-test-suite) 中的请求和签名
+func TestSignAWSv4(t *testing.T) {
+	signer := &requestSigner{
+		cfg:     config.SignConfig{Type: SignAWSv4},
+		keys:    awsCredentials{accessKey: "AKIDEXAMPLE", secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"},
+		region:  "us-east-1",
+		service: "service",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		method        string
+		url           string
+		headers       map[string]string
+		body          string
+		signedHeaders string
+		signature     string
+	}{
+		{"get-vanilla", http.MethodGet, "/", nil, "", "host;x-amz-date", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", http.MethodGet, "/?Param2=value2&Param1=value1", nil, "", "host;x-amz-date", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"get-vanilla-empty-query-key", http.MethodGet, "/?Param1=value1", nil, "", "host;x-amz-date", "a67d582fa61cc504c4bae71f336f98b97f1ea3c7a6bfe1b6e45aec72011b9aeb"},
+		{"post-vanilla", http.MethodPost, "/", nil, "", "host;x-amz-date", "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+		{"post-vanilla-query", http.MethodPost, "/?Param1=value1", nil, "", "host;x-amz-date", "28038455d6de14eafc1f9222cf5aa6f1a96197d7deb8263271d420d138af7f11"},
+		{"post-x-www-form-urlencoded", http.MethodPost, "/", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, "Param1=value1", "content-type;host;x-amz-date", "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = http.NoBody
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req, err := http.NewRequest(tt.method, "https://example.amazonaws.com"+tt.url, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if _, err := signer.sign(req, now); err != nil {
+				t.Fatalf("sign error: %v", err)
+			}
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=" + tt.signedHeaders + ", Signature=" + tt.signature
+			if got := req.Header.Get("Authorization"); got != want {
+				t.Errorf("Authorization\n got: %s\nwant: %s", got, want)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %s", got)
+			}
+		})
+	}
+}
+
+func TestSignHMAC(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	sum := sha256.Sum256([]byte(`{"a":1}`))
+	payload := strings.Join([]string{"POST", "/v1/orders?x=1", "1700000000", "host:api.example.com", "x-request-id:r1", hex.EncodeToString(sum[:])}, "\n")
+	mac := hmac.New(sha256.New, []byte("k3y"))
+	mac.Write([]byte(payload))
+	hexSig, b64Sig := hex.EncodeToString(mac.Sum(nil)), base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name   string
+		cfg    config.SignConfig
+		header string
+		want   string
+	}{
+		{
+			name:   "defaults",
+			cfg:    config.SignConfig{Type: SignHMAC, Headers: config.StringList{"Host", "X-Request-Id"}, BodyHash: true},
+			header: defaultSignHeader,
+			want:   hexSig,
+		},
+		{
+			name:   "base64 with format",
+			cfg:    config.SignConfig{Type: SignHMAC, Headers: config.StringList{"Host", "X-Request-Id"}, BodyHash: true, Encoding: "base64", Header: "Authorization", Format: "HMAC {{.key_id}}:{{.signature}} ({{.algorithm}}; {{.headers}})"},
+			header: "Authorization",
+			want:   "HMAC app1:" + b64Sig + " (sha256; host;x-request-id)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := &requestSigner{cfg: tt.cfg, secret: "k3y", keyID: "app1"}
+			req, err := http.NewRequest(http.MethodPost, "https://api.example.com/v1/orders?x=1", strings.NewReader(`{"a":1}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("X-Request-Id", "r1")
+			if _, err := signer.sign(req, now); err != nil {
+				t.Fatalf("sign error: %v", err)
+			}
+			if got := req.Header.Get(defaultTimestampHeader); got != "1700000000" {
+				t.Errorf("timestamp = %s", got)
+			}
+			if got := req.Header.Get(tt.header); got != tt.want {
+				t.Errorf("%s\n got: %s\nwant: %s", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAWSQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		// 按名称排序: a 在 a-b 之前，尽管 "a-b=" < "a="
+		{"a-b=1&a=2", "a=2&a-b=1"},
+		{"b=2&a=z&a=y", "a=y&a=z&b=2"},
+		{"q=a b&x=%2F", "q=a%20b&x=%2F"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, "https://example.com/?"+tt.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := awsQuery(req); got != tt.want {
+			t.Errorf("awsQuery(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestAWSEscape(t *testing.T) {
+	tests := []struct {
+		in          string
+		encodeSlash bool
+		want        string
+	}{
+		{"/a b/c", false, "/a%20b/c"},
+		{"/a b/c", true, "%2Fa%20b%2Fc"},
+		{"A-Z_a.z~0", true, "A-Z_a.z~0"},
+		{"a+b=c*", true, "a%2Bb%3Dc%2A"},
+		{"ü", true, "%C3%BC"},
+	}
+	for _, tt := range tests {
+		if got := awsEscape(tt.in, tt.encodeSlash); got != tt.want {
+			t.Errorf("awsEscape(%q, %v) = %s, want %s", tt.in, tt.encodeSlash, got, tt.want)
+		}
+	}
+}
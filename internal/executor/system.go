@@ -0,0 +1,100 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	"sl-cli/internal/config"
+)
+
+// ================= System Processor =================
+
+func init() {
+	Register("system", systemRunner{})
+}
+
+// systemRunner 执行 type: system 的命令 (系统命令别名)
+type systemRunner struct{}
+
+func (systemRunner) Run(ctx context.Context, env *Env, cfg config.CommandConfig, in Input) error {
+	return runSystem(ctx, env, cfg, in)
+}
+
+func (systemRunner) Validate(cfg config.CommandConfig) []string {
+	if cfg.Command == "" {
+		return []string{"Type is system but 'command' is missing"}
+	}
+	return nil
+}
+
+// DryRun 输出最终执行的命令行
+func (systemRunner) DryRun(env *Env, cfg config.CommandConfig, in Input) (string, error) {
+	args, err := systemArgs(env, cfg, in)
+	if err != nil {
+		return "", err
+	}
+	return shellJoin(append([]string{cfg.Command}, args...)) + "\n", nil
+}
+
+func (systemRunner) Complete(cfg config.CommandConfig, args []string, toComplete string) []string {
+	return nil
+}
+
+// PassthroughArgs 多余的参数追加在配置的 args 之后
+func (systemRunner) PassthroughArgs() bool { return true }
+
+func runSystem(ctx context.Context, env *Env, cfg config.CommandConfig, in Input) error {
+	finalArgs, err := systemArgs(env, cfg, in)
+	if err != nil {
+		return err
+	}
+
+	// exec 模式: 直接用目标命令替换 sl-cli 进程，信号、终端和退出码都由目标命令自行处理
+	// 设置了超时，或输入输出被替换 (嵌入调用) 时，sl-cli 需要留下来，因此回退为普通的子进程模式
+	if _, hasDeadline := ctx.Deadline(); cfg.Exec && !hasDeadline && env.ownsProcess() {
+		path, err := exec.LookPath(cfg.Command)
+		if err != nil {
+			return childExit(err)
+		}
+		return execReplace(path, append([]string{cfg.Command}, finalArgs...), env.Environ)
+	}
+
+	cmd := exec.Command(cfg.Command, finalArgs...)
+	cmd.Env = env.Environ
+	cmd.Dir = env.Dir
+	cmd.Stdin = env.Stdin
+	cmd.Stdout = env.Stdout
+	cmd.Stderr = env.Stderr
+
+	proc := &procGroup{}
+	proc.add(cmd, isTerminal(cmd.Stdin))
+	return proc.run(ctx)
+}
+
+// systemArgs 返回最终的命令参数: 配置中渲染后的 args + 命令行上多余的参数
+func systemArgs(env *Env, cfg config.CommandConfig, in Input) ([]string, error) {
+	// System 模式下，配置中的 Args 是基础参数，命令行输入的 args 追加在后面
+	// 例如配置: git log; 输入: sl-cli git-log -n 5
+	// 最终执行: git log -n 5
+	// 已被 params 声明消费的位置参数不再追加，可通过模板 {{.args.name}} 引用
+
+	sc := newScope(env, cfg, in)
+	extraArgs := in.Args
+	if len(cfg.Params) > 0 {
+		extraArgs = extraArgs[min(len(cfg.Params), len(extraArgs)):]
+	}
+
+	finalArgs := make([]string, 0, len(cfg.Args)+len(extraArgs))
+	for _, arg := range cfg.Args {
+		val, err := sc.interpolate(arg, ctxNone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render arg '%s': %w", arg, err)
+		}
+		finalArgs = append(finalArgs, val)
+	}
+	for _, arg := range extraArgs {
+		finalArgs = append(finalArgs, arg)
+	}
+	return finalArgs, nil
+}
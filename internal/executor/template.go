@@ -0,0 +1,810 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"sl-cli/internal/config"
+)
+
+// ================= Helper: Template Rendering =================
+
+// escapeContext 描述模板输出所在的上下文，决定插入的值如何被转义
+type escapeContext int
+
+const (
+	ctxNone  escapeContext = iota // 原样输出: headers、method、query_params、管道参数等 (不经过 shell，也会被单独编码)
+	ctxURL                        // api.url: path 部分按路径段编码，? 之后按 query 编码
+	ctxJSON                       // JSON body: 字符串内做 JSON 转义，字符串外输出为 JSON 值
+	ctxShell                      // shell 脚本: 按所在引号做 POSIX 转义
+	ctxJQ                         // 内置 jq 表达式: 与 JSON 相同，字符串内转义，字符串外输出为字面量
+)
+
+// rawValue 由模板函数 raw 产生，表示该值无需转义
+type rawValue struct{ v interface{} }
+
+// trusted 用于全局变量: 由配置作者编写，插入时不做转义 (例如 {{.vars.host}}/path)
+type trusted string
+
+// scope 是一次命令执行的插值上下文: 模板数据 + 展开 ${ENV} 所用的执行环境
+type scope struct {
+	data map[string]interface{}
+	env  *Env
+}
+
+// newScope 准备模板数据
+// .args: 未声明 params 时为原始参数列表 ({{index .args 0}})，声明后为 name -> 值 ({{.args.city}})
+// .argv: 始终为原始参数列表
+// .flags: 声明的 flag 值
+// .vars: 全局变量
+func newScope(env *Env, cfg config.CommandConfig, in Input) *scope {
+	var args interface{} = in.Args
+	if len(cfg.Params) > 0 {
+		args = in.Params
+	}
+	flags := in.Flags
+	if flags == nil {
+		flags = map[string]interface{}{}
+	}
+	return &scope{
+		data: map[string]interface{}{
+			"args":  args,
+			"argv":  in.Args,
+			"flags": flags,
+			"vars":  resolveVars(in.Vars, args, env),
+		},
+		env: env,
+	}
+}
+
+// interpolate 是所有配置字段共用的插值流程: 渲染 Go 模板，同时展开模板原文中的 ${ENV}
+// ${ENV} 只在配置原文里展开，参数等用户输入中的 $VAR 原样保留
+func (s *scope) interpolate(str string, ctx escapeContext) (string, error) {
+	return render(str, s.data, ctx, s.env.Getenv)
+}
+
+// renderTemplate 渲染模板，并根据 ctx 自动转义每一处 {{...}} 的输出
+// 使用 {{raw .args.x}} 或 {{.args.x | raw}} 可显式跳过转义
+func renderTemplate(tplStr string, data map[string]interface{}, ctx escapeContext) (string, error) {
+	return render(tplStr, data, ctx, nil)
+}
+
+// render 是 renderTemplate 的实现，getenv 非 nil 时展开模板原文中的 ${ENV}
+func render(tplStr string, data map[string]interface{}, ctx escapeContext, getenv func(string) string) (string, error) {
+	if tplStr == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.New("cmd").Funcs(escapeFuncs).Parse(tplStr)
+	if err != nil {
+		return "", err
+	}
+	if getenv != nil {
+		tmpl.Funcs(template.FuncMap{envFunc: getenv})
+	}
+	addEscapers(tmpl.Tree, ctx, getenv != nil)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		var ve *varError
+		if errors.As(err, &ve) {
+			return "", ve
+		}
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// CheckTemplate 只解析不执行，用于 config check 提前发现模板语法错误
+func CheckTemplate(tplStr string) error {
+	_, err := template.New("cmd").Funcs(escapeFuncs).Parse(tplStr)
+	return err
+}
+
+// varError 记录渲染失败的全局变量，插入该变量时才报错
+// 变量对所有命令共用，引用了某个 param 的变量在其他命令中渲染失败是正常的
+type varError struct {
+	name string
+	err  error
+}
+
+func (e *varError) Error() string {
+	return fmt.Sprintf("failed to render var '%s': %s", e.name, e.err)
+}
+
+// resolveVars 渲染全局变量: 支持 ${ENV} 和引用参数的模板 (不能引用其他变量，避免循环)
+// 只有不含模板动作的变量才视为 trusted 原样插入；引用了参数的变量仍按插入位置转义
+func resolveVars(vars map[string]string, args interface{}, env *Env) map[string]interface{} {
+	resolved := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		val, err := render(v, map[string]interface{}{"args": args}, ctxNone, env.Getenv)
+		switch {
+		case err != nil:
+			resolved[k] = &varError{name: k, err: err}
+		case hasActions(v):
+			resolved[k] = val
+		default:
+			resolved[k] = trusted(val)
+		}
+	}
+	return resolved
+}
+
+// hasActions 判断模板中是否含有 {{...}} 动作 (解析失败时按含有处理)
+func hasActions(tplStr string) bool {
+	tmpl, err := template.New("cmd").Funcs(escapeFuncs).Parse(tplStr)
+	if err != nil {
+		return true
+	}
+	if tmpl.Tree == nil {
+		return false
+	}
+	for _, node := range tmpl.Tree.Root.Nodes {
+		if _, ok := node.(*parse.TextNode); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSONBody 判断 body 是否按 JSON 上下文转义: Content-Type 含 json，或内容以 { / [ 开头
+func isJSONBody(api config.APIConfig) bool {
+	for k, v := range api.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			return strings.Contains(strings.ToLower(v), "json")
+		}
+	}
+	trimmed := strings.TrimSpace(api.Body)
+	return strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")
+}
+
+// ================= Helper: Context-aware Escaping =================
+
+// 转义函数在模板解析前注册，解析后再追加到每个输出动作的管道末尾
+const (
+	escNone        = "_slEscNone"
+	escURLPath     = "_slEscURLPath"
+	escURLQuery    = "_slEscURLQuery"
+	escJSONString  = "_slEscJSONString"
+	escJSONValue   = "_slEscJSONValue"
+	escShellBare   = "_slEscShellBare"
+	escShellSingle = "_slEscShellSingle"
+	escShellDouble = "_slEscShellDouble"
+	escShellNested = "_slEscShellNested" // 反引号或 heredoc 中: 需要额外的参数描述外层上下文
+	escShellRefuse = "_slEscShellRefuse" // 无法可靠转义的位置: 插入值时报错
+	envFunc        = "_slEnv"            // 读取 ${ENV}，渲染时替换为执行环境的 Getenv
+)
+
+var escapeFuncs = template.FuncMap{
+	"raw":          raw,
+	escNone:        escaper(func(s string) string { return s }),
+	escURLPath:     escaper(url.PathEscape),
+	escURLQuery:    escaper(url.QueryEscape),
+	escJSONString:  escaper(jsonStringContent),
+	escJSONValue:   jsonValue,
+	escShellBare:   escaper(shellQuote),
+	escShellSingle: escaper(func(s string) string { return strings.ReplaceAll(s, "'", `'\''`) }),
+	escShellDouble: escaper(shellDoubleQuoted),
+	escShellNested: shellNested,
+	escShellRefuse: func(reason string, v interface{}) (string, error) {
+		return "", fmt.Errorf("cannot safely insert a value inside %s", reason)
+	},
+	envFunc: func(string) string { return "" },
+}
+
+func raw(v interface{}) (rawValue, error) {
+	if e, ok := v.(*varError); ok {
+		return rawValue{}, e
+	}
+	return rawValue{v}, nil
+}
+
+// escaper 包装字符串转义函数，raw 和 trusted 的值原样输出
+func escaper(fn func(string) string) func(interface{}) (string, error) {
+	return func(v interface{}) (string, error) {
+		switch val := v.(type) {
+		case rawValue:
+			return stringify(val.v), nil
+		case trusted:
+			return string(val), nil
+		case *varError:
+			return "", val
+		default:
+			return fn(stringify(v)), nil
+		}
+	}
+}
+
+func stringify(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func jsonStringContent(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	out := strings.TrimSuffix(buf.String(), "\n")
+	return out[1 : len(out)-1]
+}
+
+// jsonValue 用于 JSON 字符串外部: 数字和布尔值原样输出，其余编码为带引号的字符串
+func jsonValue(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case rawValue:
+		return stringify(val.v), nil
+	case trusted:
+		return string(val), nil
+	case *varError:
+		return "", val
+	case nil:
+		return "null", nil
+	case bool, int, int64, float64:
+		return fmt.Sprint(val), nil
+	default:
+		return `"` + jsonStringContent(stringify(v)) + `"`, nil
+	}
+}
+
+// shellQuote 将值包裹为单引号字符串，适用于引号之外
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellJoin 将参数列表拼接为可直接粘贴到 shell 中的命令行
+func shellJoin(argv []string) string {
+	parts := make([]string, len(argv))
+	for i, a := range argv {
+		if a != "" && strings.Trim(a, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@%+,") == "" {
+			parts[i] = a
+		} else {
+			parts[i] = shellQuote(a)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellDoubleQuoted 转义双引号内具有特殊含义的字符
+func shellDoubleQuoted(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '"', '$', '`':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// shellBackslashed 转义反引号命令替换和未加引号的 heredoc 正文中具有特殊含义的字符
+// 两者都只在 \ 之后的 $、` 和 \ 上去掉反斜杠，双引号保持原样
+func shellBackslashed(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '$', '`':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// shellNested 转义位于反引号或 heredoc 中的值
+// quote 为值所在的引号状态 (同 escapeState.shell)，backtick 表示外层有反引号命令替换，
+// delim 非空时值位于 heredoc 中，不能让插入的内容构成结束分隔符所在的行
+func shellNested(quote string, backtick bool, delim string, dash bool, line string, dirty bool, v interface{}) (string, error) {
+	var s string
+	switch val := v.(type) {
+	case rawValue:
+		return stringify(val.v), nil
+	case trusted:
+		return string(val), nil
+	case *varError:
+		return "", val
+	default:
+		s = stringify(v)
+	}
+	switch quote {
+	case "s":
+		s = strings.ReplaceAll(s, "'", `'\''`)
+	case "d":
+		s = shellDoubleQuoted(s)
+	case "h":
+		s = shellBackslashed(s)
+	case "H":
+		// 带引号分隔符的 heredoc 正文不做任何展开
+	default:
+		s = shellQuote(s)
+	}
+	if backtick {
+		s = shellBackslashed(s)
+	}
+	if delim != "" && endsHeredoc(delim, dash, line, dirty, s) {
+		return "", fmt.Errorf("cannot safely insert %q inside a heredoc: it could end the heredoc delimited by %s", s, delim)
+	}
+	return s, nil
+}
+
+// endsHeredoc 判断插入 s 后是否可能出现内容为 delim 的行
+// line 为当前行中值之前的模板原文；dirty 表示这一行已经插入过值或包含展开，
+// 之前的插入已经保证这一行不是 delim 的前缀，值的第一行无需再检查
+// 值之后的模板原文未知，所以未结束的行只要是 delim 的前缀 (包括空行) 就拒绝
+func endsHeredoc(delim string, dash bool, line string, dirty bool, s string) bool {
+	trim := func(l string) string {
+		if dash {
+			return strings.TrimLeft(l, "\t")
+		}
+		return l
+	}
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		complete := i < len(lines)-1 // 之后还有换行，这一行的内容已经确定
+		if i == 0 && dirty {
+			continue
+		}
+		if i == 0 {
+			l = line + l
+		}
+		if complete && trim(l) == delim || !complete && strings.HasPrefix(delim, trim(l)) {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeState 记录扫描到模板某一位置时所处的上下文
+// 状态是值类型，分支 (if/range/with) 可以直接复制
+type escapeState struct {
+	ctx     escapeContext
+	urlPart byte // 'p' path, 'q' query, 'f' fragment
+	inJSON  bool // 是否位于 JSON 字符串内
+	// shell 引号栈，末尾为当前状态: 'b' 裸文本/子命令, 's' 单引号, 'd' 双引号, 'k' 反引号命令替换,
+	// 'x' 反引号中的嵌套反引号, 'h' heredoc 正文, 'H' 分隔符带引号的 heredoc 正文, 'a'/'A' 算术展开及其中的括号
+	shell   string
+	escape  bool // 上一个字符是反斜杠
+	comment bool // 位于 shell 注释中 (注释里的引号不影响状态)
+	prev    byte // 上一个字符，用于判断 # 是否位于单词开头
+	sub     bool // 上一个字符是 $( 的括号，用于识别 $((
+	heredoc heredocState
+	refuse  string // 非空时无法可靠地判断上下文，之后插入的值都会报错
+}
+
+// heredocState 记录 << 之后读取分隔符和正文的进度
+type heredocState struct {
+	phase  byte   // 0, hdOperator, hdWord, hdPending, hdBody
+	delim  string // 去掉引号后的分隔符
+	quoted bool   // 分隔符带引号或反斜杠: 正文不做展开
+	dash   bool   // <<- 会去掉正文每行开头的 tab
+	quote  byte   // 读取分隔符时所在的引号
+	line   string // 正文当前行中已扫描的原文
+	dirty  bool   // 当前行中插入过值或包含命令替换，不可能是结束分隔符 (由插入时的检查保证)
+}
+
+const heredocDelimReason = "a heredoc whose delimiter comes from a template value"
+
+// heredoc 的解析阶段
+const (
+	hdOperator = 'o' // 刚读到 <<，下一个字符可能是 - 或 < (here-string)
+	hdWord     = 'w' // 读取分隔符
+	hdPending  = 'p' // 分隔符已读完，正文从下一行开始
+	hdBody     = 'b' // 位于正文中
+)
+
+func (st escapeState) escaperName() string {
+	switch st.ctx {
+	case ctxURL:
+		if st.urlPart == 'q' {
+			return escURLQuery
+		}
+		return escURLPath
+	case ctxJSON, ctxJQ:
+		// jq 的字符串和字面量语法与 JSON 兼容，反斜杠被转义后也无法构成 \(...) 插值
+		if st.inJSON {
+			return escJSONString
+		}
+		return escJSONValue
+	case ctxShell:
+		top := st.shell[len(st.shell)-1]
+		switch {
+		case st.refuse != "" || top == 'x' || st.heredoc.phase == hdOperator || st.heredoc.phase == hdWord:
+			return escShellRefuse
+		case strings.IndexByte(st.shell, 'k') >= 0 || st.heredoc.phase == hdBody:
+			return escShellNested
+		case top == 's':
+			return escShellSingle
+		case top == 'd':
+			return escShellDouble
+		}
+		return escShellBare
+	}
+	return escNone
+}
+
+// escaperCommand 构造追加到输出动作末尾的转义命令，反引号和 heredoc 中的转义需要描述上下文的参数
+func (st escapeState) escaperCommand(tree *parse.Tree, pos parse.Pos) *parse.CommandNode {
+	str := func(s string) parse.Node {
+		return &parse.StringNode{NodeType: parse.NodeString, Pos: pos, Quoted: strconv.Quote(s), Text: s}
+	}
+	boolean := func(b bool) parse.Node {
+		return &parse.BoolNode{NodeType: parse.NodeBool, Pos: pos, True: b}
+	}
+	switch name := st.escaperName(); name {
+	case escShellRefuse:
+		reason := st.refuse
+		switch {
+		case reason != "":
+		case st.heredoc.phase == hdOperator || st.heredoc.phase == hdWord:
+			reason = heredocDelimReason
+		default:
+			reason = "nested backticks"
+		}
+		return identCommand(tree, pos, name, str(reason))
+	case escShellNested:
+		top := st.shell[len(st.shell)-1]
+		quote := string(top)
+		if top != 's' && top != 'd' && top != 'h' && top != 'H' {
+			quote = "b"
+		}
+		h := st.heredoc
+		delim, line, dirty := "", h.line, h.dirty
+		if h.phase == hdBody {
+			delim = h.delim
+			if top != 'h' && top != 'H' {
+				// 位于正文中的命令替换内: 当前行已有未扫描完的内容
+				dirty = true
+			}
+		}
+		backtick := strings.IndexByte(st.shell, 'k') >= 0
+		return identCommand(tree, pos, name, str(quote), boolean(backtick), str(delim), boolean(h.dash), str(line), boolean(dirty))
+	default:
+		return identCommand(tree, pos, name)
+	}
+}
+
+// afterAction 更新插入值之后的状态: 插入的值相当于普通单词字符
+func (st escapeState) afterAction() escapeState {
+	st.prev = 'a'
+	st.sub = false
+	switch st.heredoc.phase {
+	case hdOperator, hdWord:
+		st.refuse = heredocDelimReason
+	case hdBody:
+		st.heredoc.dirty = true
+	}
+	return st
+}
+
+// feed 扫描一段模板原文，推进状态
+func (st escapeState) feed(text []byte) escapeState {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch st.ctx {
+		case ctxURL:
+			if c == '?' && st.urlPart == 'p' {
+				st.urlPart = 'q'
+			} else if c == '#' {
+				st.urlPart = 'f'
+			}
+		case ctxJSON, ctxJQ:
+			if st.escape {
+				st.escape = false
+			} else if st.inJSON && c == '\\' {
+				st.escape = true
+			} else if c == '"' {
+				st.inJSON = !st.inJSON
+			}
+		case ctxShell:
+			var next byte
+			if i+1 < len(text) {
+				next = text[i+1]
+			}
+			var consumed bool
+			st, consumed = st.feedShell(c, next)
+			if consumed {
+				i++
+			}
+		}
+	}
+	return st
+}
+
+// feedShell 按 POSIX shell 的引号规则推进一个字符，返回值表示是否同时消费了 next
+func (st escapeState) feedShell(c, next byte) (escapeState, bool) {
+	if st.heredoc.phase == hdOperator || st.heredoc.phase == hdWord {
+		var consumed, done bool
+		if st, consumed, done = st.feedHeredocWord(c, next); !done {
+			return st, consumed
+		}
+	}
+	top := st.shell[len(st.shell)-1]
+	prev, sub := st.prev, st.sub
+	st.prev, st.sub = c, false
+	inBacktick := strings.IndexByte(st.shell, 'k') >= 0
+	switch {
+	case inBacktick && c == '\\' && next == '`':
+		// 反引号中的 \` 开始或结束嵌套的反引号，其中的值需要多层转义，不支持
+		if top == 'x' {
+			st.shell = st.shell[:len(st.shell)-1]
+		} else {
+			st.shell += "x"
+		}
+		return st, true
+	case inBacktick && c == '`' && !st.escape:
+		// 反引号命令替换在第一个未转义的反引号处结束，与其中的引号无关
+		st.shell = st.shell[:strings.LastIndexByte(st.shell, 'k')]
+		st.comment = false
+	case top == 'x':
+	case st.comment:
+		if c == '\n' {
+			st.comment = false
+			st = st.startHeredoc()
+		}
+	case st.escape:
+		st.escape = false
+	case top == 'h' || top == 'H':
+		return st.feedHeredocBody(top, c, next)
+	case top == 's':
+		if c == '\'' {
+			st.shell = st.shell[:len(st.shell)-1]
+		}
+	case c == '\\':
+		st.escape = true
+	case c == '$' && next == '(':
+		st.shell += "b"
+		st.prev, st.sub = next, true
+		return st, true
+	case c == '`':
+		st.shell += "k"
+	case top == 'd':
+		if c == '"' {
+			st.shell = st.shell[:len(st.shell)-1]
+		}
+	case top == 'a' || top == 'A':
+		// 算术展开中的 << 是移位运算；只跟踪括号以找到结尾的 ))
+		switch {
+		case c == '(':
+			st.shell += "A"
+		case c == ')' && top == 'A':
+			st.shell = st.shell[:len(st.shell)-1]
+		case c == ')' && next == ')':
+			st.shell = st.shell[:len(st.shell)-1]
+			return st, true
+		}
+	case c == '(' && prev == '(' && top == 'b':
+		if sub {
+			// $(( 是算术展开而不是命令替换
+			st.shell = st.shell[:len(st.shell)-1]
+		}
+		st.shell += "a"
+	case c == '<' && next == '<':
+		if top != 'b' || st.heredoc.phase != 0 {
+			st.refuse = "a heredoc nested in backticks or another heredoc"
+		} else {
+			st.heredoc = heredocState{phase: hdOperator}
+		}
+		return st, true
+	case c == '\n' && top == 'b':
+		st = st.startHeredoc()
+	case c == '#' && strings.IndexByte(" \t\n;&|(", prev) >= 0:
+		st.comment = true
+	case c == '\'':
+		st.shell += "s"
+	case c == '"':
+		st.shell += "d"
+	case c == ')' && top == 'b' && len(st.shell) > 1:
+		st.shell = st.shell[:len(st.shell)-1]
+	}
+	return st, false
+}
+
+// feedHeredocWord 读取 << 之后的分隔符，done 表示分隔符已结束，c 需要按普通字符继续处理
+func (st escapeState) feedHeredocWord(c, next byte) (_ escapeState, consumed, done bool) {
+	h := &st.heredoc
+	if h.phase == hdOperator {
+		h.phase = hdWord
+		switch c {
+		case '<':
+			// <<< 是 here-string
+			st.heredoc = heredocState{}
+			return st, false, false
+		case '-':
+			h.dash = true
+			return st, false, false
+		}
+	}
+	switch {
+	case h.quote != 0:
+		if c == h.quote {
+			h.quote = 0
+		} else {
+			h.delim += string(c)
+		}
+	case c == '\'' || c == '"':
+		h.quoted, h.quote = true, c
+	case c == '\\':
+		h.quoted = true
+		h.delim += string(next)
+		return st, true, false
+	case strings.IndexByte(" \t\n;&|<>()", c) >= 0:
+		if h.delim == "" && !h.quoted {
+			if c == ' ' || c == '\t' {
+				return st, false, false
+			}
+			st.refuse = "a heredoc without a delimiter"
+			st.heredoc = heredocState{}
+			return st, false, true
+		}
+		h.phase = hdPending
+		return st, false, true
+	default:
+		h.delim += string(c)
+	}
+	return st, false, false
+}
+
+// startHeredoc 在行尾进入已读到分隔符的 heredoc 正文
+func (st escapeState) startHeredoc() escapeState {
+	if st.heredoc.phase != hdPending {
+		return st
+	}
+	if st.heredoc.quoted {
+		st.shell += "H"
+	} else {
+		st.shell += "h"
+	}
+	st.heredoc.phase, st.heredoc.line, st.heredoc.dirty = hdBody, "", false
+	return st
+}
+
+// feedHeredocBody 推进 heredoc 正文: 按行查找结束分隔符；未加引号时 $(、` 和 \ 仍然有效
+func (st escapeState) feedHeredocBody(top, c, next byte) (escapeState, bool) {
+	h := &st.heredoc
+	if c == '\n' {
+		line := h.line
+		if h.dash {
+			line = strings.TrimLeft(line, "\t")
+		}
+		if !h.dirty && line == h.delim {
+			st.shell = st.shell[:len(st.shell)-1]
+			st.heredoc = heredocState{}
+		} else {
+			h.line, h.dirty = "", false
+		}
+		return st, false
+	}
+	h.line += string(c)
+	if top == 'H' {
+		return st, false
+	}
+	switch {
+	case c == '\\':
+		st.escape, h.dirty = true, true
+	case c == '$' && next == '(':
+		h.dirty = true
+		st.shell += "b"
+		st.prev, st.sub = next, true
+		return st, true
+	case c == '`':
+		h.dirty = true
+		st.shell += "k"
+	}
+	return st, false
+}
+
+// envEscaperName 返回 ${ENV} 的转义函数
+// 环境变量由配置作者控制，URL path 中原样插入 (常用于 ${API_BASE}/users)，
+// 但在 query 和 JSON 字符串中仍需转义，避免值中的 & 或 " 破坏结构
+func (st escapeState) envEscaperName() string {
+	switch st.escaperName() {
+	case escURLQuery, escJSONString:
+		return st.escaperName()
+	}
+	return escNone
+}
+
+// addEscapers 遍历模板语法树，为每个输出动作追加当前上下文的转义函数
+// expandEnv 为 true 时，把文本节点中的 ${ENV} 拆分为读取环境变量的动作，同样按上下文转义
+func addEscapers(tree *parse.Tree, ctx escapeContext, expandEnv bool) {
+	st := escapeState{ctx: ctx, urlPart: 'p', shell: "b", prev: '\n'}
+	escapeList(tree, tree.Root, st, expandEnv)
+}
+
+func escapeList(tree *parse.Tree, list *parse.ListNode, st escapeState, expandEnv bool) escapeState {
+	if list == nil {
+		return st
+	}
+	nodes := make([]parse.Node, 0, len(list.Nodes))
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.TextNode:
+			if !expandEnv {
+				st = st.feed(n.Text)
+				break
+			}
+			texts, names := splitEnv(string(n.Text))
+			for i, text := range texts {
+				if text != "" {
+					nodes = append(nodes, &parse.TextNode{NodeType: parse.NodeText, Pos: n.Pos, Text: []byte(text)})
+					st = st.feed([]byte(text))
+				}
+				if i < len(names) {
+					nodes = append(nodes, envAction(tree, n.Pos, names[i], st.envEscaperName()))
+					st = st.afterAction()
+				}
+			}
+			continue
+		case *parse.ActionNode:
+			// {{$x := ...}} 这类声明不产生输出
+			if len(n.Pipe.Decl) > 0 {
+				break
+			}
+			n.Pipe.Cmds = append(n.Pipe.Cmds, st.escaperCommand(tree, n.Pos))
+			st = st.afterAction()
+		case *parse.IfNode:
+			st = escapeBranch(tree, &n.BranchNode, st, expandEnv)
+		case *parse.RangeNode:
+			st = escapeBranch(tree, &n.BranchNode, st, expandEnv)
+		case *parse.WithNode:
+			st = escapeBranch(tree, &n.BranchNode, st, expandEnv)
+		}
+		nodes = append(nodes, node)
+	}
+	list.Nodes = nodes
+	return st
+}
+
+// escapeBranch 两个分支都从同一状态开始，之后沿用主分支的结束状态
+func escapeBranch(tree *parse.Tree, b *parse.BranchNode, st escapeState, expandEnv bool) escapeState {
+	escapeList(tree, b.ElseList, st, expandEnv)
+	return escapeList(tree, b.List, st, expandEnv)
+}
+
+// identCommand 构造只调用一个函数的管道命令，如 | _slEscNone
+func identCommand(tree *parse.Tree, pos parse.Pos, name string, args ...parse.Node) *parse.CommandNode {
+	ident := parse.NewIdentifier(name)
+	return &parse.CommandNode{
+		NodeType: parse.NodeCommand,
+		Pos:      pos,
+		Args:     append([]parse.Node{ident.SetTree(tree).SetPos(pos)}, args...),
+	}
+}
+
+// envAction 构造 {{_slEnv "NAME" | escaper}}
+func envAction(tree *parse.Tree, pos parse.Pos, name, escaper string) *parse.ActionNode {
+	arg := &parse.StringNode{NodeType: parse.NodeString, Pos: pos, Quoted: strconv.Quote(name), Text: name}
+	return &parse.ActionNode{
+		NodeType: parse.NodeAction,
+		Pos:      pos,
+		Pipe: &parse.PipeNode{
+			NodeType: parse.NodePipe,
+			Pos:      pos,
+			Cmds:     []*parse.CommandNode{identCommand(tree, pos, envFunc, arg), identCommand(tree, pos, escaper)},
+		},
+	}
+}
+
+// splitEnv 按 os.Expand 的规则拆分文本: texts 比 names 多一项，两者交替组成原文
+// 借助 os.Expand 本身定位变量，保证与之前展开 $VAR / ${VAR} 的语法完全一致
+func splitEnv(s string) (texts, names []string) {
+	if !strings.Contains(s, "$") || strings.Contains(s, "\x00") {
+		return []string{s}, nil
+	}
+	out := os.Expand(s, func(name string) string {
+		names = append(names, name)
+		return "\x00"
+	})
+	return strings.Split(out, "\x00"), names
+}
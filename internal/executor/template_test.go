@@ -0,0 +1,221 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+
+	"sl-cli/internal/config"
+)
+
+func TestRenderTemplateEscaping(t *testing.T) {
+	data := map[string]interface{}{
+		"args": map[string]interface{}{
+			"s":     `a b/c"d'e$f`,
+			"n":     42,
+			"ok":    true,
+			"none":  nil,
+			"shell": `x; rm -rf / #`,
+			"path":  "a/b",
+			"list":  []string{"x", "y"},
+			"cmd":   "$(touch /tmp/pwned)",
+			"tick":  "a`id`b",
+		},
+		"vars": map[string]interface{}{
+			"host": trusted("https://example.com/api"),
+		},
+	}
+	tests := []struct {
+		name string
+		ctx  escapeContext
+		tpl  string
+		want string
+	}{
+		{"none", ctxNone, `{{.args.s}}`, `a b/c"d'e$f`},
+		{"url path", ctxURL, `/users/{{.args.s}}`, `/users/a%20b%2Fc%22d%27e$f`},
+		{"url query", ctxURL, `/search?q={{.args.s}}`, `/search?q=a+b%2Fc%22d%27e%24f`},
+		{"url path then query", ctxURL, `/{{.args.path}}?p={{.args.path}}`, `/a%2Fb?p=a%2Fb`},
+		{"url trusted var", ctxURL, `{{.vars.host}}/users`, `https://example.com/api/users`},
+		{"url raw", ctxURL, `/{{raw .args.path}}`, `/a/b`},
+		{"url raw pipe", ctxURL, `/{{.args.path | raw}}`, `/a/b`},
+		{"json string", ctxJSON, `{"s": "{{.args.s}}"}`, `{"s": "a b/c\"d'e$f"}`},
+		{"json value string", ctxJSON, `{"s": {{.args.s}}}`, `{"s": "a b/c\"d'e$f"}`},
+		{"json value number", ctxJSON, `{"n": {{.args.n}}}`, `{"n": 42}`},
+		{"json value bool", ctxJSON, `{"ok": {{.args.ok}}}`, `{"ok": true}`},
+		{"json value nil", ctxJSON, `{"v": {{.args.none}}}`, `{"v": null}`},
+		{"json escaped quote", ctxJSON, `{"a": "x\"{{.args.n}}", "b": {{.args.n}}}`, `{"a": "x\"42", "b": 42}`},
+		{"json raw", ctxJSON, `{"s": {{raw .args.n}}}`, `{"s": 42}`},
+		{"shell bare", ctxShell, `echo {{.args.shell}}`, `echo 'x; rm -rf / #'`},
+		{"shell single", ctxShell, `echo '{{.args.s}}'`, `echo 'a b/c"d'\''e$f'`},
+		{"shell double", ctxShell, `echo "{{.args.s}}"`, `echo "a b/c\"d'e\$f"`},
+		{"shell subshell in double", ctxShell, `echo "$(echo {{.args.shell}})"`, `echo "$(echo 'x; rm -rf / #')"`},
+		{"shell comment quote", ctxShell, "# it's\necho {{.args.path}}", "# it's\necho 'a/b'"},
+		{"shell trusted", ctxShell, `curl {{.vars.host}}`, `curl https://example.com/api`},
+		{"shell heredoc", ctxShell, "cat <<EOF\n{{.args.cmd}}\nEOF\necho {{.args.path}}", "cat <<EOF\n\\$(touch /tmp/pwned)\nEOF\necho 'a/b'"},
+		{"shell quoted heredoc", ctxShell, "cat <<'EOF'\n{{.args.cmd}}\nEOF\necho {{.args.path}}", "cat <<'EOF'\n$(touch /tmp/pwned)\nEOF\necho 'a/b'"},
+		{"shell heredoc dash", ctxShell, "cat <<-EOF\n\t{{.args.s}}\n\tEOF\necho {{.args.path}}", "cat <<-EOF\n\ta b/c\"d'e\\$f\n\tEOF\necho 'a/b'"},
+		{"shell heredoc substitution", ctxShell, "cat <<EOF\n$(echo {{.args.shell}})\nEOF", "cat <<EOF\n$(echo 'x; rm -rf / #')\nEOF"},
+		{"shell here-string", ctxShell, `cat <<<{{.args.shell}}`, `cat <<<'x; rm -rf / #'`},
+		{"shell arithmetic shift", ctxShell, `echo $((1<<2)) {{.args.shell}}`, `echo $((1<<2)) 'x; rm -rf / #'`},
+		{"shell backtick", ctxShell, "echo `echo {{.args.cmd}}`", "echo `echo '\\$(touch /tmp/pwned)'`"},
+		{"shell backtick in double", ctxShell, "echo \"`echo {{.args.tick}}`\"", "echo \"`echo 'a\\`id\\`b'`\""},
+		{"jq string", ctxJQ, `select(.name == "{{.args.s}}")`, `select(.name == "a b/c\"d'e$f")`},
+		{"jq literal", ctxJQ, `.{{.args.s}}`, `."a b/c\"d'e$f"`},
+		{"jq number", ctxJQ, `.[{{.args.n}}]`, `.[42]`},
+		{"if branch", ctxURL, `/{{if .args.ok}}{{.args.path}}{{end}}?q={{.args.path}}`, `/a%2Fb?q=a%2Fb`},
+		{"range", ctxJSON, `[{{range .args.list}}"{{.}}",{{end}}{{.args.n}}]`, `["x","y",42]`},
+		{"declaration", ctxURL, `{{$p := .args.path}}/{{$p}}`, `/a%2Fb`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderTemplate(tt.tpl, data, tt.ctx)
+			if err != nil {
+				t.Fatalf("renderTemplate(%q) error: %v", tt.tpl, err)
+			}
+			if got != tt.want {
+				t.Errorf("renderTemplate(%q)\n got: %s\nwant: %s", tt.tpl, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRenderTemplateShellRefuse 检查无法可靠转义的 shell 上下文会报错，而不是插入未转义的值
+func TestRenderTemplateShellRefuse(t *testing.T) {
+	tests := []struct {
+		name    string
+		tpl     string
+		value   string
+		wantErr string
+	}{
+		{"heredoc delimiter in value", "cat <<EOF\n{{.args.v}}\nEOF", "x\nEOF\nrm -rf ~", "could end the heredoc"},
+		{"heredoc delimiter prefix", "cat <<EOF\n{{.args.v}}OF\nEOF", "E", "could end the heredoc"},
+		// 值之后的原文可能补全分隔符，空值同样拒绝
+		{"heredoc empty line start", "cat <<EOF\n{{.args.v}}\nEOF", "", "could end the heredoc"},
+		{"heredoc dash tab", "cat <<-EOF\n{{.args.v}}EOF\nEOF", "\t", "could end the heredoc"},
+		{"heredoc delimiter from value", "cat <<{{.args.v}}\nx\nEOF", "EOF", "delimiter comes from a template value"},
+		{"heredoc in backticks", "echo `cat <<EOF` {{.args.v}}", "x", "heredoc nested in backticks"},
+		{"nested backticks", "echo `echo \\`echo {{.args.v}}\\``", "x", "nested backticks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]interface{}{"args": map[string]interface{}{"v": tt.value}}
+			got, err := renderTemplate(tt.tpl, data, ctxShell)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("renderTemplate(%q) = %q, %v, want error %q", tt.tpl, got, err, tt.wantErr)
+			}
+		})
+	}
+	// 同一行中其他位置的值和之后的行不受影响
+	data := map[string]interface{}{"args": map[string]interface{}{"v": "EOF"}}
+	got, err := renderTemplate("cat <<EOF\nx {{.args.v}}\nEOF\necho {{.args.v}}", data, ctxShell)
+	if want := "cat <<EOF\nx EOF\nEOF\necho 'EOF'"; err != nil || got != want {
+		t.Errorf("renderTemplate = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestIsJSONBody(t *testing.T) {
+	tests := []struct {
+		api  config.APIConfig
+		want bool
+	}{
+		{config.APIConfig{Body: `{"a": 1}`}, true},
+		{config.APIConfig{Body: ` [1]`}, true},
+		{config.APIConfig{Body: `a=1`}, false},
+		{config.APIConfig{Body: `{"a": 1}`, Headers: map[string]string{"content-type": "text/plain"}}, false},
+		{config.APIConfig{Body: `a`, Headers: map[string]string{"Content-Type": "application/vnd.api+json"}}, true},
+	}
+	for _, tt := range tests {
+		if got := isJSONBody(tt.api); got != tt.want {
+			t.Errorf("isJSONBody(%q, %v) = %v, want %v", tt.api.Body, tt.api.Headers, got, tt.want)
+		}
+	}
+}
+
+func TestInterpolateEnv(t *testing.T) {
+	env := &Env{Environ: []string{
+		"API_BASE=https://api.example.com/v1",
+		"GITHUB_TOKEN=secret-token",
+		"QUOTE=x\"y",
+		"AMP=a&b c",
+		"PORT=8080",
+		"BRACES={{.args.name}}",
+	}}
+	cfg := config.CommandConfig{Params: []config.ParamConfig{{Name: "name"}}}
+	tests := []struct {
+		name string
+		arg  string
+		ctx  escapeContext
+		tpl  string
+		want string
+	}{
+		{"url base", "bob", ctxURL, `${API_BASE}/users/{{.args.name}}`, `https://api.example.com/v1/users/bob`},
+		{"url query env", "bob", ctxURL, `/x?t=${AMP}&u={{.args.name}}`, `/x?t=a%26b+c&u=bob`},
+		{"json string env", "bob", ctxJSON, `{"q": "${QUOTE}", "p": ${PORT}}`, `{"q": "x\"y", "p": 8080}`},
+		{"none", "bob", ctxNone, `Bearer $GITHUB_TOKEN`, `Bearer secret-token`},
+		{"env value is not a template", "bob", ctxNone, `${BRACES}`, `{{.args.name}}`},
+		{"unset env", "bob", ctxNone, `[${NOPE}]`, `[]`},
+		// 用户输入中的 $VAR 不会被展开
+		{"arg in path", "$GITHUB_TOKEN", ctxURL, `/u/{{.args.name}}`, `/u/$GITHUB_TOKEN`},
+		{"arg in query", "${GITHUB_TOKEN}", ctxURL, `/u?q={{.args.name}}`, `/u?q=%24%7BGITHUB_TOKEN%7D`},
+		{"arg in json", "$GITHUB_TOKEN", ctxJSON, `{"n": "{{.args.name}}"}`, `{"n": "$GITHUB_TOKEN"}`},
+		{"arg in header", "$GITHUB_TOKEN", ctxNone, `{{.args.name}}`, `$GITHUB_TOKEN`},
+		{"raw arg", "$GITHUB_TOKEN", ctxNone, `{{raw .args.name}}`, `$GITHUB_TOKEN`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := newScope(env, cfg, Input{Args: []string{tt.arg}, Params: map[string]interface{}{"name": tt.arg}})
+			got, err := sc.interpolate(tt.tpl, tt.ctx)
+			if err != nil {
+				t.Fatalf("interpolate(%q) error: %v", tt.tpl, err)
+			}
+			if got != tt.want {
+				t.Errorf("interpolate(%q)\n got: %s\nwant: %s", tt.tpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveVars(t *testing.T) {
+	env := &Env{Environ: []string{"HOST=https://example.com", "SECRET=s3cr3t"}}
+	vars := map[string]string{
+		"host":  "${HOST}/api",
+		"greet": "hello {{.args.name}}",
+		"first": "{{index .args 0}}",
+		"bad":   "{{.args.name",
+	}
+	cfg := config.CommandConfig{Params: []config.ParamConfig{{Name: "name"}}}
+	in := Input{Args: []string{`a b"$SECRET`}, Params: map[string]interface{}{"name": `a b"$SECRET`}, Vars: vars}
+	sc := newScope(env, cfg, in)
+
+	tests := []struct {
+		name    string
+		ctx     escapeContext
+		tpl     string
+		want    string
+		wantErr string
+	}{
+		{"static var is trusted", ctxURL, `{{.vars.host}}/users`, `https://example.com/api/users`, ""},
+		{"var using args is escaped in url", ctxURL, `/x?g={{.vars.greet}}`, `/x?g=hello+a+b%22%24SECRET`, ""},
+		{"var using args is escaped in json", ctxJSON, `{"g": "{{.vars.greet}}"}`, `{"g": "hello a b\"$SECRET"}`, ""},
+		{"failed var errors when used", ctxNone, `{{.vars.first}}`, "", "failed to render var 'first'"},
+		{"failed var errors with raw", ctxNone, `{{raw .vars.first}}`, "", "failed to render var 'first'"},
+		{"invalid var errors when used", ctxJSON, `{{.vars.bad}}`, "", "failed to render var 'bad'"},
+		{"unused failed var is ignored", ctxNone, `ok`, `ok`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sc.interpolate(tt.tpl, tt.ctx)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("interpolate(%q) error = %v, want %q", tt.tpl, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("interpolate(%q) error: %v", tt.tpl, err)
+			}
+			if got != tt.want {
+				t.Errorf("interpolate(%q)\n got: %s\nwant: %s", tt.tpl, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,511 @@
+package executor
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ================= HTTP Trace & HAR =================
+
+// httpRecorder 包装 HTTP 传输层，记录一次命令中的全部请求 (包括重定向的每一跳)
+// 开启 Env.Trace 时实时输出请求、响应和耗时，设置了 Env.HARFile 时在结束后写出 HAR 文件
+type httpRecorder struct {
+	base  http.RoundTripper
+	now   func() time.Time
+	trace io.Writer
+	har   string
+
+	mu        sync.Mutex
+	exchanges []*exchange
+}
+
+// newHTTPRecorder 在需要追踪或导出 HAR 时返回 recorder，否则返回 nil
+func newHTTPRecorder(env *Env, base http.RoundTripper) *httpRecorder {
+	if env.Trace == nil && env.HARFile == "" {
+		return nil
+	}
+	return &httpRecorder{base: base, now: env.Now, trace: env.Trace, har: env.HARFile}
+}
+
+// exchange 是一次请求/响应及其各阶段的时间点
+type exchange struct {
+	mu  sync.Mutex
+	rec *httpRecorder
+
+	req     *http.Request
+	reqBody []byte
+	secrets []string // 认证凭据，输出前隐藏
+	resp    *http.Response
+	err     error
+	body    bytes.Buffer // 响应 Body，仅导出 HAR 时保存，最多 maxRecordedBody
+	size    int64
+	clipped bool // 响应 Body 超过 maxRecordedBody，只保存了开头部分
+
+	start, dnsStart, dnsDone, connStart, connDone, tlsStart, tlsDone time.Time
+	gotConn, wrote, firstByte, end                                   time.Time
+	reused                                                           bool
+	remoteAddr                                                       string
+}
+
+// maxRecordedBody 是 trace 和 HAR 记录的请求、响应 Body 的上限
+const maxRecordedBody = 1 << 20
+
+func (r *httpRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	x := &exchange{rec: r, req: req, start: r.now(), secrets: secretsFrom(req.Context())}
+	// 只记录不超过 maxRecordedBody 的 Body，避免上传大文件时全部读入内存
+	if req.Body != nil && req.GetBody != nil && req.ContentLength >= 0 && req.ContentLength <= maxRecordedBody {
+		if body, err := req.GetBody(); err == nil {
+			x.reqBody, _ = io.ReadAll(body)
+		}
+	}
+	r.mu.Lock()
+	r.exchanges = append(r.exchanges, x)
+	r.mu.Unlock()
+
+	r.printRequest(x)
+	resp, err := r.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), x.clientTrace())))
+	if err != nil {
+		x.mu.Lock()
+		x.err, x.end = err, r.now()
+		x.mu.Unlock()
+		r.printf("* Error: %s\n", redact(err.Error(), x.secrets))
+		return nil, err
+	}
+	x.mu.Lock()
+	x.resp = resp
+	x.mu.Unlock()
+	r.printResponse(x)
+	resp.Body = &recordedBody{ReadCloser: resp.Body, x: x}
+	return resp, nil
+}
+
+// clientTrace 记录连接建立和请求发送各阶段的时间点
+// 回调可能来自不同的 goroutine (例如双栈同时拨号)，需要加锁
+func (x *exchange) clientTrace() *httptrace.ClientTrace {
+	mark := func(t *time.Time) {
+		x.mu.Lock()
+		if t.IsZero() {
+			*t = x.rec.now()
+		}
+		x.mu.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { mark(&x.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { mark(&x.dnsDone) },
+		ConnectStart:      func(string, string) { mark(&x.connStart) },
+		ConnectDone:       func(string, string, error) { mark(&x.connDone) },
+		TLSHandshakeStart: func() { mark(&x.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { mark(&x.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			mark(&x.gotConn)
+			x.mu.Lock()
+			x.reused = info.Reused
+			if info.Conn != nil {
+				x.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+			x.mu.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { mark(&x.wrote) },
+		GotFirstResponseByte: func() { mark(&x.firstByte) },
+	}
+}
+
+// recordedBody 在 Body 读完或关闭时记录传输结束时间，导出 HAR 时同时保存内容
+type recordedBody struct {
+	io.ReadCloser
+	x    *exchange
+	once sync.Once
+}
+
+func (b *recordedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.x.mu.Lock()
+	b.x.size += int64(n)
+	if b.x.rec.har != "" {
+		// 超出上限的部分不再保存，避免下载大文件时全部留在内存中
+		keep := min(n, maxRecordedBody-b.x.body.Len())
+		b.x.body.Write(p[:keep])
+		if keep < n {
+			b.x.clipped = true
+		}
+	}
+	b.x.mu.Unlock()
+	if err == io.EOF {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *recordedBody) Close() error {
+	b.done()
+	return b.ReadCloser.Close()
+}
+
+func (b *recordedBody) done() {
+	b.once.Do(func() {
+		b.x.mu.Lock()
+		b.x.end = b.x.rec.now()
+		b.x.mu.Unlock()
+		b.x.rec.printTiming(b.x)
+	})
+}
+
+// header 和 url 隐藏 Header、URL 中的敏感信息
+func (x *exchange) header(name, value string) string {
+	return redact(maskHeader(name, value), x.secrets)
+}
+
+func (x *exchange) url(u *url.URL) string {
+	return redact(maskURL(u), x.secrets)
+}
+
+// redirected 判断响应是否为重定向
+func (x *exchange) redirected() bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.resp == nil || x.resp.StatusCode < 300 || x.resp.StatusCode >= 400 {
+		return false
+	}
+	_, err := x.resp.Location()
+	return err == nil
+}
+
+// ---------- Trace Output ----------
+
+func (r *httpRecorder) printf(format string, args ...interface{}) {
+	if r.trace != nil {
+		fmt.Fprintf(r.trace, format, args...)
+	}
+}
+
+func (r *httpRecorder) printRequest(x *exchange) {
+	if r.trace == nil {
+		return
+	}
+	req := x.req
+	r.printf("> %s %s %s\n", req.Method, x.url(req.URL), requestProto(req))
+	r.printf("> Host: %s\n", req.URL.Host)
+	for _, k := range sortedHeaderKeys(req.Header) {
+		for _, v := range req.Header[k] {
+			r.printf("> %s: %s\n", k, x.header(k, v))
+		}
+	}
+	r.printf(">\n")
+}
+
+func (r *httpRecorder) printResponse(x *exchange) {
+	if r.trace == nil {
+		return
+	}
+	x.mu.Lock()
+	reused, addr := x.reused, x.remoteAddr
+	x.mu.Unlock()
+	if reused {
+		r.printf("* Reused connection to %s\n", addr)
+	} else if addr != "" {
+		r.printf("* Connected to %s\n", addr)
+	}
+
+	resp := x.resp
+	r.printf("< %s %s\n", resp.Proto, resp.Status)
+	for _, k := range sortedHeaderKeys(resp.Header) {
+		for _, v := range resp.Header[k] {
+			r.printf("< %s: %s\n", k, x.header(k, v))
+		}
+	}
+	r.printf("<\n")
+	if loc, err := resp.Location(); err == nil && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		r.printf("* Redirect to %s\n", x.url(loc))
+	}
+}
+
+func (r *httpRecorder) printTiming(x *exchange) {
+	if r.trace == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	r.printf("* Timing: dns %s | connect %s | tls %s | ttfb %s | transfer %s | total %s (%d bytes)\n",
+		span(x.dnsStart, x.dnsDone), span(x.connStart, x.connDone), span(x.tlsStart, x.tlsDone),
+		span(x.wrote, x.firstByte), span(x.firstByte, x.end), span(x.start, x.end), x.size)
+}
+
+// span 格式化两个时间点之间的耗时，阶段未发生 (如复用连接时的 DNS) 时输出 "-"
+func span(from, to time.Time) string {
+	if from.IsZero() || to.IsZero() {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fms", millis(from, to))
+}
+
+// requestProto 返回请求的协议版本；重定向时由 http.Client 创建的请求没有设置 Proto
+func requestProto(req *http.Request) string {
+	if req.Proto == "" {
+		return "HTTP/1.1"
+	}
+	return req.Proto
+}
+
+func millis(from, to time.Time) float64 {
+	return float64(to.Sub(from)) / float64(time.Millisecond)
+}
+
+// finish 输出重定向链并写出 HAR 文件
+func (r *httpRecorder) finish() error {
+	r.mu.Lock()
+	exchanges := r.exchanges
+	r.mu.Unlock()
+
+	// 从最后一次请求往前，找出最后一次尝试的重定向链 (之前的请求可能是被重试的失败请求)
+	first := len(exchanges) - 1
+	for first > 0 && exchanges[first-1].redirected() {
+		first--
+	}
+	if chain := exchanges[max(first, 0):]; len(chain) > 1 {
+		urls := make([]string, len(chain))
+		for i, x := range chain {
+			urls[i] = x.url(x.req.URL)
+		}
+		r.printf("* Redirect chain: %s\n", strings.Join(urls, " -> "))
+	}
+
+	if r.har == "" {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(harLog(exchanges)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.har, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write HAR file: %w", err)
+	}
+	return nil
+}
+
+// ---------- HAR ----------
+
+// HAR 1.2 格式，见 http://www.softwareishard.com/blog/har-12-spec/
+// Header 和 query 参数中的敏感信息与 --trace 一样被隐藏；Body 中只隐藏已知的认证凭据
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+	Error       string         `json:"_error,omitempty"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harFile struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+func harLog(exchanges []*exchange) harFile {
+	var f harFile
+	f.Log.Version = "1.2"
+	f.Log.Creator = harCreator{Name: "sl-cli", Version: "devel"}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		f.Log.Creator.Version = info.Main.Version
+	}
+	f.Log.Entries = make([]harEntry, 0, len(exchanges))
+	for _, x := range exchanges {
+		x.mu.Lock()
+		f.Log.Entries = append(f.Log.Entries, x.harEntry())
+		x.mu.Unlock()
+	}
+	return f
+}
+
+func (x *exchange) harEntry() harEntry {
+	req := x.req
+	e := harEntry{
+		StartedDateTime: x.start.Format(time.RFC3339Nano),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         x.url(req.URL),
+			HTTPVersion: requestProto(req),
+			Cookies:     []harNameValue{},
+			Headers:     x.harHeaders(req.Header),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    int(req.ContentLength),
+		},
+		Response: harResponse{
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+	}
+	query := req.URL.Query()
+	for _, k := range sortedHeaderKeys(http.Header(query)) {
+		for _, v := range query[k] {
+			if isSecretName(k) {
+				v = maskedValue
+			}
+			v = redact(v, x.secrets)
+			e.Request.QueryString = append(e.Request.QueryString, harNameValue{Name: k, Value: v})
+		}
+	}
+	if len(x.reqBody) > 0 {
+		e.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: redact(string(x.reqBody), x.secrets)}
+	}
+
+	if x.resp != nil {
+		resp := x.resp
+		e.Response.Status = resp.StatusCode
+		e.Response.StatusText = strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode)))
+		e.Response.HTTPVersion = resp.Proto
+		e.Response.Headers = x.harHeaders(resp.Header)
+		e.Response.BodySize = x.size
+		e.Response.Content = harContent{Size: x.size, MimeType: resp.Header.Get("Content-Type")}
+		body := x.body.Bytes()
+		text := body
+		if x.clipped {
+			// 截断处可能位于多字节字符中间，去掉不完整的字符后再判断是否为文本
+			for i := 0; i < utf8.UTFMax-1 && len(text) > 0 && !utf8.Valid(text); i++ {
+				text = text[:len(text)-1]
+			}
+		}
+		if utf8.Valid(text) {
+			e.Response.Content.Text = redact(string(text), x.secrets)
+		} else {
+			text = body
+			e.Response.Content.Text = base64.StdEncoding.EncodeToString(body)
+			e.Response.Content.Encoding = "base64"
+		}
+		if x.clipped {
+			e.Response.Content.Comment = fmt.Sprintf("truncated: only the first %d of %d bytes were recorded", len(text), x.size)
+		}
+		if loc, err := resp.Location(); err == nil {
+			e.Response.RedirectURL = x.url(loc)
+		}
+	}
+	if x.err != nil {
+		e.Response.Error = redact(x.err.Error(), x.secrets)
+	}
+	if host, _, err := net.SplitHostPort(x.remoteAddr); err == nil {
+		e.ServerIPAddress = host
+	}
+
+	// HAR 中不适用的阶段记为 -1，connect 包含 TLS 握手时间
+	phase := func(from, to time.Time) float64 {
+		if from.IsZero() || to.IsZero() {
+			return -1
+		}
+		return millis(from, to)
+	}
+	e.Timings = harTimings{
+		Blocked: -1,
+		DNS:     phase(x.dnsStart, x.dnsDone),
+		Connect: phase(x.connStart, x.connDone),
+		SSL:     phase(x.tlsStart, x.tlsDone),
+		Send:    phase(x.gotConn, x.wrote),
+		Wait:    phase(x.wrote, x.firstByte),
+		Receive: phase(x.firstByte, x.end),
+	}
+	if e.Timings.Connect >= 0 && e.Timings.SSL >= 0 {
+		e.Timings.Connect += e.Timings.SSL
+	}
+	// send/wait/receive 是必填项，不能为 -1
+	for _, t := range []*float64{&e.Timings.Send, &e.Timings.Wait, &e.Timings.Receive} {
+		if *t < 0 {
+			*t = 0
+		}
+	}
+	e.Time = phase(x.start, x.end)
+	if e.Time < 0 {
+		e.Time = 0
+	}
+	return e
+}
+
+func (x *exchange) harHeaders(h http.Header) []harNameValue {
+	out := []harNameValue{}
+	for _, k := range sortedHeaderKeys(h) {
+		for _, v := range h[k] {
+			out = append(out, harNameValue{Name: k, Value: x.header(k, v)})
+		}
+	}
+	return out
+}
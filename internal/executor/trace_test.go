@@ -0,0 +1,222 @@
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"sl-cli/internal/config"
+)
+
+func traceServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/start":
+			http.Redirect(w, r, "/end?token=abc&page=1", http.StatusFound)
+		case "/binary":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte{0xff, 0xfe, 0x00})
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Set-Cookie", "session=s3cr3t")
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTrace(t *testing.T) {
+	srv := traceServer(t)
+	env, stdout, stderr := testEnv(t)
+	env.Trace = stderr
+	api := config.APIConfig{URL: srv.URL + "/start", Headers: map[string]string{"Authorization": "Bearer t0k"}}
+	if err := runAPI(t, env, api, Input{}); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if stdout.String() != "{\"ok\":true}\n" {
+		t.Errorf("stdout = %q, the trace must not go to stdout", stdout)
+	}
+
+	trace := stderr.String()
+	for _, want := range []string{
+		"> GET " + srv.URL + "/start HTTP/1.1\n",
+		"> Authorization: Bearer ****\n",
+		"< HTTP/1.1 302 Found\n",
+		"* Redirect to " + srv.URL + "/end?token=****&page=1\n",
+		"> GET " + srv.URL + "/end?token=****&page=1 HTTP/1.1\n",
+		"< Set-Cookie: ****\n",
+		"* Timing: dns ",
+		"* Redirect chain: " + srv.URL + "/start -> " + srv.URL + "/end?token=****&page=1\n",
+	} {
+		if !strings.Contains(trace, want) {
+			t.Errorf("trace missing %q:\n%s", want, trace)
+		}
+	}
+	for _, secret := range []string{"t0k", "abc", "s3cr3t"} {
+		if strings.Contains(trace, secret) {
+			t.Errorf("trace leaks %q:\n%s", secret, trace)
+		}
+	}
+}
+
+func TestHAR(t *testing.T) {
+	srv := traceServer(t)
+	tests := []struct {
+		name  string
+		api   config.APIConfig
+		check func(t *testing.T, entries []harEntry)
+	}{
+		{"redirects", config.APIConfig{URL: srv.URL + "/start", Headers: map[string]string{"X-Api-Key": "k3y"}}, func(t *testing.T, entries []harEntry) {
+			if len(entries) != 2 {
+				t.Fatalf("got %d entries, want 2", len(entries))
+			}
+			first, last := entries[0], entries[1]
+			if first.Response.Status != 302 || first.Response.RedirectURL != srv.URL+"/end?token=****&page=1" {
+				t.Errorf("first response = %+v", first.Response)
+			}
+			if first.Request.Headers[0] != (harNameValue{Name: "X-Api-Key", Value: "****"}) {
+				t.Errorf("request headers = %+v", first.Request.Headers)
+			}
+			wantQuery := []harNameValue{{Name: "page", Value: "1"}, {Name: "token", Value: "****"}}
+			if len(last.Request.QueryString) != 2 || last.Request.QueryString[0] != wantQuery[0] || last.Request.QueryString[1] != wantQuery[1] {
+				t.Errorf("query = %+v, want %+v", last.Request.QueryString, wantQuery)
+			}
+			if last.Response.Status != 200 || last.Response.Content.Text != `{"ok":true}` || last.Response.Content.MimeType != "application/json" {
+				t.Errorf("last response = %+v", last.Response)
+			}
+		}},
+		{"post body", config.APIConfig{URL: srv.URL + "/end", Method: "POST", Body: `{"a":1}`}, func(t *testing.T, entries []harEntry) {
+			pd := entries[0].Request.PostData
+			if pd == nil || pd.Text != `{"a":1}` || entries[0].Request.BodySize != 7 {
+				t.Errorf("postData = %+v", pd)
+			}
+		}},
+		{"binary response", config.APIConfig{URL: srv.URL + "/binary"}, func(t *testing.T, entries []harEntry) {
+			c := entries[0].Response.Content
+			if c.Encoding != "base64" || c.Text != "//4A" || c.Size != 3 {
+				t.Errorf("content = %+v", c)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, _, _ := testEnv(t)
+			env.HARFile = filepath.Join(t.TempDir(), "out.har")
+			if err := runAPI(t, env, tt.api, Input{}); err != nil {
+				t.Fatalf("run error: %v", err)
+			}
+			data, err := os.ReadFile(env.HARFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var har harFile
+			if err := json.Unmarshal(data, &har); err != nil {
+				t.Fatalf("invalid HAR: %v", err)
+			}
+			if har.Log.Version != "1.2" || har.Log.Creator.Name != "sl-cli" {
+				t.Errorf("log = %+v", har.Log)
+			}
+			for _, e := range har.Log.Entries {
+				if e.Timings.Send < 0 || e.Timings.Wait < 0 || e.Timings.Receive < 0 {
+					t.Errorf("timings = %+v, send/wait/receive must not be negative", e.Timings)
+				}
+			}
+			tt.check(t, har.Log.Entries)
+		})
+	}
+}
+
+func TestHARRecordsErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close() // 连接被拒绝
+
+	env, _, _ := testEnv(t)
+	env.HARFile = filepath.Join(t.TempDir(), "out.har")
+	if err := runAPI(t, env, config.APIConfig{URL: url}, Input{}); err == nil {
+		t.Fatal("run succeeded, want a connection error")
+	}
+	data, err := os.ReadFile(env.HARFile)
+	if err != nil {
+		t.Fatalf("HAR not written on error: %v", err)
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatal(err)
+	}
+	if len(har.Log.Entries) != 1 || !strings.Contains(har.Log.Entries[0].Response.Error, "connection refused") {
+		t.Errorf("entries = %+v, want the connection error", har.Log.Entries)
+	}
+}
+
+// TestHARRedactsError 检查 HAR 中的错误信息与请求一样隐藏凭据
+func TestHARRedactsError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	x := &exchange{req: req, secrets: []string{"s3cr3t"}, err: errors.New(`proxy rejected "https://example.com/?key=s3cr3t"`)}
+	if got := x.harEntry().Response.Error; strings.Contains(got, "s3cr3t") || !strings.Contains(got, "proxy rejected") {
+		t.Errorf("_error = %q, want the message with the secret hidden", got)
+	}
+}
+
+func TestHARTruncatesLargeBody(t *testing.T) {
+	// 奇数偏移的两字节字符，截断位置落在字符中间
+	body := "a" + strings.Repeat("é", maxRecordedBody)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	env, stdout, _ := testEnv(t)
+	env.HARFile = filepath.Join(t.TempDir(), "out.har")
+	if err := runAPI(t, env, config.APIConfig{URL: srv.URL}, Input{}); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if got := strings.TrimSuffix(stdout.String(), "\n"); got != body {
+		t.Fatalf("stdout has %d bytes, want the full %d bytes", len(got), len(body))
+	}
+
+	data, err := os.ReadFile(env.HARFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var har struct {
+		Log struct {
+			Entries []struct {
+				Response struct {
+					Content struct {
+						Size    int    `json:"size"`
+						Text    string `json:"text"`
+						Comment string `json:"comment"`
+					} `json:"content"`
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatal(err)
+	}
+	if len(har.Log.Entries) != 1 {
+		t.Fatalf("entries = %d, want 1", len(har.Log.Entries))
+	}
+	content := har.Log.Entries[0].Response.Content
+	if len(content.Text) > maxRecordedBody || !utf8.ValidString(content.Text) || !strings.HasPrefix(body, content.Text) {
+		t.Errorf("recorded text has %d bytes, want a valid UTF-8 prefix of at most %d bytes", len(content.Text), maxRecordedBody)
+	}
+	if content.Size != len(body) {
+		t.Errorf("size = %d, want %d", content.Size, len(body))
+	}
+	want := fmt.Sprintf("truncated: only the first %d of %d bytes were recorded", len(content.Text), len(body))
+	if content.Comment != want {
+		t.Errorf("comment = %q, want %q", content.Comment, want)
+	}
+}
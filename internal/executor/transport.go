@@ -0,0 +1,422 @@
+package executor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"sl-cli/internal/config"
+)
+
+// ================= HTTP Transport =================
+
+// tlsVersions 是 tls.min_version 支持的取值
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// proxyDirect 表示不使用代理 (忽略 HTTP_PROXY 等环境变量)
+const proxyDirect = "direct"
+
+// hasTransportOptions 判断命令是否配置了 api.tls 或 api.transport
+func hasTransportOptions(api config.APIConfig) bool {
+	t := api.Transport
+	return api.TLS != (config.TLSConfig{}) || t.Proxy != "" || len(t.NoProxy) > 0 || t.HTTP2 != nil || len(t.Resolve) > 0 || t.UnixSocket != ""
+}
+
+// validateTransport 校验 api.tls 和 api.transport，返回问题描述 (供 config check 输出)
+func validateTransport(api config.APIConfig) []string {
+	var problems []string
+	if api.TLS.Key != "" && api.TLS.Cert == "" {
+		problems = append(problems, "tls.key requires tls.cert")
+	}
+	if _, ok := tlsVersions[api.TLS.MinVersion]; api.TLS.MinVersion != "" && !ok {
+		problems = append(problems, fmt.Sprintf("invalid tls.min_version '%s', must be one of 1.0, 1.1, 1.2, 1.3", api.TLS.MinVersion))
+	}
+
+	t := api.Transport
+	// 包含 ${ENV} 的代理地址在执行时展开后再校验
+	if t.Proxy != "" && t.Proxy != proxyDirect && !strings.Contains(t.Proxy, "${") {
+		if _, problem := parseProxy(t.Proxy); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	for from, to := range t.Resolve {
+		host := to
+		if h, _, err := net.SplitHostPort(to); err == nil {
+			host = h
+		}
+		if net.ParseIP(host) == nil {
+			problems = append(problems, fmt.Sprintf("invalid transport.resolve '%s: %s', must be an IP or IP:port", from, to))
+		}
+	}
+	if t.UnixSocket != "" && (t.Proxy != "" && t.Proxy != proxyDirect || len(t.Resolve) > 0) {
+		problems = append(problems, "transport.unix_socket cannot be combined with proxy or resolve")
+	}
+	return problems
+}
+
+// withTransport 返回按 api.tls 和 api.transport 配置了 HTTP 传输层的执行环境
+// OAuth2 获取 token 等同一命令中的其他请求也使用它
+func (e *Env) withTransport(api config.APIConfig) (*Env, error) {
+	if !hasTransportOptions(api) {
+		return e, nil
+	}
+	if problems := validateTransport(api); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid transport: %s", problems[0])
+	}
+	base, ok := e.Transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("api.tls and api.transport require an *http.Transport, got %T", e.Transport)
+	}
+	t := base.Clone()
+
+	if err := e.configureTLS(t, api.TLS); err != nil {
+		return nil, err
+	}
+	if err := e.configureProxy(t, api.Transport); err != nil {
+		return nil, err
+	}
+	if h2 := api.Transport.HTTP2; h2 != nil {
+		protocols := &http.Protocols{}
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(*h2)
+		t.Protocols = protocols
+		if !*h2 && t.TLSClientConfig != nil {
+			// 已经使用过的 Transport 的 TLS 配置中可能带有 ALPN h2，需要去掉
+			t.TLSClientConfig = t.TLSClientConfig.Clone()
+			t.TLSClientConfig.NextProtos = slices.DeleteFunc(t.TLSClientConfig.NextProtos, func(p string) bool { return p == "h2" })
+		}
+	}
+	e.configureDial(t, api.Transport)
+
+	out := *e
+	out.Transport = t
+	return &out, nil
+}
+
+func (e *Env) configureTLS(t *http.Transport, c config.TLSConfig) error {
+	if c == (config.TLSConfig{}) {
+		return nil
+	}
+	cfg := &tls.Config{}
+	if t.TLSClientConfig != nil {
+		cfg = t.TLSClientConfig.Clone()
+	}
+	if c.CA != "" {
+		path := e.expandPath(c.CA)
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read tls.ca: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no PEM certificates found in tls.ca %s", path)
+		}
+		cfg.RootCAs = pool
+	}
+	if c.Cert != "" {
+		key := c.Key
+		if key == "" {
+			key = c.Cert
+		}
+		cert, err := tls.LoadX509KeyPair(e.expandPath(c.Cert), e.expandPath(key))
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if c.MinVersion != "" {
+		cfg.MinVersion = tlsVersions[c.MinVersion]
+	}
+	if c.ServerName != "" {
+		cfg.ServerName = c.ServerName
+	}
+	if c.Insecure {
+		cfg.InsecureSkipVerify = true
+		fmt.Fprintln(e.Stderr, "⚠️  WARNING: TLS certificate verification is disabled (api.tls.insecure), the connection is NOT secure")
+	}
+	t.TLSClientConfig = cfg
+	return nil
+}
+
+func (e *Env) configureProxy(t *http.Transport, c config.TransportConfig) error {
+	if c.UnixSocket != "" || c.Proxy == proxyDirect {
+		t.Proxy = nil
+		return nil
+	}
+	if c.Proxy == "" && len(c.NoProxy) == 0 {
+		return nil
+	}
+
+	var proxy *url.URL
+	if c.Proxy != "" {
+		u, problem := parseProxy(e.expand(c.Proxy))
+		if problem != "" {
+			return fmt.Errorf("invalid transport: %s", problem)
+		}
+		proxy = u
+	}
+	fromEnv := t.Proxy
+	t.Proxy = func(req *http.Request) (*url.URL, error) {
+		if matchNoProxy(c.NoProxy, req.URL) {
+			return nil, nil
+		}
+		if proxy != nil {
+			return proxy, nil
+		}
+		if fromEnv == nil {
+			return nil, nil
+		}
+		return fromEnv(req)
+	}
+	return nil
+}
+
+// parseProxy 解析代理地址，地址无效时返回问题描述
+func parseProxy(proxy string) (*url.URL, string) {
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Sprintf("invalid transport.proxy '%s'", proxy)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+		return nil, fmt.Sprintf("invalid transport.proxy scheme '%s', must be http, https or socks5", u.Scheme)
+	}
+	return u, ""
+}
+
+// matchNoProxy 判断 URL 是否命中 no_proxy: "*"、域名 (同时匹配子域名，可以 . 或 *. 开头)、IP 或 CIDR，
+// 均可以带 :port 限定端口
+func matchNoProxy(patterns []string, u *url.URL) bool {
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	ip := net.ParseIP(host)
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "*" {
+			return true
+		}
+		if h, pp, err := net.SplitHostPort(p); err == nil {
+			if pp != port {
+				continue
+			}
+			p = h
+		}
+		if _, cidr, err := net.ParseCIDR(p); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if pip := net.ParseIP(p); pip != nil {
+			if ip != nil && pip.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		p = strings.TrimPrefix(strings.TrimPrefix(p, "*"), ".")
+		h := strings.ToLower(host)
+		if h == p || strings.HasSuffix(h, "."+p) {
+			return true
+		}
+	}
+	return false
+}
+
+// configureDial 处理 resolve 和 unix_socket: 只改变连接的地址，Host 和 TLS 校验仍使用 URL 中的主机名
+func (e *Env) configureDial(t *http.Transport, c config.TransportConfig) {
+	if len(c.Resolve) == 0 && c.UnixSocket == "" {
+		return
+	}
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	socket := ""
+	if c.UnixSocket != "" {
+		socket = e.expandPath(c.UnixSocket)
+	}
+	overrides := make(map[string]string, len(c.Resolve))
+	for from, to := range c.Resolve {
+		overrides[strings.ToLower(from)] = to
+	}
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if socket != "" {
+			return dial(ctx, "unix", socket)
+		}
+		return dial(ctx, network, resolveAddr(overrides, addr))
+	}
+}
+
+// resolveAddr 按 resolve 替换连接地址，host:port 的配置优先于只写 host 的配置
+func resolveAddr(overrides map[string]string, addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	to, ok := overrides[strings.ToLower(addr)]
+	if !ok {
+		if to, ok = overrides[strings.ToLower(host)]; !ok {
+			return addr
+		}
+	}
+	if _, _, err := net.SplitHostPort(to); err == nil {
+		return to
+	}
+	return net.JoinHostPort(to, port)
+}
+
+// expandPath 展开路径中的 ${ENV} 和开头的 ~/
+func (e *Env) expandPath(path string) string {
+	path = e.expand(path)
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home := e.Getenv("HOME")
+		if home == "" {
+			home, _ = os.UserHomeDir()
+		}
+		return filepath.Join(home, rest)
+	}
+	return path
+}
+
+// ---------- Rendering ----------
+
+// transportArgs 返回与 api.tls、api.transport 等价的命令行参数 (用于 --as)
+// 目标工具无法表达的选项返回错误，避免输出行为不一致的命令
+func transportArgs(format string, api config.APIConfig, u *url.URL) ([]string, error) {
+	c, t := api.TLS, api.Transport
+	unsupported := func(option string) error {
+		return fmt.Errorf("--as %s does not support %s", format, option)
+	}
+	var args []string
+	switch format {
+	case RenderCurl:
+		args = appendFlag(args, "--cacert", c.CA)
+		args = appendFlag(args, "--cert", c.Cert)
+		args = appendFlag(args, "--key", c.Key)
+		if c.MinVersion != "" {
+			args = append(args, "--tlsv"+c.MinVersion)
+		}
+		if c.ServerName != "" {
+			return nil, unsupported("tls.server_name")
+		}
+		if c.Insecure {
+			args = append(args, "--insecure")
+		}
+		if t.Proxy == proxyDirect {
+			args = append(args, "--noproxy", "*")
+		} else {
+			args = appendFlag(args, "--proxy", maskProxy(t.Proxy))
+			if len(t.NoProxy) > 0 {
+				args = append(args, "--noproxy", strings.Join(t.NoProxy, ","))
+			}
+		}
+		if t.HTTP2 != nil && !*t.HTTP2 {
+			args = append(args, "--http1.1")
+		}
+		froms := make([]string, 0, len(t.Resolve))
+		for from := range t.Resolve {
+			froms = append(froms, from)
+		}
+		sort.Strings(froms)
+		for _, from := range froms {
+			// curl --resolve 需要 host:port:addr
+			host, port, err := net.SplitHostPort(from)
+			if err != nil {
+				host, port = from, u.Port()
+				if port == "" {
+					port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+				}
+			}
+			addr := t.Resolve[from]
+			if h, p, err := net.SplitHostPort(addr); err == nil {
+				args = append(args, "--connect-to", net.JoinHostPort(host, port)+":"+net.JoinHostPort(h, p))
+				continue
+			}
+			if strings.Contains(addr, ":") {
+				addr = "[" + addr + "]"
+			}
+			args = append(args, "--resolve", host+":"+port+":"+addr)
+		}
+		args = appendFlag(args, "--unix-socket", t.UnixSocket)
+	case RenderHTTPie, RenderWget:
+		for _, o := range []struct {
+			name string
+			set  bool
+		}{
+			{"tls.min_version", c.MinVersion != ""},
+			{"tls.server_name", c.ServerName != ""},
+			{"transport.no_proxy", len(t.NoProxy) > 0},
+			{"transport.resolve", len(t.Resolve) > 0},
+			{"transport.unix_socket", t.UnixSocket != ""},
+		} {
+			if o.set {
+				return nil, unsupported(o.name)
+			}
+		}
+		if format == RenderHTTPie {
+			if c.Insecure {
+				args = append(args, "--verify=no")
+			} else if c.CA != "" {
+				args = append(args, "--verify="+c.CA)
+			}
+			args = appendFlag(args, "--cert", c.Cert)
+			args = appendFlag(args, "--cert-key", c.Key)
+			if t.Proxy != "" && t.Proxy != proxyDirect {
+				args = append(args, "--proxy="+u.Scheme+":"+maskProxy(t.Proxy))
+			}
+		} else {
+			if c.CA != "" {
+				args = append(args, "--ca-certificate="+c.CA)
+			}
+			if c.Cert != "" {
+				args = append(args, "--certificate="+c.Cert)
+			}
+			if c.Key != "" {
+				args = append(args, "--private-key="+c.Key)
+			}
+			if c.Insecure {
+				args = append(args, "--no-check-certificate")
+			}
+			if t.Proxy == proxyDirect {
+				args = append(args, "--no-proxy")
+			} else if t.Proxy != "" {
+				args = append(args, "-e", "use_proxy=on", "-e", u.Scheme+"_proxy="+maskProxy(t.Proxy))
+			}
+		}
+	}
+	return args, nil
+}
+
+func appendFlag(args []string, flag, value string) []string {
+	if value == "" {
+		return args
+	}
+	return append(args, flag, value)
+}
+
+// maskProxy 隐藏代理地址中的密码
+func maskProxy(proxy string) string {
+	if u, err := url.Parse(proxy); err == nil && u.Host != "" {
+		return maskURL(u)
+	}
+	return proxy
+}
@@ -0,0 +1,341 @@
+package executor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sl-cli/internal/config"
+)
+
+// okHandler 返回请求的 Host 和客户端证书的 CN
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	cn := ""
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		cn = r.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	w.Write([]byte(r.Host + " " + cn))
+})
+
+// newTLSServer 启动不输出握手错误日志的 TLS 测试服务器 (部分用例预期握手失败)
+func newTLSServer(cfg *tls.Config) *httptest.Server {
+	srv := httptest.NewUnstartedServer(okHandler)
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.TLS = cfg
+	srv.StartTLS()
+	return srv
+}
+
+// writePEM 将 PEM 块写入临时文件并返回路径
+func writePEM(t *testing.T, name string, blocks ...*pem.Block) string {
+	t.Helper()
+	var data []byte
+	for _, b := range blocks {
+		data = append(data, pem.EncodeToMemory(b)...)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// serverCA 将测试服务器的证书写入文件，作为 tls.ca 使用
+func serverCA(t *testing.T, srv *httptest.Server) string {
+	return writePEM(t, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+}
+
+// clientCert 生成自签名的客户端证书，返回证书和私钥 PEM 块
+func clientCert(t *testing.T, cn string) (*x509.Certificate, *pem.Block, *pem.Block) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, &pem.Block{Type: "CERTIFICATE", Bytes: der}, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}
+}
+
+func TestTransportTLS(t *testing.T) {
+	srv := newTLSServer(nil)
+	defer srv.Close()
+	ca := serverCA(t, srv)
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	tests := []struct {
+		name    string
+		api     config.APIConfig
+		want    string
+		warning bool
+		wantErr string
+	}{
+		{name: "untrusted", api: config.APIConfig{URL: srv.URL}, wantErr: "certificate"},
+		{name: "custom ca", api: config.APIConfig{URL: srv.URL, TLS: config.TLSConfig{CA: ca}}, want: "127.0.0.1:" + port},
+		{name: "ca from env", api: config.APIConfig{URL: srv.URL, TLS: config.TLSConfig{CA: "${SL_TEST_CA}"}}, want: "127.0.0.1:" + port},
+		{name: "missing ca", api: config.APIConfig{URL: srv.URL, TLS: config.TLSConfig{CA: ca + ".missing"}}, wantErr: "failed to read tls.ca"},
+		{name: "insecure", api: config.APIConfig{URL: srv.URL, TLS: config.TLSConfig{Insecure: true}}, want: "127.0.0.1:" + port, warning: true},
+		{
+			// httptest 的证书包含 example.com，resolve 只改变连接地址，Host 和证书校验仍使用 URL 中的主机名
+			name: "resolve",
+			api: config.APIConfig{
+				URL:       "https://example.com:" + port,
+				TLS:       config.TLSConfig{CA: ca},
+				Transport: config.TransportConfig{Proxy: proxyDirect, Resolve: map[string]string{"example.com": "127.0.0.1"}},
+			},
+			want: "example.com:" + port,
+		},
+		{
+			name: "server name",
+			api: config.APIConfig{
+				URL:       srv.URL,
+				TLS:       config.TLSConfig{CA: ca, ServerName: "other.test"},
+				Transport: config.TransportConfig{Proxy: proxyDirect},
+			},
+			wantErr: "other.test",
+		},
+		{name: "min version", api: config.APIConfig{URL: srv.URL, TLS: config.TLSConfig{CA: ca, MinVersion: "1.3"}}, want: "127.0.0.1:" + port},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, stdout, stderr := testEnv(t, "SL_TEST_CA="+ca)
+			err := runAPI(t, env, tt.api, Input{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run error: %v", err)
+			}
+			if got := strings.TrimSpace(stdout.String()); got != tt.want {
+				t.Errorf("stdout = %q, want %q", got, tt.want)
+			}
+			if got := strings.Contains(stderr.String(), "WARNING: TLS certificate verification is disabled"); got != tt.warning {
+				t.Errorf("insecure warning = %v, want %v (stderr %q)", got, tt.warning, stderr)
+			}
+		})
+	}
+}
+
+func TestTransportClientCert(t *testing.T) {
+	cert, certPEM, keyPEM := clientCert(t, "sl-cli-test")
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	srv := newTLSServer(&tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool})
+	defer srv.Close()
+	ca := serverCA(t, srv)
+
+	tests := []struct {
+		name    string
+		tls     config.TLSConfig
+		wantErr string
+	}{
+		{"cert and key", config.TLSConfig{CA: ca, Cert: writePEM(t, "cert.pem", certPEM), Key: writePEM(t, "key.pem", keyPEM)}, ""},
+		{"key in cert file", config.TLSConfig{CA: ca, Cert: writePEM(t, "both.pem", certPEM, keyPEM)}, ""},
+		{"no cert", config.TLSConfig{CA: ca}, "certificate"},
+		{"missing key", config.TLSConfig{CA: ca, Cert: writePEM(t, "only.pem", certPEM)}, "failed to load client certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, stdout, _ := testEnv(t)
+			err := runAPI(t, env, config.APIConfig{URL: srv.URL, TLS: tt.tls}, Input{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run error: %v", err)
+			}
+			if !strings.HasSuffix(strings.TrimSpace(stdout.String()), " sl-cli-test") {
+				t.Errorf("stdout = %q, want the client certificate CN", stdout)
+			}
+		})
+	}
+}
+
+func TestTransportUnixSocket(t *testing.T) {
+	// Unix socket 路径长度有限制 (macOS 为 104 字节)，不使用 t.TempDir()
+	dir, err := os.MkdirTemp("", "sl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "api.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(okHandler)
+	srv.Listener = ln
+	srv.Start()
+	defer srv.Close()
+
+	env, stdout, _ := testEnv(t, "SL_TEST_DIR="+dir)
+	api := config.APIConfig{URL: "http://docker/v1/info", Transport: config.TransportConfig{UnixSocket: "${SL_TEST_DIR}/api.sock"}}
+	if err := runAPI(t, env, api, Input{}); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "docker" {
+		t.Errorf("stdout = %q, want the Host from the URL", got)
+	}
+}
+
+func TestTransportProxy(t *testing.T) {
+	// 代理收到的是绝对 URL，返回它看到的目标地址
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("via proxy " + r.URL.String()))
+	}))
+	defer proxy.Close()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("direct"))
+	}))
+	defer origin.Close()
+
+	tests := []struct {
+		name string
+		api  config.APIConfig
+		env  []string
+		want string
+	}{
+		{
+			name: "proxy",
+			api:  config.APIConfig{URL: "http://upstream.test/items", Transport: config.TransportConfig{Proxy: proxy.URL}},
+			want: "via proxy http://upstream.test/items",
+		},
+		{
+			name: "proxy from env",
+			api:  config.APIConfig{URL: "http://upstream.test/items", Transport: config.TransportConfig{Proxy: "${SL_TEST_PROXY}"}},
+			env:  []string{"SL_TEST_PROXY=" + proxy.URL},
+			want: "via proxy http://upstream.test/items",
+		},
+		{
+			name: "invalid proxy from env",
+			api:  config.APIConfig{URL: "http://upstream.test/items", Transport: config.TransportConfig{Proxy: "${SL_TEST_PROXY}"}},
+			env:  []string{"SL_TEST_PROXY=proxy.test"},
+			want: "invalid transport.proxy 'proxy.test'",
+		},
+		{
+			name: "no proxy",
+			api:  config.APIConfig{URL: origin.URL, Transport: config.TransportConfig{Proxy: proxy.URL, NoProxy: config.StringList{"127.0.0.0/8"}}},
+			want: "direct",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, stdout, _ := testEnv(t, tt.env...)
+			if err := runAPI(t, env, tt.api, Input{}); err != nil {
+				if strings.Contains(err.Error(), tt.want) {
+					return
+				}
+				t.Fatalf("run error: %v", err)
+			}
+			if got := strings.TrimSpace(stdout.String()); got != tt.want {
+				t.Errorf("stdout = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchNoProxy(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		url      string
+		want     bool
+	}{
+		{[]string{"*"}, "https://api.example.com", true},
+		{[]string{"example.com"}, "https://api.example.com", true},
+		{[]string{".example.com"}, "https://example.com", true},
+		{[]string{"*.example.com"}, "https://API.Example.com", true},
+		{[]string{"example.com"}, "https://notexample.com", false},
+		{[]string{"example.com:443"}, "https://example.com", true},
+		{[]string{"example.com:8443"}, "https://example.com", false},
+		{[]string{"10.0.0.0/8"}, "http://10.1.2.3:8080", true},
+		{[]string{"10.0.0.0/8"}, "http://example.com", false},
+		{[]string{"::1"}, "http://[::1]:8080", true},
+		{nil, "http://example.com", false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := matchNoProxy(tt.patterns, u); got != tt.want {
+			t.Errorf("matchNoProxy(%q, %s) = %v, want %v", tt.patterns, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestResolveAddr(t *testing.T) {
+	overrides := map[string]string{"api.test": "10.0.0.1", "api.test:8443": "10.0.0.2:9443"}
+	tests := map[string]string{
+		"api.test:443":   "10.0.0.1:443",
+		"API.test:80":    "10.0.0.1:80",
+		"api.test:8443":  "10.0.0.2:9443",
+		"other.test:443": "other.test:443",
+	}
+	for addr, want := range tests {
+		if got := resolveAddr(overrides, addr); got != want {
+			t.Errorf("resolveAddr(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
+
+func TestValidateTransport(t *testing.T) {
+	tests := []struct {
+		name string
+		api  config.APIConfig
+		want string
+	}{
+		{"valid", config.APIConfig{TLS: config.TLSConfig{Cert: "c.pem", MinVersion: "1.3"}, Transport: config.TransportConfig{Proxy: "socks5://127.0.0.1:1080"}}, ""},
+		{"key without cert", config.APIConfig{TLS: config.TLSConfig{Key: "k.pem"}}, "tls.key requires tls.cert"},
+		{"min version", config.APIConfig{TLS: config.TLSConfig{MinVersion: "1.4"}}, "invalid tls.min_version '1.4'"},
+		{"proxy scheme", config.APIConfig{Transport: config.TransportConfig{Proxy: "ftp://proxy:21"}}, "invalid transport.proxy scheme 'ftp'"},
+		{"proxy without host", config.APIConfig{Transport: config.TransportConfig{Proxy: "proxy"}}, "invalid transport.proxy 'proxy'"},
+		{"resolve to host", config.APIConfig{Transport: config.TransportConfig{Resolve: map[string]string{"a.test": "b.test"}}}, "must be an IP or IP:port"},
+		{"socket with proxy", config.APIConfig{Transport: config.TransportConfig{UnixSocket: "/s", Proxy: "http://p:1"}}, "cannot be combined"},
+	}
+	for _, tt := range tests {
+		problems := strings.Join(validateTransport(tt.api), "; ")
+		if (tt.want == "") != (problems == "") || !strings.Contains(problems, tt.want) {
+			t.Errorf("%s: validateTransport = %q, want %q", tt.name, problems, tt.want)
+		}
+	}
+}
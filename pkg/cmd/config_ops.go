@@ -6,8 +6,12 @@ import (
 
 	"path/filepath"
 	"sl-cli/internal/config"
+	"sl-cli/internal/executor"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -43,6 +47,7 @@ var checkCmd = &cobra.Command{
 
 		// 3. 递归逻辑校验
 		errCount := 0
+		errCount += validateAuthProfiles(cfg.Auth)
 		for i, c := range cfg.Commands {
 			// 顶层命令路径直接用名字，如果没有名字则用索引
 			cmdName := c.Name
@@ -61,6 +66,29 @@ var checkCmd = &cobra.Command{
 	},
 }
 
+// validateAuthProfiles 校验顶层 auth 中定义的认证配置
+func validateAuthProfiles(profiles map[string]config.AuthConfig) int {
+	errs := 0
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		a := profiles[name]
+		if a.Use != "" {
+			fmt.Printf("❌ Error in [auth.%s]: auth profiles cannot reference other profiles ('use: %s').\n", name, a.Use)
+			errs++
+			continue
+		}
+		for _, problem := range executor.ValidateAuth(a) {
+			fmt.Printf("❌ Error in [auth.%s]: %s.\n", name, problem)
+			errs++
+		}
+	}
+	return errs
+}
+
 // validateCommand 递归校验命令配置
 // c: 当前命令配置
 // path: 命令路径面包屑，例如 "dev -> info"
@@ -76,47 +104,44 @@ func validateCommand(c config.CommandConfig, path string) int {
 
 	// 2. 结构校验：必须是 "有效的功能命令" 或者 "包含子命令的组"
 	// 如果没有 Type 且没有 SubCommands，那就是个空壳
+	types := strings.Join(executor.Types(), "/")
 	if c.Type == "" && len(c.SubCommands) == 0 {
-		fmt.Printf("❌ Error in [%s]: Must specify 'type' (http/shell/system) OR have 'subcommands'.\n", path)
+		fmt.Printf("❌ Error in [%s]: Must specify 'type' (%s) OR have 'subcommands'.\n", path, types)
 		errs++
 	}
 
 	// 3. 类型校验 (如果指定了 Type)
 	if c.Type != "" {
-		validTypes := map[string]bool{"http": true, "shell": true, "system": true}
-		if !validTypes[c.Type] {
-			fmt.Printf("❌ Error in [%s]: Invalid type '%s'. Must be http, shell, or system.\n", path, c.Type)
+		runner, ok := executor.Lookup(c.Type)
+		if !ok {
+			fmt.Printf("❌ Error in [%s]: Invalid type '%s'. Must be one of: %s.\n", path, c.Type, types)
 			errs++
-		}
-
-		// 4. 字段校验 (根据 Type)
-		switch c.Type {
-		case "http":
-			if c.API.URL == "" {
-				fmt.Printf("❌ Error in [%s]: Type is http but 'api.url' is missing.\n", path)
-				errs++
-			}
-			// 校验 Pipes
-			for idx, p := range c.API.Pipes {
-				if p.Command == "" {
-					fmt.Printf("❌ Error in [%s]: Pipe #%d missing 'command'.\n", path, idx+1)
-					errs++
-				}
-			}
-		case "shell":
-			if c.Script == "" {
-				fmt.Printf("❌ Error in [%s]: Type is shell but 'script' is missing.\n", path)
-				errs++
-			}
-		case "system":
-			if c.Command == "" {
-				fmt.Printf("❌ Error in [%s]: Type is system but 'command' is missing.\n", path)
+		} else {
+			// 4. 字段校验 (由各类型的 Runner 负责)
+			for _, problem := range runner.Validate(c) {
+				fmt.Printf("❌ Error in [%s]: %s.\n", path, problem)
 				errs++
 			}
 		}
 	}
 
-	// 5. 递归校验子命令
+	if _, err := executor.ParseTimeout(c.Timeout); err != nil {
+		fmt.Printf("❌ Error in [%s]: %s.\n", path, err)
+		errs++
+	}
+	for _, problem := range executor.ValidateOutput(c.Output) {
+		fmt.Printf("❌ Error in [%s]: %s.\n", path, problem)
+		errs++
+	}
+	if c.Exec && c.Type != "system" {
+		fmt.Printf("❌ Error in [%s]: 'exec' is only supported for system commands.\n", path)
+		errs++
+	}
+
+	// 5. 参数声明校验
+	errs += validateParams(c, path)
+
+	// 6. 递归校验子命令
 	for _, sub := range c.SubCommands {
 		subPath := path + " -> " + sub.Name
 		if sub.Name == "" {
@@ -128,6 +153,70 @@ func validateCommand(c config.CommandConfig, path string) int {
 	return errs
 }
 
+// validateParams 校验 params/flags 声明
+func validateParams(c config.CommandConfig, path string) int {
+	errs := 0
+	seen := make(map[string]bool)
+	optionalSeen := false
+	for idx, p := range c.Params {
+		for _, problem := range p.Validate() {
+			fmt.Printf("❌ Error in [%s]: Param #%d %s.\n", path, idx+1, problem)
+			errs++
+		}
+		if p.Shorthand != "" {
+			fmt.Printf("❌ Error in [%s]: Param '%s' is positional and cannot have 'shorthand'.\n", path, p.Name)
+			errs++
+		}
+		// 必填参数不能出现在可选参数之后，否则无法确定位置
+		if p.Required && optionalSeen {
+			fmt.Printf("❌ Error in [%s]: Required param '%s' must not follow optional params.\n", path, p.Name)
+			errs++
+		}
+		if !p.Required {
+			optionalSeen = true
+		}
+		if p.Name != "" && seen[p.Name] {
+			fmt.Printf("❌ Error in [%s]: Duplicate param name '%s'.\n", path, p.Name)
+			errs++
+		}
+		seen[p.Name] = true
+	}
+
+	// help 和全局标志占用的名称不能再声明
+	seen, shorthands := reservedFlags()
+	for idx, f := range c.Flags {
+		for _, problem := range f.Validate() {
+			fmt.Printf("❌ Error in [%s]: Flag #%d %s.\n", path, idx+1, problem)
+			errs++
+		}
+		if f.Name != "" && seen[f.Name] {
+			fmt.Printf("❌ Error in [%s]: Duplicate or reserved flag name '%s'.\n", path, f.Name)
+			errs++
+		}
+		seen[f.Name] = true
+		if f.Shorthand != "" {
+			if shorthands[f.Shorthand] {
+				fmt.Printf("❌ Error in [%s]: Duplicate or reserved flag shorthand '%s'.\n", path, f.Shorthand)
+				errs++
+			}
+			shorthands[f.Shorthand] = true
+		}
+	}
+	return errs
+}
+
+// reservedFlags 返回根命令已占用的 flag 名称和简写: 全局标志 (--config 和 sl.AddGlobalFlags 添加的) 以及 help
+func reservedFlags() (names, shorthands map[string]bool) {
+	names, shorthands = map[string]bool{"help": true}, map[string]bool{"h": true}
+	rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		names[f.Name] = true
+		if f.Shorthand != "" {
+			shorthands[f.Shorthand] = true
+		}
+	})
+	return names, shorthands
+}
+
 // initCmd 用于生成示例配置文件
 var initCmd = &cobra.Command{
 	Use:   "init",
@@ -203,13 +292,18 @@ commands:
   - name: "weather"
     usage: "Get weather for a city (usage: sl-cli weather London)"
     type: "http"
+    # Declared positional args are validated and available as {{.args.NAME}}
+    params:
+      - name: "city"
+        required: true
+        help: "City name"
     api:
-      url: "https://goweather.herokuapp.com/weather/{{index .args 0}}"
+      url: "https://goweather.herokuapp.com/weather/{{.args.city}}"
       method: "GET"
-      # Pipeline: API Response -> jq -> grep -> Stdout
+      # Pipeline: API Response -> jq (内置) -> grep -> Stdout
       pipes:
-        - command: "jq"
-          args: ["."]
+        - builtin: "jq"
+          expr: "."
         - command: "grep"
           args: ["temperature"]
 
@@ -219,9 +313,18 @@ commands:
   - name: "greet"
     usage: "Run a shell script with arguments"
     type: "shell"
+    # Declared flags become real --flags, available as {{.flags.NAME}}
+    flags:
+      - name: "times"
+        shorthand: "n"
+        type: "int"
+        default: "1"
+        help: "How many times to greet"
     script: |
       echo "--------------------------------"
-      echo "Hello, {{index .args 0}}!"
+      for i in $(seq {{.flags.times}}); do
+        echo "Hello, {{index .args 0}}!"
+      done
       echo "Current Dir: $(pwd)"
       echo "--------------------------------"
 
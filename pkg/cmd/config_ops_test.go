@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"testing"
+
+	"sl-cli/internal/config"
+)
+
+func TestValidateParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.CommandConfig
+		wantErrs int
+	}{
+		{"valid", config.CommandConfig{
+			Params: []config.ParamConfig{{Name: "city", Required: true}, {Name: "days", Type: config.ParamInt}},
+			Flags:  []config.ParamConfig{{Name: "units", Shorthand: "u"}},
+		}, 0},
+		{"required after optional", config.CommandConfig{Params: []config.ParamConfig{{Name: "a"}, {Name: "b", Required: true}}}, 1},
+		{"positional with shorthand", config.CommandConfig{Params: []config.ParamConfig{{Name: "a", Shorthand: "a"}}}, 1},
+		{"duplicate param", config.CommandConfig{Params: []config.ParamConfig{{Name: "a"}, {Name: "a"}}}, 1},
+		{"invalid param type", config.CommandConfig{Params: []config.ParamConfig{{Name: "a", Type: "float"}}}, 1},
+		{"duplicate flag", config.CommandConfig{Flags: []config.ParamConfig{{Name: "x"}, {Name: "x"}}}, 1},
+		{"reserved flag help", config.CommandConfig{Flags: []config.ParamConfig{{Name: "help"}}}, 1},
+		{"reserved flag config", config.CommandConfig{Flags: []config.ParamConfig{{Name: "config"}}}, 1},
+		{"reserved flag timeout", config.CommandConfig{Flags: []config.ParamConfig{{Name: "timeout"}}}, 1},
+		{"reserved flag output", config.CommandConfig{Flags: []config.ParamConfig{{Name: "output"}}}, 1},
+		{"reserved flag dry-run", config.CommandConfig{Flags: []config.ParamConfig{{Name: "dry-run"}}}, 1},
+		{"reserved flag trace", config.CommandConfig{Flags: []config.ParamConfig{{Name: "trace"}}}, 1},
+		{"reserved shorthand v", config.CommandConfig{Flags: []config.ParamConfig{{Name: "verbose", Shorthand: "v"}}}, 1},
+		{"reserved shorthand o", config.CommandConfig{Flags: []config.ParamConfig{{Name: "org", Shorthand: "o"}}}, 1},
+		// 名称和简写都来自根命令实际注册的全局标志
+		{"reserved flag har", config.CommandConfig{Flags: []config.ParamConfig{{Name: "har"}}}, 1},
+		{"reserved flag max-pages", config.CommandConfig{Flags: []config.ParamConfig{{Name: "max-pages"}}}, 1},
+		{"reserved shorthand O", config.CommandConfig{Flags: []config.ParamConfig{{Name: "out", Shorthand: "O"}}}, 1},
+		{"reserved shorthand h", config.CommandConfig{Flags: []config.ParamConfig{{Name: "host", Shorthand: "h"}}}, 1},
+		{"duplicate shorthand", config.CommandConfig{Flags: []config.ParamConfig{{Name: "a", Shorthand: "x"}, {Name: "b", Shorthand: "x"}}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateParams(tt.cfg, "test"); got != tt.wantErrs {
+				t.Errorf("validateParams() = %d errors, want %d", got, tt.wantErrs)
+			}
+		})
+	}
+}
@@ -1,13 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"sl-cli/internal/config"
-	"sl-cli/internal/executor"
+	"sl-cli/pkg/sl"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -31,8 +31,12 @@ func Execute() {
 
 	// 2. 执行命令
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		// 配置命令的执行错误已经输出过，只需透传退出码
+		var exitErr *sl.ExitError
+		if !errors.As(err, &exitErr) || !exitErr.Quiet {
+			fmt.Println(err)
+		}
+		os.Exit(sl.ExitCode(err))
 	}
 }
 
@@ -59,6 +63,12 @@ func preParseConfigFlag() {
 func init() {
 	// 定义全局标志
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "配置文件 (默认为 $HOME/.config/sl-cli/sl-cli.yaml)")
+	sl.AddGlobalFlags(rootCmd) // --timeout、--output
+
+	// 允许在子命令之前解析全局标志，例如 sl-cli --timeout 5s <cmd> ...
+	// 禁用了标志解析的 shell/system 命令只能通过这种方式使用全局标志
+	rootCmd.TraverseChildren = true
+	rootCmd.RunE = sl.RootRunE
 }
 
 func initConfig() {
@@ -111,55 +121,10 @@ func loadDynamicCommands() {
 		return
 	}
 
-	cfg, err := config.LoadConfig(configFile)
+	engine, err := sl.Load(configFile)
 	if err != nil {
 		fmt.Printf("Error loading config: %s\n", err)
 		return
 	}
-
-	for _, cmdCfg := range cfg.Commands {
-		cmd := buildCommand(cmdCfg, cfg.Vars)
-		// 重复添加的命令丢弃，如果有子命令则将子命令追加到已存在命令的字命令中
-		deplicated := false
-		for _, c := range rootCmd.Commands() {
-			if c.Name() != cmd.Name() {
-				continue
-			}
-			deplicated = true
-			if cmd.HasSubCommands() {
-				c.AddCommand(cmd.Commands()...)
-			}
-		}
-		if !deplicated {
-			rootCmd.AddCommand(cmd)
-		}
-	}
-}
-
-// buildCommand 递归构建命令
-func buildCommand(cfg config.CommandConfig, vars map[string]string) *cobra.Command {
-	cmd := &cobra.Command{
-		Use:   cfg.Name,
-		Short: cfg.Usage,
-		// DisableFlagParsing: true, // 可选：如果希望由 shell/system 接管所有参数解析，可以开启此项
-		Run: func(c *cobra.Command, args []string) {
-			if err := executor.Run(cfg, args, vars); err != nil {
-				fmt.Printf("Execution failed: %s\n", err)
-				os.Exit(1)
-			}
-		},
-	}
-
-	// 对于 system 和 shell 类型，禁用 Cobra 的标志解析
-	// 这样 -la 这种参数就会被原样放入 args 切片中，而不是被 Cobra 拦截报错
-	if cfg.Type == "system" || cfg.Type == "shell" {
-		cmd.DisableFlagParsing = true
-	}
-
-	for _, subCfg := range cfg.SubCommands {
-		subCmd := buildCommand(subCfg, vars)
-		cmd.AddCommand(subCmd)
-	}
-
-	return cmd
+	engine.Build(rootCmd)
 }
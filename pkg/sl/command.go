@@ -0,0 +1,150 @@
+package sl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sl-cli/internal/executor"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// buildCommand 递归构建命令
+func (e *Engine) buildCommand(cfg CommandConfig) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   useLine(cfg),
+		Short: cfg.Usage,
+		// DisableFlagParsing: true, // 可选：如果希望由 shell/system 接管所有参数解析，可以开启此项
+	}
+	if help := paramsHelp(cfg.Params); help != "" {
+		cmd.Long = strings.TrimSpace(cfg.Usage + "\n\n" + help)
+	}
+
+	flagValues := addParamFlags(cmd, cfg.Flags)
+	var flags map[string]interface{}
+
+	// 在 PreRunE 中完成 flag 解析，错误会作为用法错误连同 usage 一起输出
+	cmd.PreRunE = func(c *cobra.Command, args []string) error {
+		var err error
+		flags, err = resolveFlags(c, cfg.Flags, flagValues, e.getenv)
+		return err
+	}
+	if cfg.Type != "" {
+		cmd.RunE = func(c *cobra.Command, args []string) error {
+			return e.run(c, cfg, args, flags)
+		}
+	}
+
+	if len(cfg.Params) > 0 {
+		cmd.Args = paramArgs(cfg, e.getenv)
+	}
+	if cfg.Type != "" {
+		cmd.ValidArgsFunction = argCompletion(cfg)
+	}
+
+	// 对于透传参数的类型 (system、shell)，禁用 Cobra 的标志解析
+	// 这样 -la 这种参数就会被原样放入 args 切片中，而不是被 Cobra 拦截报错
+	// 如果声明了 flags，则需要由 Cobra 解析
+	if executor.PassthroughArgs(cfg.Type) && len(cfg.Flags) == 0 {
+		cmd.DisableFlagParsing = true
+	}
+
+	for _, subCfg := range cfg.SubCommands {
+		subCmd := e.buildCommand(subCfg)
+		cmd.AddCommand(subCmd)
+	}
+
+	return cmd
+}
+
+// run 执行配置命令
+// 执行失败时在这里输出错误信息，返回的 ExitError 标记为 Quiet，调用方只需处理退出码
+func (e *Engine) run(c *cobra.Command, cfg CommandConfig, args []string, flags map[string]interface{}) error {
+	params, _ := resolveParams(cfg.Params, args, e.getenv) // 已在 Args 校验阶段检查过
+	in := Input{Args: args, Params: params, Flags: flags, Vars: e.config.Vars}
+
+	// 全局 --timeout、--output、--download、--all (如果根命令定义了) 覆盖配置中的 timeout、output、api.download 和 api.paginate
+	// 从根命令读取，避免被命令自己声明的同名 flag 遮蔽
+	global := c.Root().PersistentFlags()
+	if f := global.Lookup("timeout"); f != nil && f.Changed {
+		cfg.Timeout = f.Value.String()
+	}
+	if f := global.Lookup("output"); f != nil && f.Changed {
+		output, err := executor.ApplyOutputFlag(cfg.Output, f.Value.String())
+		if err != nil {
+			return err
+		}
+		cfg.Output = output
+	}
+	if f := global.Lookup("download"); f != nil && f.Changed {
+		download, err := executor.ApplyDownloadFlag(cfg, f.Value.String())
+		if err != nil {
+			return err
+		}
+		cfg = download
+	}
+	all, maxPages := global.Lookup("all"), global.Lookup("max-pages")
+	if all != nil && maxPages != nil && (all.Changed || maxPages.Changed) {
+		allPages := cfg.API.Paginate.All
+		if all.Changed {
+			allPages = all.Value.String() == "true"
+		}
+		n, _ := strconv.Atoi(maxPages.Value.String())
+		paged, err := executor.ApplyPaginateFlags(cfg, allPages, n)
+		if err != nil {
+			return err
+		}
+		cfg = paged
+	}
+
+	env := &Env{}
+	if e.Env != nil {
+		*env = *e.Env
+	}
+	env.Stdin, env.Stdout, env.Stderr = c.InOrStdin(), c.OutOrStdout(), c.ErrOrStderr()
+	if f := global.Lookup("trace"); f != nil && f.Value.String() == "true" {
+		env.Trace = c.ErrOrStderr()
+	}
+	if f := global.Lookup("har"); f != nil && f.Changed {
+		env.HARFile = f.Value.String()
+	}
+
+	// --dry-run / --as 只渲染，不执行
+	if rendered, ok, err := render(global, env, cfg, in); ok {
+		if err != nil {
+			c.SilenceUsage = true
+			return err
+		}
+		_, err = fmt.Fprint(c.OutOrStdout(), rendered)
+		return err
+	}
+
+	err := executor.Run(c.Context(), env, cfg, in)
+	if err == nil {
+		return nil
+	}
+	// 执行阶段的错误不是用法错误，不需要 cobra 再输出错误和 usage
+	c.SilenceErrors, c.SilenceUsage = true, true
+	// 子进程自己已经输出了错误信息，这里只透传退出码
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || !exitErr.Quiet {
+		fmt.Fprintf(c.ErrOrStderr(), "Execution failed: %s\n", err)
+	}
+	return &ExitError{Code: ExitCode(err), Err: err, Quiet: true}
+}
+
+// render 处理全局 --dry-run 和 --as，ok 为 false 表示两者均未指定，需要正常执行
+func render(global *pflag.FlagSet, env *Env, cfg CommandConfig, in Input) (out string, ok bool, err error) {
+	if f := global.Lookup("as"); f != nil && f.Changed {
+		out, err = executor.RenderAs(env, cfg, in, f.Value.String())
+		return out, true, err
+	}
+	if f := global.Lookup("dry-run"); f != nil && f.Value.String() == "true" {
+		out, err = executor.DryRun(env, cfg, in)
+		return out, true, err
+	}
+	return "", false, nil
+}
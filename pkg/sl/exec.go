@@ -0,0 +1,88 @@
+package sl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"sl-cli/internal/executor"
+
+	"github.com/spf13/cobra"
+)
+
+// Result 是一次命令执行的结构化结果
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	Err      error // 执行或用法错误，成功时为 nil
+	Duration time.Duration
+}
+
+// Root 构建一个包含全部命令的根命令，可直接 Execute 或挂载到其他 cobra 程序中
+func (e *Engine) Root() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "sl-cli",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	AddGlobalFlags(root)
+	root.TraverseChildren = true
+	root.RunE = RootRunE
+	e.Build(root)
+	return root
+}
+
+// AddGlobalFlags 为根命令添加作用于所有配置命令的全局标志
+func AddGlobalFlags(root *cobra.Command) {
+	root.PersistentFlags().Duration("timeout", 0, "命令执行超时 (如 30s)，覆盖配置中的 timeout")
+	root.PersistentFlags().StringP("output", "o", "", "输出格式: json|yaml|table|csv|raw，或 table=<列,...>、csv=<列,...>、template=<模板>")
+	_ = root.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(
+		[]cobra.Completion{"json", "yaml", "table", "csv", "raw", "template="}, cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveNoSpace))
+	root.PersistentFlags().Bool("dry-run", false, "只输出最终的请求、脚本或命令行，不实际执行")
+	root.PersistentFlags().String("as", "", "输出等价的单行命令而不执行 (仅 http): curl|httpie|wget")
+	_ = root.RegisterFlagCompletionFunc("as", cobra.FixedCompletions(executor.RenderFormats, cobra.ShellCompDirectiveNoFileComp))
+	root.PersistentFlags().StringP("download", "O", "", "将响应保存为文件 (仅 http)，为目录时使用 Content-Disposition 或 URL 中的文件名")
+	_ = root.MarkPersistentFlagFilename("download")
+	root.PersistentFlags().Bool("all", false, "获取全部分页结果并拼接为一个数组 (需要配置 api.paginate)")
+	root.PersistentFlags().Int("max-pages", 0, "最多获取的页数，隐含 --all (需要配置 api.paginate)")
+	root.PersistentFlags().BoolP("trace", "v", false, "输出 HTTP 请求/响应的 Header、重定向和各阶段耗时 (stderr)")
+	root.PersistentFlags().String("har", "", "将 HTTP 请求记录到 HAR 文件")
+	_ = root.MarkPersistentFlagFilename("har", "har")
+}
+
+// RootRunE 是根命令的 RunE: 不带参数时输出帮助，否则报告未知命令
+// 开启 TraverseChildren 后 cobra 不再检查未知命令，需要根命令自行处理
+func RootRunE(c *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return c.Help()
+	}
+	c.SilenceUsage = true
+	return fmt.Errorf("unknown command %q for %q\nRun '%s --help' for usage", args[0], c.CommandPath(), c.CommandPath())
+}
+
+// Exec 按路径执行命令，例如 []string{"dev", "info", "--verbose"}，并捕获输出
+// stdin 为 nil 时命令读取到的是空输入
+func (e *Engine) Exec(ctx context.Context, argv []string, stdin io.Reader) *Result {
+	if stdin == nil {
+		stdin = bytes.NewReader(nil)
+	}
+	var stdout, stderr bytes.Buffer
+	root := e.Root()
+	root.SetArgs(argv)
+	root.SetIn(stdin)
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+
+	start := time.Now()
+	err := root.ExecuteContext(ctx)
+	return &Result{
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		ExitCode: ExitCode(err),
+		Err:      err,
+		Duration: time.Since(start),
+	}
+}
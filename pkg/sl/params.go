@@ -0,0 +1,231 @@
+package sl
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"sl-cli/internal/config"
+	"sl-cli/internal/executor"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// paramValue 将 ParamConfig 适配为 pflag.Value，所有类型共用同一套解析与校验逻辑
+type paramValue struct {
+	param config.ParamConfig
+	raw   string
+	value interface{}
+}
+
+func newParamValue(p config.ParamConfig) *paramValue {
+	v := &paramValue{param: p, raw: p.Default, value: p.Zero()}
+	if p.Default != "" {
+		if parsed, err := p.Parse(p.Default); err == nil {
+			v.value = parsed
+		} else {
+			// 默认值无效 (例如 file 不存在) 时保留原始字符串，交给 config check 报告
+			v.value = p.Default
+		}
+	}
+	return v
+}
+
+func (v *paramValue) String() string { return v.raw }
+
+func (v *paramValue) Set(s string) error {
+	parsed, err := v.param.Parse(s)
+	if err != nil {
+		return err
+	}
+	v.raw = s
+	v.value = parsed
+	return nil
+}
+
+func (v *paramValue) Type() string {
+	if v.param.Kind() == config.ParamEnum {
+		return strings.Join(v.param.Values, "|")
+	}
+	return v.param.Kind()
+}
+
+// globalShorthands 是 AddGlobalFlags 占用的简写，从 AddGlobalFlags 本身得到，新增全局标志时无需同步
+var globalShorthands = func() map[string]bool {
+	c := &cobra.Command{}
+	AddGlobalFlags(c)
+	shorthands := make(map[string]bool)
+	c.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Shorthand != "" {
+			shorthands[f.Shorthand] = true
+		}
+	})
+	return shorthands
+}()
+
+// addParamFlags 根据 flags 声明注册 cobra flag，返回 name -> value 的映射供执行时读取
+func addParamFlags(cmd *cobra.Command, flags []config.ParamConfig) map[string]*paramValue {
+	values := make(map[string]*paramValue, len(flags))
+	for _, p := range flags {
+		// 重复的声明会让 pflag panic，这里跳过，由 config check 报告
+		if cmd.Flags().Lookup(p.Name) != nil {
+			continue
+		}
+		// 与全局标志 (-o、-v) 冲突的简写在合并 persistent flags 时同样会 panic
+		shorthand := p.Shorthand
+		if len(shorthand) != 1 || cmd.Flags().ShorthandLookup(shorthand) != nil || globalShorthands[shorthand] {
+			shorthand = ""
+		}
+		v := newParamValue(p)
+		f := cmd.Flags().VarPF(v, p.Name, shorthand, paramDescription(p))
+		if p.Kind() == config.ParamBool {
+			// 允许 --verbose 这种不带值的写法
+			f.NoOptDefVal = "true"
+		}
+		values[p.Name] = v
+
+		switch p.Kind() {
+		case config.ParamEnum:
+			_ = cmd.RegisterFlagCompletionFunc(p.Name, cobra.FixedCompletions(p.Values, cobra.ShellCompDirectiveNoFileComp))
+		case config.ParamFile:
+			_ = cmd.MarkFlagFilename(p.Name)
+		}
+	}
+	return values
+}
+
+// resolveFlags 处理环境变量回退和必填校验，返回模板中使用的 flag 值
+func resolveFlags(cmd *cobra.Command, flags []config.ParamConfig, values map[string]*paramValue, getenv func(string) string) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{}, len(flags))
+	for _, p := range flags {
+		v := values[p.Name]
+		changed := cmd.Flags().Changed(p.Name)
+		if !changed && p.Env != "" {
+			if envVal := getenv(p.Env); envVal != "" {
+				if err := v.Set(envVal); err != nil {
+					return nil, fmt.Errorf("invalid value %q for --%s (from $%s): %w", envVal, p.Name, p.Env, err)
+				}
+				changed = true
+			}
+		}
+		if p.Required && !changed && p.Default == "" {
+			return nil, fmt.Errorf("required flag \"--%s\" not set", p.Name)
+		}
+		resolved[p.Name] = v.value
+	}
+	return resolved, nil
+}
+
+// resolveParams 将位置参数按声明转换为 name -> value
+// 取值优先级: 命令行 > 环境变量 > 默认值
+func resolveParams(params []config.ParamConfig, args []string, getenv func(string) string) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{}, len(params))
+	for i, p := range params {
+		raw, source := "", ""
+		switch {
+		case i < len(args):
+			raw, source = args[i], "argument"
+		case p.Env != "" && getenv(p.Env) != "":
+			raw, source = getenv(p.Env), "$"+p.Env
+		case p.Default != "":
+			raw, source = p.Default, "default"
+		case p.Required:
+			return nil, fmt.Errorf("missing required argument <%s>", p.Name)
+		default:
+			resolved[p.Name] = p.Zero()
+			continue
+		}
+
+		val, err := p.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q for <%s>: %w", source, raw, p.Name, err)
+		}
+		resolved[p.Name] = val
+	}
+	return resolved, nil
+}
+
+// paramArgs 生成 cobra 的位置参数校验器
+// 透传参数的类型 (shell/system) 的多余参数会继续交给脚本或命令，其他类型不接受多余参数
+func paramArgs(cfg config.CommandConfig, getenv func(string) string) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if !executor.PassthroughArgs(cfg.Type) && len(args) > len(cfg.Params) {
+			return fmt.Errorf("accepts at most %d arg(s), received %d", len(cfg.Params), len(args))
+		}
+		_, err := resolveParams(cfg.Params, args, getenv)
+		return err
+	}
+}
+
+// argCompletion 为位置参数提供补全: 已声明的 params 提供 enum 值和文件名补全，
+// 其余位置交给命令类型的 Runner，Runner 没有候选时使用默认的文件名补全
+func argCompletion(cfg config.CommandConfig) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) >= len(cfg.Params) {
+			if r, ok := executor.Lookup(cfg.Type); ok {
+				if candidates := r.Complete(cfg, args, toComplete); candidates != nil {
+					return candidates, cobra.ShellCompDirectiveNoFileComp
+				}
+			}
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		p := cfg.Params[len(args)]
+		switch p.Kind() {
+		case config.ParamEnum:
+			return p.Values, cobra.ShellCompDirectiveNoFileComp
+		case config.ParamFile:
+			return nil, cobra.ShellCompDirectiveDefault
+		default:
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+}
+
+// useLine 生成形如 "weather <city> [units]" 的用法行
+func useLine(cfg config.CommandConfig) string {
+	parts := []string{cfg.Name}
+	for _, p := range cfg.Params {
+		if p.Required {
+			parts = append(parts, "<"+p.Name+">")
+		} else {
+			parts = append(parts, "["+p.Name+"]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// paramsHelp 生成位置参数说明，附加在命令的 Long 描述中 (同时出现在 --help 和 man page)
+func paramsHelp(params []config.ParamConfig) string {
+	if len(params) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	buf.WriteString("Arguments:\n")
+	w := tabwriter.NewWriter(&buf, 0, 0, 3, ' ', 0)
+	for _, p := range params {
+		desc := paramDescription(p)
+		if p.Default != "" {
+			desc += fmt.Sprintf(" (default %q)", p.Default)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", p.Name, p.Kind(), desc)
+	}
+	_ = w.Flush()
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// paramDescription 拼接 help 文本及可选值、环境变量、必填等提示
+func paramDescription(p config.ParamConfig) string {
+	desc := p.Help
+	if p.Kind() == config.ParamEnum {
+		desc += fmt.Sprintf(" (one of: %s)", strings.Join(p.Values, ", "))
+	}
+	if p.Env != "" {
+		desc += fmt.Sprintf(" (env: %s)", p.Env)
+	}
+	if p.Required {
+		desc += " (required)"
+	}
+	return strings.TrimSpace(desc)
+}
@@ -0,0 +1,107 @@
+package sl
+
+import (
+	"strings"
+	"testing"
+
+	"sl-cli/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+// testGetenv 返回只包含 environ 中变量的 getenv
+func testGetenv(environ map[string]string) func(string) string {
+	return func(key string) string { return environ[key] }
+}
+
+func TestResolveParams(t *testing.T) {
+	getenv := testGetenv(map[string]string{"SL_TEST_CITY": "Paris"})
+	params := []config.ParamConfig{
+		{Name: "city", Required: true},
+		{Name: "days", Type: config.ParamInt, Default: "3"},
+		{Name: "units", Type: config.ParamEnum, Values: []string{"metric", "imperial"}},
+	}
+	tests := []struct {
+		name    string
+		params  []config.ParamConfig
+		args    []string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{"all given", params, []string{"London", "5", "imperial"}, map[string]interface{}{"city": "London", "days": 5, "units": "imperial"}, ""},
+		{"defaults and zero values", params, []string{"London"}, map[string]interface{}{"city": "London", "days": 3, "units": ""}, ""},
+		{"missing required", params, nil, nil, "missing required argument <city>"},
+		{"invalid int", params, []string{"London", "many"}, nil, `invalid argument "many" for <days>: must be an integer`},
+		{"invalid enum", params, []string{"London", "1", "kelvin"}, nil, "must be one of: metric, imperial"},
+		{"env fallback", []config.ParamConfig{{Name: "city", Required: true, Env: "SL_TEST_CITY"}}, nil, map[string]interface{}{"city": "Paris"}, ""},
+		{"argument wins over env", []config.ParamConfig{{Name: "city", Env: "SL_TEST_CITY"}}, []string{"Rome"}, map[string]interface{}{"city": "Rome"}, ""},
+		{"invalid default", []config.ParamConfig{{Name: "n", Type: config.ParamInt, Default: "x"}}, nil, nil, `invalid default "x" for <n>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveParams(tt.params, tt.args, getenv)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveParams error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %#v, want %#v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveFlags(t *testing.T) {
+	getenv := testGetenv(map[string]string{"SL_TEST_TIMES": "7"})
+	flags := []config.ParamConfig{
+		{Name: "times", Shorthand: "n", Type: config.ParamInt, Default: "1", Env: "SL_TEST_TIMES"},
+		{Name: "verbose", Type: config.ParamBool},
+		{Name: "token", Required: true},
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{"env fallback", []string{"--token", "t"}, map[string]interface{}{"times": 7, "verbose": false, "token": "t"}, ""},
+		{"flag wins over env", []string{"-n", "2", "--verbose", "--token=t"}, map[string]interface{}{"times": 2, "verbose": true, "token": "t"}, ""},
+		{"missing required", nil, nil, `required flag "--token" not set`},
+		{"invalid value", []string{"--times", "x"}, nil, "must be an integer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			values := addParamFlags(cmd, flags)
+			err := cmd.ParseFlags(tt.args)
+			var got map[string]interface{}
+			if err == nil {
+				got, err = resolveFlags(cmd, flags, values, getenv)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveFlags error: %v", err)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %#v, want %#v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
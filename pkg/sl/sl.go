@@ -0,0 +1,123 @@
+// Package sl 是 sl-cli 执行引擎的公开 API，供其他 Go 程序嵌入使用:
+// 加载配置 (含 imports)、构建与 sl-cli 相同的 cobra 命令树、
+// 注册原生 Go 命令，以及按路径执行命令并获取捕获的输出和结构化结果。
+//
+//	eng, err := sl.Load("sl-cli.yaml")
+//	if err != nil { ... }
+//	res := eng.Exec(ctx, []string{"weather", "beijing"}, nil)
+//	fmt.Println(res.ExitCode, string(res.Stdout))
+package sl
+
+import (
+	"os"
+
+	"sl-cli/internal/config"
+	"sl-cli/internal/executor"
+
+	"github.com/spf13/cobra"
+)
+
+// 配置结构
+type (
+	Config        = config.Config
+	CommandConfig = config.CommandConfig
+	APIConfig     = config.APIConfig
+	PipeConfig    = config.PipeConfig
+	ParamConfig   = config.ParamConfig
+	StringList    = config.StringList
+)
+
+// 执行器
+type (
+	Env       = executor.Env
+	Input     = executor.Input
+	Runner    = executor.Runner
+	ExitError = executor.ExitError
+)
+
+// LoadConfig 读取配置文件，递归处理 imports 并返回合并后的配置
+func LoadConfig(path string) (*Config, error) {
+	return config.LoadConfig(path)
+}
+
+// Register 注册一种命令类型，配置中 type 为 typ 的命令会交给 r 执行
+func Register(typ string, r Runner) {
+	executor.Register(typ, r)
+}
+
+// ExitCode 从错误中提取退出码: nil 为 0，未携带退出码的错误为 1
+func ExitCode(err error) int {
+	return executor.ExitCode(err)
+}
+
+// Engine 持有一份配置，并据此构建命令树
+type Engine struct {
+	// Env 是执行环境的模板: Environ、Dir、Now、Transport 对所有命令生效，
+	// 标准输入输出则取自 cobra 命令 (SetIn/SetOut/SetErr)
+	Env *Env
+
+	config *Config
+	native []func() *cobra.Command
+}
+
+// New 基于已加载的配置创建引擎
+func New(cfg *Config) *Engine {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	return &Engine{config: cfg}
+}
+
+// Load 读取配置文件并创建引擎
+func Load(path string) (*Engine, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg), nil
+}
+
+// Config 返回引擎使用的配置
+func (e *Engine) Config() *Config {
+	return e.config
+}
+
+// AddCommand 注册原生 Go 命令的构造函数，与配置中的命令一起挂载
+// 每次 Build (包括每次 Exec) 都会调用构造函数创建新的命令，flag 的值不会在多次执行之间残留
+// 同名时原生命令优先，配置中的子命令会追加到原生命令下
+func (e *Engine) AddCommand(newCmds ...func() *cobra.Command) {
+	e.native = append(e.native, newCmds...)
+}
+
+// Build 将原生命令和配置中的命令挂载到 root 上
+func (e *Engine) Build(root *cobra.Command) {
+	for _, newCmd := range e.native {
+		root.AddCommand(newCmd())
+	}
+
+	for _, cmdCfg := range e.config.Commands {
+		cmd := e.buildCommand(cmdCfg)
+		// 重复添加的命令丢弃，如果有子命令则将子命令追加到已存在命令的字命令中
+		deplicated := false
+		for _, c := range root.Commands() {
+			if c.Name() != cmd.Name() {
+				continue
+			}
+			deplicated = true
+			if cmd.HasSubCommands() {
+				c.AddCommand(cmd.Commands()...)
+			}
+		}
+		if !deplicated {
+			root.AddCommand(cmd)
+		}
+	}
+}
+
+// getenv 读取参数和 flag 的环境变量回退: 优先使用 Env.Environ，未设置时读取当前进程的环境变量
+func (e *Engine) getenv(key string) string {
+	if e.Env != nil && e.Env.Environ != nil {
+		return e.Env.Getenv(key)
+	}
+	return os.Getenv(key)
+}
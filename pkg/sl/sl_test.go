@@ -0,0 +1,267 @@
+package sl
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sl-cli/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+func TestExec(t *testing.T) {
+	cfg := &Config{
+		Vars: map[string]string{"greeting": "hello"},
+		Commands: []CommandConfig{
+			{
+				Name:   "greet",
+				Type:   "shell",
+				Params: []ParamConfig{{Name: "name", Required: true}},
+				Flags:  []ParamConfig{{Name: "loud", Type: "bool"}},
+				Script: `{{if .flags.loud}}echo "{{.vars.greeting}} {{.args.name}}!"{{else}}echo "{{.vars.greeting}} {{.args.name}}"{{end}}`,
+			},
+			{Name: "fail", Type: "shell", Script: "echo bad >&2; exit 3"},
+			{Name: "cat", Type: "shell", Script: "cat"},
+			{Name: "group", SubCommands: []CommandConfig{{Name: "child", Type: "shell", Script: "echo child"}}},
+		},
+	}
+	tests := []struct {
+		name     string
+		argv     []string
+		stdin    io.Reader
+		wantOut  string
+		wantErr  string
+		wantCode int
+	}{
+		{"config command", []string{"greet", "world"}, nil, "hello world\n", "", 0},
+		{"flags", []string{"greet", "--loud", "world"}, nil, "hello world!\n", "", 0},
+		{"subcommand", []string{"group", "child"}, nil, "child\n", "", 0},
+		{"stdin", []string{"cat"}, strings.NewReader("piped"), "piped", "", 0},
+		{"exit code", []string{"fail"}, nil, "", "bad\n", 3},
+		{"missing argument", []string{"greet"}, nil, "", "missing required argument <name>", 1},
+		{"unknown command", []string{"nope"}, nil, "", `unknown command "nope"`, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := New(cfg)
+			eng.Env = &Env{Environ: []string{"PATH=" + os.Getenv("PATH")}}
+			res := eng.Exec(context.Background(), tt.argv, tt.stdin)
+			if res.ExitCode != tt.wantCode || (res.Err == nil) != (tt.wantCode == 0) {
+				t.Fatalf("exit code = %d, err = %v; want %d", res.ExitCode, res.Err, tt.wantCode)
+			}
+			if string(res.Stdout) != tt.wantOut {
+				t.Errorf("stdout = %q, want %q", res.Stdout, tt.wantOut)
+			}
+			// 执行错误输出到 stderr，用法错误通过 Result.Err 返回
+			errText := string(res.Stderr)
+			if res.Err != nil {
+				errText += res.Err.Error()
+			}
+			if !strings.Contains(errText, tt.wantErr) {
+				t.Errorf("errors = %q, want %q", errText, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNativeCommands(t *testing.T) {
+	cfg := &Config{Commands: []CommandConfig{
+		// 与原生命令同名: 丢弃配置命令本身，子命令追加到原生命令下
+		{Name: "dev", Type: "shell", Script: "echo config", SubCommands: []CommandConfig{{Name: "info", Type: "shell", Script: "echo info"}}},
+	}}
+	eng := New(cfg)
+	eng.AddCommand(func() *cobra.Command {
+		return &cobra.Command{
+			Use: "dev",
+			RunE: func(c *cobra.Command, args []string) error {
+				c.Println("native")
+				return nil
+			},
+		}
+	})
+	for argv, want := range map[string]string{"dev": "native\n", "dev info": "info\n"} {
+		res := eng.Exec(context.Background(), strings.Fields(argv), nil)
+		if res.Err != nil || string(res.Stdout) != want {
+			t.Errorf("Exec(%s) = %q, %v; want %q", argv, res.Stdout, res.Err, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sl-cli.yaml")
+	yaml := "commands:\n  - name: hi\n    type: shell\n    script: echo hi\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	eng, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if len(eng.Config().Commands) != 1 {
+		t.Fatalf("Config() = %+v, want one command", eng.Config())
+	}
+	if res := eng.Exec(context.Background(), []string{"hi"}, nil); string(res.Stdout) != "hi\n" {
+		t.Errorf("stdout = %q, want %q", res.Stdout, "hi\n")
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load(missing) succeeded")
+	}
+}
+
+func TestOutputFlag(t *testing.T) {
+	cfg := &Config{Commands: []CommandConfig{{
+		Name:   "list",
+		Type:   "shell",
+		Script: `echo '[{"name":"a","n":1},{"name":"b","n":2}]'`,
+		Output: config.OutputConfig{Format: "table"},
+		// 命令自己的 -o 简写与全局 --output 冲突，不会被注册
+		Flags: []ParamConfig{{Name: "owner", Shorthand: "o"}},
+	}}}
+	tests := []struct {
+		argv    []string
+		want    string
+		wantErr string
+	}{
+		{[]string{"list"}, "NAME  N\na     1\nb     2\n", ""},
+		{[]string{"--output", "csv=name", "list"}, "NAME\na\nb\n", ""},
+		{[]string{"list", "-o", "raw"}, "[{\"name\":\"a\",\"n\":1},{\"name\":\"b\",\"n\":2}]\n", ""},
+		{[]string{"list", "-o", "xml"}, "", `invalid output format "xml"`},
+	}
+	for _, tt := range tests {
+		res := New(cfg).Exec(context.Background(), tt.argv, nil)
+		if tt.wantErr != "" {
+			if res.Err == nil || !strings.Contains(res.Err.Error(), tt.wantErr) {
+				t.Errorf("Exec(%q) error = %v, want %q", tt.argv, res.Err, tt.wantErr)
+			}
+			continue
+		}
+		if res.Err != nil || string(res.Stdout) != tt.want {
+			t.Errorf("Exec(%q) = %q, %v; want %q", tt.argv, res.Stdout, res.Err, tt.want)
+		}
+	}
+}
+
+func TestDryRunDoesNotExecute(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer srv.Close()
+
+	marker := filepath.Join(t.TempDir(), "ran")
+	cfg := &Config{Commands: []CommandConfig{
+		{Name: "get", Type: "http", API: APIConfig{URL: srv.URL + "/items/{{index .args 0}}", Headers: map[string]string{"Authorization": "Bearer t0k"}}},
+		{Name: "sh", Type: "shell", Script: `touch "{{index .args 0}}"`},
+		{Name: "sys", Type: "system", Command: "touch"},
+	}}
+	tests := []struct {
+		name    string
+		argv    []string
+		want    string
+		wantErr string
+	}{
+		{"http dry-run", []string{"--dry-run", "get", "1"}, "GET " + srv.URL + "/items/1\nAuthorization: Bearer ****\n", ""},
+		{"http as curl", []string{"--as", "curl", "get", "1"}, "curl -H 'Authorization: Bearer ****' " + srv.URL + "/items/1\n", ""},
+		{"http as wget", []string{"get", "--as=wget", "1"}, "wget -qO- '--header=Authorization: Bearer ****' " + srv.URL + "/items/1\n", ""},
+		{"shell dry-run", []string{"--dry-run", "sh", marker}, "#!/bin/sh\ntouch \"" + marker + "\"", ""},
+		{"system dry-run", []string{"--dry-run", "sys", marker}, "touch " + marker + "\n", ""},
+		{"shell as curl", []string{"--as", "curl", "sh", marker}, "", "--as is not supported for shell commands"},
+		{"invalid as", []string{"--as", "postman", "get", "1"}, "", "invalid --as format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := New(cfg).Exec(context.Background(), tt.argv, nil)
+			if tt.wantErr != "" {
+				if res.Err == nil || !strings.Contains(res.Err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", res.Err, tt.wantErr)
+				}
+			} else if res.Err != nil || string(res.Stdout) != tt.want {
+				t.Errorf("Exec = %q, %v; want %q", res.Stdout, res.Err, tt.want)
+			}
+			if hits != 0 {
+				t.Errorf("server received %d requests, want none", hits)
+			}
+			if _, err := os.Stat(marker); err == nil {
+				t.Errorf("command ran and created %s", marker)
+			}
+		})
+	}
+}
+
+func TestExecNativeCommandIsRebuilt(t *testing.T) {
+	eng := New(nil)
+	eng.AddCommand(func() *cobra.Command {
+		var loud bool
+		cmd := &cobra.Command{
+			Use: "hello",
+			RunE: func(c *cobra.Command, args []string) error {
+				msg := "hello"
+				if loud {
+					msg = "HELLO"
+				}
+				c.Println(msg)
+				return nil
+			},
+		}
+		cmd.Flags().BoolVar(&loud, "loud", false, "")
+		return cmd
+	})
+
+	// 每次 Exec 都重新创建原生命令，上一次的 --loud 不会残留
+	for _, tt := range []struct {
+		argv []string
+		want string
+	}{
+		{[]string{"hello", "--loud"}, "HELLO\n"},
+		{[]string{"hello"}, "hello\n"},
+	} {
+		res := eng.Exec(context.Background(), tt.argv, nil)
+		if res.Err != nil {
+			t.Fatalf("Exec(%q) error: %v", tt.argv, res.Err)
+		}
+		if got := string(res.Stdout); got != tt.want {
+			t.Errorf("Exec(%q) stdout = %q, want %q", tt.argv, got, tt.want)
+		}
+	}
+}
+
+func TestParamEnvFallback(t *testing.T) {
+	t.Setenv("GREET_NAME", "from-process")
+	cfg := &Config{Commands: []CommandConfig{{
+		Name:   "greet",
+		Type:   "shell",
+		Script: `echo "hello {{.flags.name}}"`,
+		Flags:  []ParamConfig{{Name: "name", Env: "GREET_NAME"}},
+	}}}
+
+	tests := []struct {
+		name    string
+		environ []string
+		want    string
+	}{
+		// 未设置 Env.Environ 时读取进程环境变量
+		{"process environment", nil, "hello from-process"},
+		// 设置了 Env.Environ 时只读取其中的变量
+		{"engine environment", []string{"PATH=" + os.Getenv("PATH"), "GREET_NAME=from-engine"}, "hello from-engine"},
+		{"unset in engine environment", []string{"PATH=" + os.Getenv("PATH")}, "hello "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := New(cfg)
+			eng.Env = &Env{Environ: tt.environ}
+			res := eng.Exec(context.Background(), []string{"greet"}, nil)
+			if res.Err != nil {
+				t.Fatalf("Exec error: %v (stderr %q)", res.Err, res.Stderr)
+			}
+			if got := strings.TrimSuffix(string(res.Stdout), "\n"); got != tt.want {
+				t.Errorf("stdout = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}